@@ -22,6 +22,10 @@ type ConcurrentMap interface {
 	Delete(key string) bool
 	// Len 会返回当前字典中键-元素对的数量。
 	Len() uint64
+	// Range 会依次以每个键-元素对为参数调用函数f。
+	// 若f返回false，则遍历随即终止。
+	// 遍历基于各个散列段的快照进行，因此f可以安全地修改当前字典。
+	Range(f func(key string, element interface{}) bool)
 }
 
 // myConcurrentMap 代表ConcurrentMap接口的实现类型。
@@ -92,6 +96,19 @@ func (cmap *myConcurrentMap) Len() uint64 {
 	return atomic.LoadUint64(&cmap.total)
 }
 
+func (cmap *myConcurrentMap) Range(f func(key string, element interface{}) bool) {
+	if f == nil {
+		return
+	}
+	for _, s := range cmap.segments {
+		for _, p := range s.Pairs() {
+			if !f(p.Key(), p.Element()) {
+				return
+			}
+		}
+	}
+}
+
 // findSegment 会根据给定参数寻找并返回对应散列段。
 func (cmap *myConcurrentMap) findSegment(keyHash uint64) Segment {
 	if cmap.concurrency == 1 {
@@ -203,6 +203,51 @@ func TestCmapDelete(t *testing.T) {
 	}
 }
 
+func TestCmapRange(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	concurrency := number / 2
+	cm, _ := NewConcurrentMap(concurrency, nil)
+	expectedMap := make(map[string]interface{}, number)
+	for _, p := range testCases {
+		cm.Put(p.Key(), p.Element())
+		expectedMap[p.Key()] = p.Element()
+	}
+	actualMap := make(map[string]interface{}, number)
+	cm.Range(func(key string, element interface{}) bool {
+		actualMap[key] = element
+		return true
+	})
+	if len(actualMap) != len(expectedMap) {
+		t.Fatalf("Inconsistent ranged pair number: expected: %d, actual: %d",
+			len(expectedMap), len(actualMap))
+	}
+	for key, element := range expectedMap {
+		if actualMap[key] != element {
+			t.Fatalf("Inconsistent ranged element: expected: %#v, actual: %#v (key: %s)",
+				element, actualMap[key], key)
+		}
+	}
+	var count int
+	cm.Range(func(key string, element interface{}) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Fatalf("Inconsistent ranged count after break: expected: %d, actual: %d",
+			10, count)
+	}
+	// 测试在遍历过程中删除键-元素对的情况。
+	cm.Range(func(key string, element interface{}) bool {
+		cm.Delete(key)
+		return true
+	})
+	if cm.Len() != 0 {
+		t.Fatalf("Inconsistent size: expected: %d, actual: %d",
+			0, cm.Len())
+	}
+}
+
 func TestCmapDeleteInParallel(t *testing.T) {
 	number := 30
 	testCases := genNoRepetitiveTestingPairs(number)
@@ -175,6 +175,29 @@ func TestSegmentDelete(t *testing.T) {
 	}
 }
 
+func TestSegmentPairs(t *testing.T) {
+	number := 30
+	testCases := genNoRepetitiveTestingPairs(number)
+	s := newSegment(-1, nil)
+	for _, p := range testCases {
+		s.Put(p)
+	}
+	pairs := s.Pairs()
+	if len(pairs) != number {
+		t.Fatalf("Inconsistent pair number: expected: %d, actual: %d",
+			number, len(pairs))
+	}
+	keyMap := make(map[string]bool, number)
+	for _, p := range pairs {
+		keyMap[p.Key()] = true
+	}
+	for _, p := range testCases {
+		if !keyMap[p.Key()] {
+			t.Fatalf("Not found pair in segment snapshot! (pair: %#v)", p)
+		}
+	}
+}
+
 func TestSegmentDeleteInParallel(t *testing.T) {
 	number := 30
 	testCases := genNoRepetitiveTestingPairs(number)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ErrorType 代表错误类型。
@@ -27,6 +28,27 @@ type CrawlerError interface {
 	Type() ErrorType
 	// Error 用于获得错误提示信息。
 	Error() string
+	// MID 用于获得出错的组件的ID。若为空，则代表错误不是由组件产生的。
+	MID() string
+	// URL 用于获得出错时正在处理的请求的URL。若为空，则代表错误与具体请求无关。
+	URL() string
+	// Depth 用于获得出错时正在处理的请求的深度。
+	Depth() uint32
+	// Time 用于获得错误产生的时间。
+	Time() time.Time
+	// Unwrap 用于获得被包装的错误值。若为nil，则代表没有被包装的错误值。
+	// 它使得errors.Is和errors.As可以检查被包装的错误值。
+	Unwrap() error
+}
+
+// ErrorContext 代表爬虫错误的上下文信息。
+type ErrorContext struct {
+	// MID 代表出错的组件的ID。
+	MID string
+	// URL 代表出错时正在处理的请求的URL。
+	URL string
+	// Depth 代表出错时正在处理的请求的深度。
+	Depth uint32
 }
 
 // myCrawlerError 代表爬虫错误的实现类型。
@@ -37,6 +59,12 @@ type myCrawlerError struct {
 	errMsg string
 	// fullErrMsg 代表完整的错误提示信息。
 	fullErrMsg string
+	// errCtx 代表错误的上下文信息。
+	errCtx ErrorContext
+	// time 代表错误产生的时间。
+	time time.Time
+	// cause 代表被包装的错误值。
+	cause error
 }
 
 // NewCrawlerError 用于创建一个新的爬虫错误值。
@@ -44,18 +72,74 @@ func NewCrawlerError(errType ErrorType, errMsg string) CrawlerError {
 	return &myCrawlerError{
 		errType: errType,
 		errMsg:  strings.TrimSpace(errMsg),
+		time:    time.Now(),
 	}
 }
 
 // NewCrawlerErrorBy 用于根据给定的错误值创建一个新的爬虫错误值。
+// 给定的错误值会被包装在新的爬虫错误值中。
 func NewCrawlerErrorBy(errType ErrorType, err error) CrawlerError {
-	return NewCrawlerError(errType, err.Error())
+	return &myCrawlerError{
+		errType: errType,
+		errMsg:  strings.TrimSpace(err.Error()),
+		time:    time.Now(),
+		cause:   err,
+	}
+}
+
+// NewCrawlerErrorWithContext 用于根据给定的错误值和上下文信息创建一个新的爬虫错误值。
+// 若给定的错误值本身就是爬虫错误值，则新的错误值会沿用它的类型、提示信息、
+// 产生时间和被包装的错误值，并且只会补充它缺少的上下文信息。
+// 否则，给定的错误值会被包装在新的爬虫错误值中。
+func NewCrawlerErrorWithContext(
+	errType ErrorType, err error, errCtx ErrorContext) CrawlerError {
+	ce, ok := err.(*myCrawlerError)
+	if !ok {
+		newCE := NewCrawlerErrorBy(errType, err).(*myCrawlerError)
+		newCE.errCtx = errCtx
+		return newCE
+	}
+	newCE := &myCrawlerError{
+		errType: ce.errType,
+		errMsg:  ce.errMsg,
+		errCtx:  ce.errCtx,
+		time:    ce.time,
+		cause:   ce.cause,
+	}
+	if newCE.errCtx.MID == "" {
+		newCE.errCtx.MID = errCtx.MID
+	}
+	if newCE.errCtx.URL == "" {
+		newCE.errCtx.URL = errCtx.URL
+		newCE.errCtx.Depth = errCtx.Depth
+	}
+	return newCE
 }
 
 func (ce *myCrawlerError) Type() ErrorType {
 	return ce.errType
 }
 
+func (ce *myCrawlerError) MID() string {
+	return ce.errCtx.MID
+}
+
+func (ce *myCrawlerError) URL() string {
+	return ce.errCtx.URL
+}
+
+func (ce *myCrawlerError) Depth() uint32 {
+	return ce.errCtx.Depth
+}
+
+func (ce *myCrawlerError) Time() time.Time {
+	return ce.time
+}
+
+func (ce *myCrawlerError) Unwrap() error {
+	return ce.cause
+}
+
 func (ce *myCrawlerError) Error() string {
 	if ce.fullErrMsg == "" {
 		ce.genFullErrMsg()
@@ -72,6 +156,19 @@ func (ce *myCrawlerError) genFullErrMsg() {
 		buffer.WriteString(": ")
 	}
 	buffer.WriteString(ce.errMsg)
+	if ce.errCtx.MID != "" || ce.errCtx.URL != "" {
+		var details []string
+		if ce.errCtx.MID != "" {
+			details = append(details, "MID: "+ce.errCtx.MID)
+		}
+		if ce.errCtx.URL != "" {
+			details = append(details, "URL: "+ce.errCtx.URL,
+				fmt.Sprintf("depth: %d", ce.errCtx.Depth))
+		}
+		buffer.WriteString(" (")
+		buffer.WriteString(strings.Join(details, ", "))
+		buffer.WriteString(")")
+	}
 	ce.fullErrMsg = fmt.Sprintf("%s", buffer.String())
 	return
 }
@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCrawlerError(t *testing.T) {
+	ce := NewCrawlerError(ERROR_TYPE_SCHEDULER, " testing error ")
+	expectedErrMsg := "crawler error: scheduler error: testing error"
+	if ce.Error() != expectedErrMsg {
+		t.Fatalf("Inconsistent error message: expected: %q, actual: %q",
+			expectedErrMsg, ce.Error())
+	}
+	if ce.Unwrap() != nil {
+		t.Fatalf("Inconsistent wrapped error: expected: %v, actual: %v",
+			nil, ce.Unwrap())
+	}
+	if ce.Time().IsZero() {
+		t.Fatalf("Zero error time!")
+	}
+	if ce.MID() != "" || ce.URL() != "" || ce.Depth() != 0 {
+		t.Fatalf("Inconsistent error context: MID: %q, URL: %q, depth: %d",
+			ce.MID(), ce.URL(), ce.Depth())
+	}
+}
+
+func TestCrawlerErrorWithContext(t *testing.T) {
+	cause := &net.DNSError{Err: "no such host", Name: "example.com"}
+	errCtx := ErrorContext{
+		MID:   "D1|127.0.0.1:8080",
+		URL:   "http://example.com/a.html",
+		Depth: 2,
+	}
+	ce := NewCrawlerErrorWithContext(ERROR_TYPE_DOWNLOADER, cause, errCtx)
+	if ce.Type() != ERROR_TYPE_DOWNLOADER {
+		t.Fatalf("Inconsistent error type: expected: %q, actual: %q",
+			ERROR_TYPE_DOWNLOADER, ce.Type())
+	}
+	if ce.MID() != errCtx.MID || ce.URL() != errCtx.URL || ce.Depth() != errCtx.Depth {
+		t.Fatalf("Inconsistent error context: expected: %#v, actual: MID: %q, URL: %q, depth: %d",
+			errCtx, ce.MID(), ce.URL(), ce.Depth())
+	}
+	expectedErrMsg := "crawler error: downloader error: " + cause.Error() +
+		" (MID: D1|127.0.0.1:8080, URL: http://example.com/a.html, depth: 2)"
+	if ce.Error() != expectedErrMsg {
+		t.Fatalf("Inconsistent error message: expected: %q, actual: %q",
+			expectedErrMsg, ce.Error())
+	}
+	if !errors.Is(ce, cause) {
+		t.Fatalf("The crawler error does not wrap the cause %v!", cause)
+	}
+	var dnsErr *net.DNSError
+	if !errors.As(ce, &dnsErr) || dnsErr != cause {
+		t.Fatalf("Couldn't get the cause from the crawler error: %v", ce)
+	}
+	var target CrawlerError
+	if !errors.As(error(ce), &target) || target != ce {
+		t.Fatalf("Couldn't get the crawler error: %v", ce)
+	}
+	// 已有的上下文信息不会被覆盖，缺少的上下文信息会被补充。
+	another := NewCrawlerErrorWithContext(ERROR_TYPE_SCHEDULER, ce,
+		ErrorContext{MID: "A2", URL: "http://example.com/b.html", Depth: 3})
+	if another.Type() != ERROR_TYPE_DOWNLOADER || another.MID() != errCtx.MID ||
+		another.URL() != errCtx.URL || another.Depth() != errCtx.Depth ||
+		another.Time() != ce.Time() || !errors.Is(another, cause) {
+		t.Fatalf("Inconsistent crawler error: expected: %v, actual: %v", ce, another)
+	}
+	ce = NewCrawlerError(ERROR_TYPE_ANALYZER, "testing error")
+	another = NewCrawlerErrorWithContext(ERROR_TYPE_SCHEDULER, ce, errCtx)
+	if another.MID() != errCtx.MID || another.URL() != errCtx.URL {
+		t.Fatalf("Inconsistent error context: expected: %#v, actual: MID: %q, URL: %q",
+			errCtx, another.MID(), another.URL())
+	}
+}
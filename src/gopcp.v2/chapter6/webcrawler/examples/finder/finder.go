@@ -10,7 +10,9 @@ import (
 
 	lib "gopcp.v2/chapter6/webcrawler/examples/finder/internal"
 	"gopcp.v2/chapter6/webcrawler/examples/finder/monitor"
+	"gopcp.v2/chapter6/webcrawler/module/local/downloader"
 	sched "gopcp.v2/chapter6/webcrawler/scheduler"
+	"gopcp.v2/chapter6/webcrawler/toolkit/warc"
 	"gopcp.v2/helper/log"
 )
 
@@ -20,6 +22,10 @@ var (
 	domains  string
 	depth    uint
 	dirPath  string
+	// adminAddr 代表管理服务器的监听地址。
+	adminAddr string
+	// warcDirPath 代表存放WARC文件的目录。
+	warcDirPath string
 )
 
 // 日志记录器。
@@ -27,7 +33,8 @@ var logger = log.DLogger()
 
 func init() {
 	flag.StringVar(&firstURL, "first", "http://zhihu.sogou.com/zhihu?query=golang+logo",
-		"The first URL which you want to access.")
+		"The first URL which you want to access. "+
+			"Please using comma-separated multiple URLs.")
 	flag.StringVar(&domains, "domains", "zhihu.com",
 		"The primary domains which you accepted. "+
 			"Please using comma-separated multiple domains.")
@@ -35,6 +42,12 @@ func init() {
 		"The depth for crawling.")
 	flag.StringVar(&dirPath, "dir", "./pictures",
 		"The path which you want to save the image files.")
+	flag.StringVar(&adminAddr, "admin", "",
+		"The address of the admin server, e.g. \"localhost:8080\". "+
+			"The server is disabled if the address is empty.")
+	flag.StringVar(&warcDirPath, "warc", "",
+		"The path which you want to save the WARC files and their CDX indexes. "+
+			"The WARC output is disabled if the path is empty.")
 }
 
 func Usage() {
@@ -73,7 +86,16 @@ func main() {
 		ErrorBufferCap:       50,
 		ErrorMaxBufferNumber: 1,
 	}
-	downloaders, err := lib.GetDownloaders(1)
+	var downloaderOpts []downloader.Option
+	if warcDirPath != "" {
+		warcWriter, err := warc.NewWriter(warcDirPath, "finder", 0)
+		if err != nil {
+			logger.Fatalf("An error occurs when creating WARC writer: %s", err)
+		}
+		defer warcWriter.Close()
+		downloaderOpts = append(downloaderOpts, downloader.WithWARC(warcWriter))
+	}
+	downloaders, err := lib.GetDownloaders(1, downloaderOpts...)
 	if err != nil {
 		logger.Fatalf("An error occurs when creating downloaders: %s", err)
 	}
@@ -98,6 +120,16 @@ func main() {
 	if err != nil {
 		logger.Fatalf("An error occurs when initializing scheduler: %s", err)
 	}
+	// 开启管理服务器。
+	if adminAddr != "" {
+		go func() {
+			logger.Infof("Start the admin server at %s...", adminAddr)
+			err := http.ListenAndServe(adminAddr, sched.NewAdminHandler(scheduler))
+			if err != nil {
+				logger.Errorf("An error occurs when running the admin server: %s", err)
+			}
+		}()
+	}
 	// 准备监控参数。
 	checkInterval := time.Second
 	summarizeInterval := 100 * time.Millisecond
@@ -111,13 +143,21 @@ func main() {
 		true,
 		lib.Record)
 	// 准备调度器的启动参数。
-	firstHTTPReq, err := http.NewRequest("GET", firstURL, nil)
-	if err != nil {
-		logger.Fatalln(err)
-		return
+	firstHTTPReqs := []*http.Request{}
+	for _, u := range strings.Split(firstURL, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		firstHTTPReq, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			logger.Fatalln(err)
+			return
+		}
+		firstHTTPReqs = append(firstHTTPReqs, firstHTTPReq)
 	}
 	// 开启调度器
-	err = scheduler.Start(firstHTTPReq)
+	err = scheduler.Start(firstHTTPReqs...)
 	if err != nil {
 		logger.Fatalf("An error occurs when starting scheduler: %s", err)
 	}
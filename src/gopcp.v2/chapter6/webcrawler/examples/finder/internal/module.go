@@ -11,7 +11,8 @@ import (
 var snGen = module.NewSNGenertor(1, 0)
 
 // GetDownloaders 用于获取下载器列表。
-func GetDownloaders(number uint8) ([]module.Downloader, error) {
+// 参数opts代表所有下载器共用的选项。
+func GetDownloaders(number uint8, opts ...downloader.Option) ([]module.Downloader, error) {
 	downloaders := []module.Downloader{}
 	if number == 0 {
 		return downloaders, nil
@@ -23,7 +24,7 @@ func GetDownloaders(number uint8) ([]module.Downloader, error) {
 			return downloaders, err
 		}
 		d, err := downloader.New(
-			mid, genHTTPClient(), module.CalculateScoreSimple)
+			mid, genHTTPClient(), module.CalculateScoreSimple, opts...)
 		if err != nil {
 			return downloaders, err
 		}
@@ -44,8 +45,8 @@ func GetAnalyzers(number uint8) ([]module.Analyzer, error) {
 		if err != nil {
 			return analyzers, err
 		}
-		a, err := analyzer.New(
-			mid, genResponseParsers(), module.CalculateScoreSimple)
+		a, err := analyzer.NewWithRoutes(
+			mid, genResponseRoutes(), module.CalculateScoreSimple)
 		if err != nil {
 			return analyzers, err
 		}
@@ -1,6 +1,7 @@
 package module
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -16,11 +17,23 @@ type Request struct {
 	httpReq *http.Request
 	// depth 代表请求的深度。
 	depth uint32
+	// priority 代表请求的优先级。值越大越优先。
+	priority int
+	// attempt 代表请求的尝试次数。首次尝试时为1。
+	attempt uint32
+	// meta 代表请求携带的元数据。
+	meta Meta
 }
 
 // NewRequest 用于创建一个新的请求实例。
 func NewRequest(httpReq *http.Request, depth uint32) *Request {
-	return &Request{httpReq: httpReq, depth: depth}
+	return &Request{httpReq: httpReq, depth: depth, attempt: 1}
+}
+
+// NewPriorityRequest 用于创建一个带有优先级的请求实例。
+// 参数priority的值越大，请求就越优先被下载。
+func NewPriorityRequest(httpReq *http.Request, depth uint32, priority int) *Request {
+	return &Request{httpReq: httpReq, depth: depth, priority: priority, attempt: 1}
 }
 
 // HTTPReq 用于获取HTTP请求。
@@ -33,6 +46,51 @@ func (req *Request) Depth() uint32 {
 	return req.depth
 }
 
+// Priority 用于获取请求的优先级。
+func (req *Request) Priority() int {
+	return req.priority
+}
+
+// Attempt 用于获取请求的尝试次数。首次尝试时为1。
+func (req *Request) Attempt() uint32 {
+	return req.attempt
+}
+
+// Meta 用于获取请求携带的元数据的副本。
+// 其中的Attempt字段即为请求的尝试次数。
+func (req *Request) Meta() Meta {
+	meta := req.meta.Copy()
+	meta.Attempt = req.attempt
+	return meta
+}
+
+// WithMeta 用于生成一个携带给定元数据的请求实例。
+// 新实例的其他部分与当前实例相同。参数meta中的Attempt字段会被忽略。
+func (req *Request) WithMeta(meta Meta) *Request {
+	another := *req
+	another.meta = meta.Copy()
+	another.meta.Attempt = 0
+	return &another
+}
+
+// Retry 用于生成一个用于重试的请求实例。
+// 新实例的尝试次数会比当前实例的多1，其他部分则与当前实例相同。
+func (req *Request) Retry() *Request {
+	retry := *req
+	retry.attempt++
+	return &retry
+}
+
+// WithContext 用于生成一个其HTTP请求携带给定上下文的请求实例。
+// 新实例的其他部分与当前实例相同，当前实例及其HTTP请求不会被改变。
+func (req *Request) WithContext(ctx context.Context) *Request {
+	another := *req
+	if req.httpReq != nil && ctx != nil {
+		another.httpReq = req.httpReq.WithContext(ctx)
+	}
+	return &another
+}
+
 // Valid 用于判断请求是否有效。
 func (req *Request) Valid() bool {
 	return req.httpReq != nil && req.httpReq.URL != nil
@@ -44,6 +102,8 @@ type Response struct {
 	httpResp *http.Response
 	// depth 代表响应的深度。
 	depth uint32
+	// meta 代表响应携带的元数据。它通常来自对应的请求。
+	meta Meta
 }
 
 // NewResponse 用于创建一个新的响应实例。
@@ -61,6 +121,19 @@ func (resp *Response) Depth() uint32 {
 	return resp.depth
 }
 
+// Meta 用于获取响应携带的元数据的副本。
+func (resp *Response) Meta() Meta {
+	return resp.meta.Copy()
+}
+
+// WithMeta 用于生成一个携带给定元数据的响应实例。
+// 新实例的其他部分与当前实例相同。
+func (resp *Response) WithMeta(meta Meta) *Response {
+	another := *resp
+	another.meta = meta.Copy()
+	return &another
+}
+
 // Valid 用于判断响应是否有效。
 func (resp *Response) Valid() bool {
 	return resp.httpResp != nil && resp.httpResp.Body != nil
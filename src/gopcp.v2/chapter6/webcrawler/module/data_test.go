@@ -1,6 +1,7 @@
 package module
 
 import (
+	"context"
 	"net/http"
 	"strings"
 	"testing"
@@ -45,6 +46,70 @@ func TestRequest(t *testing.T) {
 		t.Fatalf("Inconsistent depth for request: expected: %d, actual: %d",
 			expectedDepth, req.Depth())
 	}
+	if req.Priority() != 0 {
+		t.Fatalf("Inconsistent priority for request: expected: %d, actual: %d",
+			0, req.Priority())
+	}
+	expectedPriority := 5
+	req = NewPriorityRequest(expectedHTTPReq, expectedDepth, expectedPriority)
+	if req.Priority() != expectedPriority {
+		t.Fatalf("Inconsistent priority for request: expected: %d, actual: %d",
+			expectedPriority, req.Priority())
+	}
+	if req.Attempt() != 1 {
+		t.Fatalf("Inconsistent attempt for request: expected: %d, actual: %d",
+			1, req.Attempt())
+	}
+	retry := req.Retry()
+	if retry.Attempt() != 2 {
+		t.Fatalf("Inconsistent attempt for retry request: expected: %d, actual: %d",
+			2, retry.Attempt())
+	}
+	if req.Attempt() != 1 {
+		t.Fatalf("Inconsistent attempt for original request: expected: %d, actual: %d",
+			1, req.Attempt())
+	}
+	if retry.HTTPReq() != req.HTTPReq() || retry.Depth() != req.Depth() ||
+		retry.Priority() != req.Priority() {
+		t.Fatalf("Inconsistent retry request: expected: %#v, actual: %#v",
+			req, retry)
+	}
+	// 测试元数据。
+	meta := Meta{ParentURL: "https://github.com", Attempt: 5,
+		Values: map[string]string{"team": "gopcp"}}
+	withMeta := retry.WithMeta(meta)
+	actualMeta := withMeta.Meta()
+	if actualMeta.ParentURL != meta.ParentURL || actualMeta.Value("team") != "gopcp" {
+		t.Fatalf("Inconsistent meta for request: expected: %#v, actual: %#v",
+			meta, actualMeta)
+	}
+	if actualMeta.Attempt != retry.Attempt() {
+		t.Fatalf("Inconsistent attempt in meta: expected: %d, actual: %d",
+			retry.Attempt(), actualMeta.Attempt)
+	}
+	if retry.Meta().ParentURL != "" {
+		t.Fatalf("The original request has been changed! (request: %#v)", retry)
+	}
+	if withMeta.Retry().Meta().ParentURL != meta.ParentURL {
+		t.Fatal("Inconsistent meta for retry request!")
+	}
+	// 测试上下文。
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	withCtx := withMeta.WithContext(ctx)
+	if withCtx.HTTPReq().Context() != ctx {
+		t.Fatalf("Inconsistent context for request: expected: %v, actual: %v",
+			ctx, withCtx.HTTPReq().Context())
+	}
+	if withMeta.HTTPReq().Context() == ctx {
+		t.Fatalf("The original HTTP request has been changed! (request: %#v)", withMeta)
+	}
+	if withCtx.Attempt() != withMeta.Attempt() || withCtx.Depth() != withMeta.Depth() ||
+		withCtx.Meta().ParentURL != meta.ParentURL ||
+		withCtx.HTTPReq().URL != withMeta.HTTPReq().URL {
+		t.Fatalf("Inconsistent request with context: expected: %#v, actual: %#v",
+			withMeta, withCtx)
+	}
 	expectedHTTPReq.URL = nil
 	req = NewRequest(expectedHTTPReq, expectedDepth)
 	expectedValidity = false
@@ -92,6 +157,17 @@ func TestResponse(t *testing.T) {
 		t.Fatalf("Inconsistent depth for response: expected: %d, actual: %d",
 			expectedDepth, resp.Depth())
 	}
+	meta := Meta{ParentURL: "https://github.com", Attempt: 2}
+	withMeta := resp.WithMeta(meta)
+	if actualMeta := withMeta.Meta(); actualMeta.ParentURL != meta.ParentURL ||
+		actualMeta.Attempt != meta.Attempt {
+		t.Fatalf("Inconsistent meta for response: expected: %#v, actual: %#v",
+			meta, actualMeta)
+	}
+	if withMeta.HTTPResp() != resp.HTTPResp() || withMeta.Depth() != resp.Depth() {
+		t.Fatalf("Inconsistent response with meta: expected: %#v, actual: %#v",
+			resp, withMeta)
+	}
 	expectHTTPResp.Body = nil
 	resp = NewResponse(expectHTTPResp, expectedDepth)
 	expectedValidity = false
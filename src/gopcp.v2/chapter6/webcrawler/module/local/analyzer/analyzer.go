@@ -1,7 +1,12 @@
 package analyzer
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/module/stub"
@@ -16,7 +21,8 @@ var logger = log.DLogger()
 func New(
 	mid module.MID,
 	respParsers []module.ParseResponse,
-	scoreCalculator module.CalculateScore) (module.Analyzer, error) {
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Analyzer, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -27,30 +33,62 @@ func New(
 	if len(respParsers) == 0 {
 		return nil, genParameterError("empty response parser list")
 	}
-	var innerParsers []module.ParseResponse
+	var innerRoutes []*route
 	for i, parser := range respParsers {
 		if parser == nil {
 			return nil, genParameterError(fmt.Sprintf("nil response parser[%d]", i))
 		}
-		innerParsers = append(innerParsers, parser)
+		innerRoutes = append(innerRoutes, &route{parser: parser})
 	}
-	return &myAnalyzer{
-		ModuleInternal: moduleBase,
-		respParsers:    innerParsers,
-	}, nil
+	return newAnalyzer(moduleBase, innerRoutes, opts), nil
+}
+
+// NewWithRoutes 用于创建一个按照路由规则分派响应的分析器实例。
+// 每个响应只会被交给与其MIME类型和URL相匹配的响应解析函数。
+// 未匹配任何路由规则的响应会被计入摘要信息。
+func NewWithRoutes(
+	mid module.MID,
+	routes []Route,
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Analyzer, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+	if routes == nil {
+		return nil, genParameterError("nil routes")
+	}
+	if len(routes) == 0 {
+		return nil, genParameterError("empty route list")
+	}
+	var innerRoutes []*route
+	for i, r := range routes {
+		compiled, err := newRoute(r)
+		if err != nil {
+			return nil, genParameterError(fmt.Sprintf("illegal route[%d]: %s", i, err))
+		}
+		innerRoutes = append(innerRoutes, compiled)
+	}
+	return newAnalyzer(moduleBase, innerRoutes, opts), nil
 }
 
 // 分析器的实现类型。
 type myAnalyzer struct {
 	// stub.ModuleInternal 代表组件基础实例。
 	stub.ModuleInternal
-	// respParsers 代表响应解析器列表。
-	respParsers []module.ParseResponse
+	// routes 代表路由规则列表，其中包含了响应解析器。
+	routes []*route
+	// unmatched 代表未匹配任何路由规则的响应的计数器。
+	unmatched unmatchedCounter
+	// memLimit 代表响应体在内存中的最大字节数。
+	memLimit int64
 }
 
 func (analyzer *myAnalyzer) RespParsers() []module.ParseResponse {
-	parsers := make([]module.ParseResponse, len(analyzer.respParsers))
-	copy(parsers, analyzer.respParsers)
+	parsers := make([]module.ParseResponse, len(analyzer.routes))
+	for i, r := range analyzer.routes {
+		parsers[i] = r.parser
+	}
 	return parsers
 }
 
@@ -89,24 +127,66 @@ func (analyzer *myAnalyzer) Analyze(
 
 	// 解析HTTP响应。
 	originalRespBody := httpResp.Body
+	var body *bufio.Reader
 	if originalRespBody != nil {
 		defer originalRespBody.Close()
+		body = bufio.NewReaderSize(originalRespBody, sniffSize)
+	}
+	var mimeType string
+	for _, r := range analyzer.routes {
+		if r.needMIMEType() {
+			mimeType = detectMIMEType(httpResp, body)
+			break
+		}
+	}
+	var matchedRoutes []*route
+	for _, r := range analyzer.routes {
+		if r.match(mimeType, reqURL.String()) {
+			matchedRoutes = append(matchedRoutes, r)
+		}
+	}
+	if len(matchedRoutes) == 0 {
+		analyzer.unmatched.incr(mimeType)
+		logger.Infof("No matched response parser for the response (URL: %s, MIME type: %q).\n",
+			reqURL, mimeType)
+		analyzer.ModuleInternal.IncrCompletedCount()
+		return []module.Data{}, nil
+	}
+	// 若所有匹配的响应解析函数都只需要响应体的前若干个字节，则只读取这些字节。
+	var src io.Reader
+	if body != nil {
+		src = body
+		if limit := readLimit(matchedRoutes); limit >= 0 {
+			src = io.LimitReader(body, limit)
+		}
 	}
-	multipleReader, err := reader.NewMultipleReader(originalRespBody)
+	multipleReader, err := reader.NewMultipleReaderWithLimit(src, analyzer.memLimit)
 	if err != nil {
 		errorList = append(errorList, genError(err.Error()))
 		return
 	}
+	// 已经交给响应解析函数的读取器在被关闭之前仍然可用。
+	defer multipleReader.Close()
+	// 使响应解析函数可以通过module.MetaFromResponse获取元数据。
+	meta := resp.Meta()
+	httpResp.Request = httpReq.WithContext(
+		module.ContextWithMeta(httpReq.Context(), meta))
 	dataList = []module.Data{}
-	for _, respParser := range analyzer.respParsers {
-		httpResp.Body = multipleReader.Reader()
+	for _, r := range matchedRoutes {
+		respParser := r.parser
+		httpResp.Body = r.body(multipleReader)
 		pDataList, pErrorList := respParser(httpResp, respDepth)
+		// 响应体在内存占用超限时是单独打开的临时文件，必须及时关闭。
+		httpResp.Body.Close()
 		if pDataList != nil {
+			parentMeta := meta.Copy()
+			parentMeta.ParentURL = reqURL.String()
+			parentMeta.Parser = parserName(respParser)
 			for _, pData := range pDataList {
 				if pData == nil {
 					continue
 				}
-				dataList = appendDataList(dataList, pData, respDepth)
+				dataList = appendDataList(dataList, pData, respDepth, parentMeta)
 			}
 		}
 		if pErrorList != nil {
@@ -124,18 +204,79 @@ func (analyzer *myAnalyzer) Analyze(
 	return dataList, errorList
 }
 
+// extraSummaryStruct 代表分析器额外信息的摘要类型。
+type extraSummaryStruct struct {
+	RouteNumber    int               `json:"route_number"`
+	Unmatched      uint64            `json:"unmatched"`
+	UnmatchedTypes map[string]uint64 `json:"unmatched_types,omitempty"`
+}
+
+func (analyzer *myAnalyzer) Summary() module.SummaryStruct {
+	summary := analyzer.ModuleInternal.Summary()
+	unmatched, unmatchedTypes := analyzer.unmatched.get()
+	summary.Extra = extraSummaryStruct{
+		RouteNumber:    len(analyzer.routes),
+		Unmatched:      unmatched,
+		UnmatchedTypes: unmatchedTypes,
+	}
+	return summary
+}
+
 // appendDataList 用于添加请求值或条目值到列表。
-func appendDataList(dataList []module.Data, data module.Data, respDepth uint32) []module.Data {
+// 参数parentMeta代表被解析的响应的元数据，
+// 其中的ParentURL和Parser字段分别为该响应的URL和解析它的函数的名称。
+// 请求会继承其中的自定义键值对，条目则会以module.ITEM_KEY_META为键携带它。
+func appendDataList(
+	dataList []module.Data,
+	data module.Data,
+	respDepth uint32,
+	parentMeta module.Meta) []module.Data {
 	if data == nil {
 		return dataList
 	}
 	req, ok := data.(*module.Request)
 	if !ok {
+		if item, ok := data.(module.Item); ok && item != nil {
+			if _, ok := item[module.ITEM_KEY_META]; !ok {
+				item[module.ITEM_KEY_META] = parentMeta.Copy()
+			}
+		}
 		return append(dataList, data)
 	}
-	newDepth := respDepth + 1
-	if req.Depth() != newDepth {
-		req = module.NewRequest(req.HTTPReq(), newDepth)
+	meta := req.Meta()
+	if meta.ParentURL == "" {
+		meta.ParentURL = parentMeta.ParentURL
+	}
+	if meta.Referrer == "" {
+		meta.Referrer = meta.ParentURL
 	}
+	if meta.Parser == "" {
+		meta.Parser = parentMeta.Parser
+	}
+	if len(parentMeta.Values) > 0 {
+		values := make(map[string]string, len(parentMeta.Values)+len(meta.Values))
+		for k, v := range parentMeta.Values {
+			// 站点地图相关的值只适用于父请求本身。
+			if strings.HasPrefix(k, module.META_KEY_PREFIX_SITEMAP) {
+				continue
+			}
+			values[k] = v
+		}
+		for k, v := range meta.Values {
+			values[k] = v
+		}
+		meta.Values = values
+	}
+	newDepth := respDepth + 1
+	req = module.NewPriorityRequest(req.HTTPReq(), newDepth, req.Priority()).
+		WithMeta(meta)
 	return append(dataList, req)
 }
+
+// parserName 用于获取给定响应解析函数的名称。
+func parserName(parser module.ParseResponse) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(parser).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
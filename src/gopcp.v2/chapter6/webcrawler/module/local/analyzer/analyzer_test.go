@@ -3,6 +3,8 @@ package analyzer
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -163,6 +165,316 @@ func TestAnalyze(t *testing.T) {
 	}
 }
 
+func TestAnalyzeMeta(t *testing.T) {
+	parentURL := "https://github.com/gopcp"
+	childURL := "https://github.com/gopcp/example.v2"
+	var parserMeta module.Meta
+	var found bool
+	parser := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		parserMeta, found = module.MetaFromResponse(httpResp)
+		childHTTPReq, _ := http.NewRequest("GET", childURL, nil)
+		child := module.NewRequest(childHTTPReq, 0).WithMeta(module.Meta{
+			Values: map[string]string{"tag": "child"},
+		})
+		return []module.Data{child, module.Item{"url": parentURL}}, nil
+	}
+	a, err := New(module.MID("A1"), []module.ParseResponse{parser}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	resp := getTestingResps(1, "GET", parentURL, 1, t)[0]
+	resp = resp.WithMeta(module.Meta{
+		Values: map[string]string{
+			"tag":  "parent",
+			"team": "gopcp",
+			module.META_KEY_PREFIX_SITEMAP + "lastmod": "2017-01-01",
+		},
+	})
+	dataList, errs := a.Analyze(resp)
+	if len(errs) > 0 {
+		t.Fatalf("An error occurs when analyzing response: %s", errs[0])
+	}
+	if !found {
+		t.Fatal("Couldn't get meta in response parser!")
+	}
+	if parserMeta.Value("team") != "gopcp" {
+		t.Fatalf("Inconsistent meta value in response parser: expected: %q, actual: %q",
+			"gopcp", parserMeta.Value("team"))
+	}
+	if len(dataList) != 2 {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d",
+			2, len(dataList))
+	}
+	req := dataList[0].(*module.Request)
+	if req.Depth() != 2 {
+		t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 2, req.Depth())
+	}
+	meta := req.Meta()
+	if meta.ParentURL != parentURL || meta.Referrer != parentURL {
+		t.Fatalf("Inconsistent parent URL or referrer: expected: %s, actual: %s and %s",
+			parentURL, meta.ParentURL, meta.Referrer)
+	}
+	if !strings.Contains(meta.Parser, "TestAnalyzeMeta") {
+		t.Fatalf("Inconsistent parser name: %q", meta.Parser)
+	}
+	expectedValues := map[string]string{
+		"tag":  "child",
+		"team": "gopcp",
+		module.META_KEY_PREFIX_SITEMAP + "lastmod": "",
+	}
+	for k, v := range expectedValues {
+		if meta.Value(k) != v {
+			t.Fatalf("Inconsistent meta value: expected: %q, actual: %q (key: %s)",
+				v, meta.Value(k), k)
+		}
+	}
+	item := dataList[1].(module.Item)
+	itemMeta, ok := item[module.ITEM_KEY_META].(module.Meta)
+	if !ok {
+		t.Fatalf("Inconsistent item meta type: expected: %T, actual: %T",
+			module.Meta{}, item[module.ITEM_KEY_META])
+	}
+	if itemMeta.ParentURL != parentURL || itemMeta.Value("tag") != "parent" ||
+		itemMeta.Value(module.META_KEY_PREFIX_SITEMAP+"lastmod") != "2017-01-01" {
+		t.Fatalf("Inconsistent item meta: %#v", itemMeta)
+	}
+}
+
+func TestNewWithRoutes(t *testing.T) {
+	mid := module.MID("A1|127.0.0.1:8080")
+	routes := []Route{
+		{MIMETypes: []string{"text/html"}, Parser: genTestingRespParser(false)},
+		{URLPattern: `\.png$`, Parser: genTestingRespParser(false)},
+	}
+	a, err := NewWithRoutes(mid, routes, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s (mid: %s)",
+			err, mid)
+	}
+	if len(a.RespParsers()) != len(routes) {
+		t.Fatalf("Inconsistent response parser number: expected: %d, actual: %d",
+			len(routes), len(a.RespParsers()))
+	}
+	// 测试参数有误的情况。
+	routesList := [][]Route{
+		nil,
+		[]Route{},
+		[]Route{{MIMETypes: []string{"text/html"}}},
+		[]Route{{MIMETypes: []string{"text/[html"}, Parser: genTestingRespParser(false)}},
+		[]Route{{URLPattern: "(", Parser: genTestingRespParser(false)}},
+	}
+	for _, routes := range routesList {
+		_, err = NewWithRoutes(mid, routes, nil)
+		if err == nil {
+			t.Fatalf("No error when create an analyzer with illegal routes %#v!",
+				routes)
+		}
+	}
+}
+
+func TestAnalyzeWithRoutes(t *testing.T) {
+	var htmlCount, imageCount, githubCount int
+	routes := []Route{
+		{
+			MIMETypes: []string{"text/html", "application/xhtml+xml"},
+			Parser: func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+				htmlCount++
+				return nil, nil
+			},
+		},
+		{
+			MIMETypes: []string{"image/*"},
+			Parser: func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+				imageCount++
+				return nil, nil
+			},
+		},
+		{
+			MIMETypes:  []string{"text/*"},
+			URLPattern: `^https://github\.com/`,
+			Parser: func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+				githubCount++
+				return nil, nil
+			},
+		},
+	}
+	a, err := NewWithRoutes(module.MID("A1"), routes, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	cases := []struct {
+		url         string
+		contentType string
+	}{
+		{"https://github.com/gopcp", "text/html; charset=utf-8"},
+		{"https://example.com/a.html", "TEXT/HTML"},
+		{"https://example.com/a.png", "image/png"},
+		{"https://example.com/a.css", "text/css"},
+		{"https://example.com/a.json", "application/json"},
+		// 响应头中没有内容类型，会根据内容探测为text/plain。
+		{"https://example.com/a", ""},
+	}
+	for _, c := range cases {
+		resp := getTestingResps(1, "GET", c.url, 0, t)[0]
+		if c.contentType != "" {
+			resp.HTTPResp().Header = http.Header{"Content-Type": {c.contentType}}
+		}
+		if _, errs := a.Analyze(resp); len(errs) > 0 {
+			t.Fatalf("An error occurs when analyzing response: %s", errs[0])
+		}
+	}
+	if htmlCount != 2 || imageCount != 1 || githubCount != 1 {
+		t.Fatalf("Inconsistent parser calls: expected: html: %d, image: %d, github: %d, actual: html: %d, image: %d, github: %d",
+			2, 1, 1, htmlCount, imageCount, githubCount)
+	}
+	extra, ok := a.Summary().Extra.(extraSummaryStruct)
+	if !ok {
+		t.Fatalf("Inconsistent extra summary type: expected: %T, actual: %T",
+			extraSummaryStruct{}, a.Summary().Extra)
+	}
+	expectedExtra := extraSummaryStruct{
+		RouteNumber: len(routes),
+		Unmatched:   3,
+		UnmatchedTypes: map[string]uint64{
+			"text/css":         1,
+			"application/json": 1,
+			"text/plain":       1,
+		},
+	}
+	if extra.RouteNumber != expectedExtra.RouteNumber ||
+		extra.Unmatched != expectedExtra.Unmatched ||
+		len(extra.UnmatchedTypes) != len(expectedExtra.UnmatchedTypes) {
+		t.Fatalf("Inconsistent extra summary: expected: %#v, actual: %#v",
+			expectedExtra, extra)
+	}
+	for mimeType, count := range expectedExtra.UnmatchedTypes {
+		if extra.UnmatchedTypes[mimeType] != count {
+			t.Fatalf("Inconsistent unmatched count: expected: %d, actual: %d (MIME type: %s)",
+				count, extra.UnmatchedTypes[mimeType], mimeType)
+		}
+	}
+	if completed := a.CompletedCount(); completed != uint64(len(cases)) {
+		t.Fatalf("Inconsistent completed count: expected: %d, actual: %d",
+			len(cases), completed)
+	}
+}
+
+func TestAnalyzeMaxBytes(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	var headBody, fullBody string
+	readBody := func(target *string) module.ParseResponse {
+		return func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+			data, err := ioutil.ReadAll(httpResp.Body)
+			if err != nil {
+				return nil, []error{err}
+			}
+			*target = string(data)
+			return nil, nil
+		}
+	}
+	newResp := func(body io.Reader) *module.Response {
+		httpReq, _ := http.NewRequest("GET", "https://github.com/gopcp", nil)
+		httpResp := &http.Response{
+			Request: httpReq,
+			Body:    ioutil.NopCloser(body),
+		}
+		return module.NewResponse(httpResp, 0)
+	}
+	// 只有需要前若干个字节的响应解析函数。
+	a, err := NewWithRoutes(module.MID("A1"), []Route{
+		{Parser: readBody(&headBody), MaxBytes: 5},
+	}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	body := strings.NewReader(content)
+	if _, errs := a.Analyze(newResp(body)); len(errs) > 0 {
+		t.Fatalf("An error occurs when analyzing response: %s", errs[0])
+	}
+	if headBody != content[:5] {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", content[:5], headBody)
+	}
+	// 除了预读的内容之外，分析器不应该读取多余的内容。
+	if body.Len() < len(content)-sniffSize {
+		t.Fatalf("Inconsistent unread size: expected: at least %d, actual: %d",
+			len(content)-sniffSize, body.Len())
+	}
+	// 同时有需要完整响应体的响应解析函数，且响应体超过了内存占用上限。
+	a, err = NewWithRoutes(module.MID("A1"), []Route{
+		{Parser: readBody(&headBody), MaxBytes: 5},
+		{Parser: readBody(&fullBody)},
+	}, nil, WithMemoryLimit(100))
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	headBody = ""
+	if _, errs := a.Analyze(newResp(strings.NewReader(content))); len(errs) > 0 {
+		t.Fatalf("An error occurs when analyzing response: %s", errs[0])
+	}
+	if headBody != content[:5] || fullBody != content {
+		t.Fatalf("Inconsistent body: expected: %q and %q, actual: %q and %q",
+			content[:5], content, headBody, fullBody)
+	}
+	// 非法的最大字节数。
+	_, err = NewWithRoutes(module.MID("A1"), []Route{
+		{Parser: readBody(&headBody), MaxBytes: -1},
+	}, nil)
+	if err == nil {
+		t.Fatalf("No error when create an analyzer with negative max bytes!")
+	}
+}
+
+func TestAnalyzeCloseBodies(t *testing.T) {
+	countFDs := func() int {
+		fds, err := ioutil.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skipf("Couldn't count open file descriptors: %s", err)
+		}
+		return len(fds)
+	}
+	var bodies []io.ReadCloser
+	keepBody := func(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+		bodies = append(bodies, httpResp.Body)
+		// 只读取部分内容，不读到末尾。
+		httpResp.Body.Read(make([]byte, 10))
+		return nil, nil
+	}
+	a, err := NewWithRoutes(module.MID("A1"), []Route{
+		{Parser: keepBody},
+		{Parser: keepBody},
+	}, nil, WithMemoryLimit(100))
+	if err != nil {
+		t.Fatalf("An error occurs when creating an analyzer: %s", err)
+	}
+	content := strings.Repeat("0123456789", 1000)
+	before := countFDs()
+	number := 50
+	for i := 0; i < number; i++ {
+		httpReq, _ := http.NewRequest("GET", "https://github.com/gopcp", nil)
+		httpResp := &http.Response{
+			Request: httpReq,
+			Body:    ioutil.NopCloser(strings.NewReader(content)),
+		}
+		if _, errs := a.Analyze(module.NewResponse(httpResp, 0)); len(errs) > 0 {
+			t.Fatalf("An error occurs when analyzing response: %s", errs[0])
+		}
+	}
+	if after := countFDs(); after > before {
+		t.Fatalf("Inconsistent open file descriptor number: expected: %d, actual: %d",
+			before, after)
+	}
+	if len(bodies) != number*2 {
+		t.Fatalf("Inconsistent body number: expected: %d, actual: %d",
+			number*2, len(bodies))
+	}
+	// 交给响应解析函数的响应体在其返回之后都已被关闭。
+	for i, body := range bodies {
+		if _, err := body.Read(make([]byte, 1)); err == nil {
+			t.Fatalf("The response body is not closed! (index: %d)", i)
+		}
+	}
+}
+
 func TestCount(t *testing.T) {
 	mid := module.MID("D1|127.0.0.1:8080")
 	// 测试初始化后的计数。
@@ -0,0 +1,35 @@
+package analyzer
+
+import "gopcp.v2/chapter6/webcrawler/module/stub"
+
+// DefaultMemoryLimit 代表默认的响应体在内存中的最大字节数。
+// 超出该大小的响应体会被写入临时文件。
+const DefaultMemoryLimit = 4 * 1024 * 1024
+
+// Option 代表分析器的选项。
+type Option func(analyzer *myAnalyzer)
+
+// WithMemoryLimit 用于设置响应体在内存中的最大字节数。
+// 超出该大小的响应体会被写入临时文件，以避免大文件占用过多的内存。
+// 参数limit小于0时代表不限制，即总是把响应体读入内存。
+func WithMemoryLimit(limit int64) Option {
+	return func(analyzer *myAnalyzer) {
+		analyzer.memLimit = limit
+	}
+}
+
+// newAnalyzer 用于根据给定的选项创建分析器实例。
+func newAnalyzer(
+	moduleBase stub.ModuleInternal, routes []*route, opts []Option) *myAnalyzer {
+	analyzer := &myAnalyzer{
+		ModuleInternal: moduleBase,
+		routes:         routes,
+		memLimit:       DefaultMemoryLimit,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(analyzer)
+		}
+	}
+	return analyzer
+}
@@ -0,0 +1,196 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/reader"
+)
+
+// sniffSize 代表在响应头中没有内容类型时，用于探测内容类型的最大字节数。
+const sniffSize = 512
+
+// unknownMIMEType 代表无法确定的MIME类型在摘要信息中的名称。
+const unknownMIMEType = "unknown"
+
+// Route 代表响应解析函数的路由规则。
+// 只有同时满足MIME类型模式和URL模式的响应才会被交给对应的响应解析函数。
+type Route struct {
+	// MIMETypes 代表MIME类型模式的列表，满足其中任意一个即可。
+	// 模式的语法与path.Match一致，如text/html、image/*。
+	// 若为空，则匹配任何MIME类型。
+	MIMETypes []string
+	// URLPattern 代表URL的正则表达式。若为空，则匹配任何URL。
+	URLPattern string
+	// Parser 代表响应解析函数。
+	Parser module.ParseResponse
+	// MaxBytes 代表响应解析函数需要的响应体的最大字节数。
+	// 若大于0，则响应解析函数只能读取到响应体的前MaxBytes个字节。
+	// 若所有匹配的路由规则都设置了该值，则分析器只会读取其中最大的字节数。
+	// 若为0，则代表需要完整的响应体。
+	MaxBytes int64
+}
+
+// route 代表已编译的路由规则。
+type route struct {
+	// mimeTypes 代表小写的MIME类型模式的列表。
+	mimeTypes []string
+	// urlRegexp 代表URL的正则表达式。若为nil，则匹配任何URL。
+	urlRegexp *regexp.Regexp
+	// parser 代表响应解析函数。
+	parser module.ParseResponse
+	// maxBytes 代表响应解析函数需要的响应体的最大字节数。0代表不限制。
+	maxBytes int64
+}
+
+// newRoute 用于编译给定的路由规则。
+func newRoute(r Route) (*route, error) {
+	if r.Parser == nil {
+		return nil, fmt.Errorf("nil response parser")
+	}
+	if r.MaxBytes < 0 {
+		return nil, fmt.Errorf("negative max bytes %d", r.MaxBytes)
+	}
+	compiled := &route{parser: r.Parser, maxBytes: r.MaxBytes}
+	for _, pattern := range r.MIMETypes {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("illegal MIME type pattern %q: %s", pattern, err)
+		}
+		compiled.mimeTypes = append(compiled.mimeTypes, pattern)
+	}
+	if r.URLPattern != "" {
+		urlRegexp, err := regexp.Compile(r.URLPattern)
+		if err != nil {
+			return nil, fmt.Errorf("illegal URL pattern %q: %s", r.URLPattern, err)
+		}
+		compiled.urlRegexp = urlRegexp
+	}
+	return compiled, nil
+}
+
+// needMIMEType 用于判断该路由规则是否需要依据MIME类型进行匹配。
+func (r *route) needMIMEType() bool {
+	return len(r.mimeTypes) > 0
+}
+
+// match 用于判断给定的MIME类型和URL是否满足该路由规则。
+func (r *route) match(mimeType string, rawURL string) bool {
+	if r.urlRegexp != nil && !r.urlRegexp.MatchString(rawURL) {
+		return false
+	}
+	if len(r.mimeTypes) == 0 {
+		return true
+	}
+	if mimeType == "" {
+		return false
+	}
+	for _, pattern := range r.mimeTypes {
+		if matched, _ := path.Match(pattern, mimeType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// body 用于为响应解析函数生成读取响应体的读取器。
+func (r *route) body(multipleReader reader.MultipleReader) io.ReadCloser {
+	rc := multipleReader.Reader()
+	if r.maxBytes <= 0 {
+		return rc
+	}
+	return &limitedReadCloser{Reader: io.LimitReader(rc, r.maxBytes), Closer: rc}
+}
+
+// limitedReadCloser 代表只能读取前若干个字节的可关闭读取器。
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// readLimit 用于获取给定的路由规则需要的响应体的最大字节数。
+// 若其中有需要完整响应体的路由规则，则返回-1。
+func readLimit(routes []*route) int64 {
+	var limit int64
+	for _, r := range routes {
+		if r.maxBytes <= 0 {
+			return -1
+		}
+		if r.maxBytes > limit {
+			limit = r.maxBytes
+		}
+	}
+	return limit
+}
+
+// detectMIMEType 用于确定响应内容的MIME类型。
+// 若响应头中没有内容类型，则会根据内容本身进行探测。
+// 探测时只会预读而不会消耗参数body中的内容。
+// 若无法确定，则返回空字符串。
+func detectMIMEType(httpResp *http.Response, body *bufio.Reader) string {
+	contentType := httpResp.Header.Get("Content-Type")
+	if contentType == "" {
+		if body == nil {
+			return ""
+		}
+		head, _ := body.Peek(sniffSize)
+		if len(head) == 0 {
+			return ""
+		}
+		contentType = http.DetectContentType(head)
+	}
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mimeType
+}
+
+// unmatchedCounter 代表未匹配任何路由规则的响应的计数器。
+type unmatchedCounter struct {
+	// lock 代表保护计数的互斥锁。
+	lock sync.Mutex
+	// total 代表总计数。
+	total uint64
+	// byType 代表MIME类型与计数的映射。
+	byType map[string]uint64
+}
+
+// incr 用于增加给定MIME类型的计数。
+func (uc *unmatchedCounter) incr(mimeType string) {
+	if mimeType == "" {
+		mimeType = unknownMIMEType
+	}
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+	if uc.byType == nil {
+		uc.byType = map[string]uint64{}
+	}
+	uc.total++
+	uc.byType[mimeType]++
+}
+
+// get 用于获取总计数以及MIME类型与计数的映射的副本。
+func (uc *unmatchedCounter) get() (uint64, map[string]uint64) {
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+	if len(uc.byType) == 0 {
+		return uc.total, nil
+	}
+	byType := make(map[string]uint64, len(uc.byType))
+	for k, v := range uc.byType {
+		byType[k] = v
+	}
+	return uc.total, byType
+}
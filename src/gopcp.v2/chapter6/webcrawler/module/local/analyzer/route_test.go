@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestRouteMatch(t *testing.T) {
+	r, err := newRoute(Route{
+		MIMETypes:  []string{" Image/* ", "text/html", ""},
+		URLPattern: `^https?://example\.com/`,
+		Parser:     genTestingRespParser(false),
+	})
+	if err != nil {
+		t.Fatalf("An error occurs when creating a route: %s", err)
+	}
+	if !r.needMIMEType() {
+		t.Fatalf("Inconsistent MIME type requirement: expected: %v, actual: %v",
+			true, r.needMIMEType())
+	}
+	cases := []struct {
+		mimeType string
+		url      string
+		expected bool
+	}{
+		{"image/png", "http://example.com/a.png", true},
+		{"text/html", "https://example.com/", true},
+		{"text/css", "https://example.com/a.css", false},
+		{"image/png", "https://other.com/a.png", false},
+		{"", "https://example.com/", false},
+	}
+	for _, c := range cases {
+		if actual := r.match(c.mimeType, c.url); actual != c.expected {
+			t.Fatalf("Inconsistent matching result: expected: %v, actual: %v (MIME type: %q, URL: %s)",
+				c.expected, actual, c.mimeType, c.url)
+		}
+	}
+	r = &route{parser: genTestingRespParser(false)}
+	if r.needMIMEType() || !r.match("", "https://example.com/") {
+		t.Fatalf("The route without patterns should match any response!")
+	}
+}
+
+func TestUnmatchedCounter(t *testing.T) {
+	var uc unmatchedCounter
+	if total, byType := uc.get(); total != 0 || byType != nil {
+		t.Fatalf("Inconsistent unmatched count: expected: %d and %v, actual: %d and %v",
+			0, nil, total, byType)
+	}
+	uc.incr("text/css")
+	uc.incr("text/css")
+	uc.incr("")
+	total, byType := uc.get()
+	if total != 3 || byType["text/css"] != 2 || byType[unknownMIMEType] != 1 {
+		t.Fatalf("Inconsistent unmatched count: total: %d, by type: %v", total, byType)
+	}
+	byType["text/css"] = 0
+	if _, byType := uc.get(); byType["text/css"] != 2 {
+		t.Fatalf("The unmatched count has been modified from outside!")
+	}
+}
@@ -0,0 +1,168 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// limitedBody 代表限制了最大字节数的响应体。
+type limitedBody struct {
+	// rc 代表原始的响应体。
+	rc io.ReadCloser
+	// limit 代表最大字节数。
+	limit int64
+	// read 代表已读取的字节数。
+	read int64
+	// url 代表响应对应的请求的URL，仅用于生成错误信息。
+	url string
+}
+
+// newLimitedBody 用于创建一个限制了最大字节数的响应体。
+func newLimitedBody(rc io.ReadCloser, limit int64, url string) io.ReadCloser {
+	return &limitedBody{rc: rc, limit: limit, url: url}
+}
+
+// Read 会在读取的字节数超过最大字节数时返回错误。
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.read > lb.limit {
+		return 0, lb.tooLarge()
+	}
+	// 多读取1个字节，以便判断响应体是否超过了最大字节数。
+	if remaining := lb.limit + 1 - lb.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lb.rc.Read(p)
+	lb.read += int64(n)
+	if lb.read > lb.limit {
+		return n - int(lb.read-lb.limit), lb.tooLarge()
+	}
+	return n, err
+}
+
+func (lb *limitedBody) Close() error {
+	return lb.rc.Close()
+}
+
+// tooLarge 用于生成响应体过大的错误值。
+func (lb *limitedBody) tooLarge() error {
+	return genError(fmt.Sprintf("too large response body: more than %d bytes (URL: %s)",
+		lb.limit, lb.url))
+}
+
+// cancelBody 代表会在关闭时取消请求的上下文的响应体。
+// 它用于释放请求的上下文所占用的资源。
+type cancelBody struct {
+	io.ReadCloser
+	// cancel 代表请求的上下文的取消函数。
+	cancel context.CancelFunc
+}
+
+func (cb *cancelBody) Close() error {
+	err := cb.ReadCloser.Close()
+	cb.cancel()
+	return err
+}
+
+// recordingMemoryLimit 代表在记录响应体时可以保存在内存中的最大字节数。
+// 超出该大小的响应体会被写入临时文件。
+const recordingMemoryLimit = 1024 * 1024
+
+// recordingBody 代表会在被完整读取或关闭后把已读取的内容交给记录函数的响应体。
+// 内容会随着读取被逐步保存，所以读取者不必等待记录完成。
+// 若在读取过程中发生了错误，则不会记录。
+type recordingBody struct {
+	// rc 代表原始的响应体。
+	rc io.ReadCloser
+	// record 代表记录函数。参数body代表完整的响应体，只在记录函数返回前可用。
+	record func(body io.ReadSeeker)
+	// buf 代表保存在内存中的已读取的内容。
+	buf bytes.Buffer
+	// file 代表保存已读取的内容的临时文件。
+	file *os.File
+	// failed 代表读取或保存内容时是否发生过错误。
+	failed bool
+	// done 代表是否已完成记录。
+	done bool
+}
+
+// newRecordingBody 用于创建一个会把已读取的内容交给给定记录函数的响应体。
+func newRecordingBody(rc io.ReadCloser, record func(body io.ReadSeeker)) *recordingBody {
+	return &recordingBody{rc: rc, record: record}
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.rc.Read(p)
+	if n > 0 && !rb.failed && !rb.done {
+		rb.save(p[:n])
+	}
+	if err == io.EOF {
+		rb.finish()
+	} else if err != nil {
+		rb.failed = true
+	}
+	return n, err
+}
+
+// save 用于保存已读取的内容。
+func (rb *recordingBody) save(p []byte) {
+	if rb.file == nil && rb.buf.Len()+len(p) > recordingMemoryLimit {
+		file, err := ioutil.TempFile("", "webcrawler-record-")
+		if err == nil {
+			_, err = file.Write(rb.buf.Bytes())
+		}
+		rb.file = file
+		rb.buf = bytes.Buffer{}
+		if err != nil {
+			logger.Warnf("Couldn't create temp file for recording: %s\n", err)
+			rb.failed = true
+			return
+		}
+	}
+	if rb.file == nil {
+		rb.buf.Write(p)
+		return
+	}
+	if _, err := rb.file.Write(p); err != nil {
+		logger.Warnf("Couldn't write temp file for recording: %s\n", err)
+		rb.failed = true
+	}
+}
+
+// finish 用于把已保存的内容交给记录函数，并清理临时文件。
+func (rb *recordingBody) finish() {
+	if rb.done {
+		return
+	}
+	rb.done = true
+	if rb.file != nil {
+		defer func() {
+			rb.file.Close()
+			os.Remove(rb.file.Name())
+		}()
+	}
+	if rb.failed {
+		return
+	}
+	var body io.ReadSeeker = bytes.NewReader(rb.buf.Bytes())
+	if rb.file != nil {
+		if _, err := rb.file.Seek(0, io.SeekStart); err != nil {
+			logger.Warnf("Couldn't rewind temp file for recording: %s\n", err)
+			return
+		}
+		body = rb.file
+	}
+	rb.record(body)
+}
+
+// Close 会在关闭前读取剩余的内容，以保证记录的内容是完整的。
+func (rb *recordingBody) Close() error {
+	if !rb.done && !rb.failed {
+		io.Copy(ioutil.Discard, rb)
+	}
+	rb.finish()
+	return rb.rc.Close()
+}
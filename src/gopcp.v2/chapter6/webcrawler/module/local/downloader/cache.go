@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+)
+
+// MaxCacheEntrySize 代表可以被缓存的响应体的最大字节数。
+// 超出该大小的响应不会被缓存。
+const MaxCacheEntrySize = 10 * 1024 * 1024
+
+// httpCache 代表下载器使用的HTTP缓存。
+type httpCache struct {
+	// store 代表缓存存储。
+	store httpcache.Store
+	// hits 代表由新鲜的缓存直接提供响应的计数。
+	hits uint64
+	// revalidated 代表服务端返回304状态码后由缓存提供响应的计数。
+	revalidated uint64
+	// misses 代表未能由缓存提供响应的计数。
+	misses uint64
+}
+
+// cacheSummaryStruct 代表HTTP缓存的摘要类型。
+type cacheSummaryStruct struct {
+	Hits        uint64 `json:"cache_hits"`
+	Revalidated uint64 `json:"cache_revalidated"`
+	Misses      uint64 `json:"cache_misses"`
+}
+
+// summary 用于获取HTTP缓存的摘要。
+func (cache *httpCache) summary() cacheSummaryStruct {
+	return cacheSummaryStruct{
+		Hits:        atomic.LoadUint64(&cache.hits),
+		Revalidated: atomic.LoadUint64(&cache.revalidated),
+		Misses:      atomic.LoadUint64(&cache.misses),
+	}
+}
+
+// doWithCache 用于在使用HTTP缓存的情况下执行请求。
+// 若缓存是新鲜的，则直接由缓存提供响应；
+// 否则会发送带有验证器的条件请求，并在服务端返回304状态码时由缓存提供响应。
+// 可以被缓存的响应会在其响应体被完整读取后存入缓存。
+func (downloader *myDownloader) doWithCache(httpReq *http.Request) (*http.Response, error) {
+	if httpReq.Method != "GET" {
+		return downloader.do(httpReq)
+	}
+	cache := downloader.cache
+	key := httpcache.Key(httpReq)
+	entry, err := cache.store.Get(key)
+	if err != nil {
+		logger.Warnf("Couldn't get the cache entry: %s (key: %s)\n", err, key)
+		entry = nil
+	}
+	if entry != nil && entry.Fresh(httpReq, time.Now()) {
+		atomic.AddUint64(&cache.hits, 1)
+		return entry.Response(httpReq, httpcache.FROM_CACHE_FRESH), nil
+	}
+	outReq := httpReq
+	if entry != nil {
+		conditionalReq := httpReq.Clone(httpReq.Context())
+		if entry.SetConditional(conditionalReq) {
+			outReq = conditionalReq
+		}
+	}
+	httpResp, err := downloader.do(outReq)
+	if err != nil {
+		return nil, err
+	}
+	// 不让后续的处理看到条件请求头。
+	httpResp.Request = httpReq
+	if httpResp.StatusCode == http.StatusNotModified && outReq != httpReq {
+		if httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+		updated := entry.Revalidated(httpResp, time.Now())
+		if err := cache.store.Set(key, updated); err != nil {
+			logger.Warnf("Couldn't update the cache entry: %s (key: %s)\n", err, key)
+		}
+		atomic.AddUint64(&cache.revalidated, 1)
+		return updated.Response(httpReq, httpcache.FROM_CACHE_REVALIDATED), nil
+	}
+	atomic.AddUint64(&cache.misses, 1)
+	if !httpcache.Cacheable(httpResp) || httpResp.Body == nil {
+		if entry != nil {
+			if err := cache.store.Delete(key); err != nil {
+				logger.Warnf("Couldn't delete the cache entry: %s (key: %s)\n", err, key)
+			}
+		}
+		return httpResp, nil
+	}
+	httpResp.Body = &cachingBody{
+		rc:    httpResp.Body,
+		limit: MaxCacheEntrySize,
+		save: func(body []byte) {
+			newEntry := httpcache.NewEntry(httpResp, body, time.Now())
+			if err := cache.store.Set(key, newEntry); err != nil {
+				logger.Warnf("Couldn't store the cache entry: %s (key: %s)\n", err, key)
+			}
+		},
+	}
+	return httpResp, nil
+}
+
+// cachingBody 代表会在被完整读取后把内容存入缓存的响应体。
+type cachingBody struct {
+	// rc 代表原始的响应体。
+	rc io.ReadCloser
+	// buf 代表已读取的内容。
+	buf bytes.Buffer
+	// limit 代表可以被缓存的最大字节数。
+	limit int64
+	// overflow 代表内容是否超过了可以被缓存的最大字节数。
+	overflow bool
+	// saved 代表内容是否已被存入缓存。
+	saved bool
+	// save 代表把内容存入缓存的函数。
+	save func(body []byte)
+}
+
+func (cb *cachingBody) Read(p []byte) (int, error) {
+	n, err := cb.rc.Read(p)
+	if n > 0 && !cb.overflow {
+		if int64(cb.buf.Len()+n) > cb.limit {
+			cb.overflow = true
+			cb.buf = bytes.Buffer{}
+		} else {
+			cb.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !cb.overflow && !cb.saved {
+		cb.saved = true
+		cb.save(cb.buf.Bytes())
+	}
+	return n, err
+}
+
+func (cb *cachingBody) Close() error {
+	return cb.rc.Close()
+}
@@ -0,0 +1,112 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+)
+
+func TestDownloadWithCache(t *testing.T) {
+	var requests, fullResponses uint64
+	mux := http.NewServeMux()
+	// 需要验证的页面。
+	mux.HandleFunc("/etag.html", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddUint64(&fullResponses, 1)
+		fmt.Fprint(w, "etag")
+	})
+	// 新鲜的页面。
+	mux.HandleFunc("/fresh.html", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		atomic.AddUint64(&fullResponses, 1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		fmt.Fprint(w, "fresh")
+	})
+	// 不可被缓存的页面。
+	mux.HandleFunc("/nostore.html", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&requests, 1)
+		atomic.AddUint64(&fullResponses, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, "nostore")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	d, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithCache(httpcache.NewMemoryStore()))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	download := func(path string) (string, string) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("An error occurs when downloading content: %s (path: %s)", err, path)
+		}
+		httpResp := resp.HTTPResp()
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			t.Fatalf("An error occurs when reading body: %s (path: %s)", err, path)
+		}
+		if httpResp.StatusCode != http.StatusOK {
+			t.Fatalf("Inconsistent status code: expected: %d, actual: %d (path: %s)",
+				http.StatusOK, httpResp.StatusCode, path)
+		}
+		if httpResp.Request.Header.Get("If-None-Match") != "" {
+			t.Fatalf("The conditional header has been exposed! (path: %s)", path)
+		}
+		return string(body), httpResp.Header.Get(httpcache.HEADER_FROM_CACHE)
+	}
+	cases := []struct {
+		path      string
+		body      string
+		fromCache string
+	}{
+		{"/etag.html", "etag", ""},
+		{"/etag.html", "etag", httpcache.FROM_CACHE_REVALIDATED},
+		{"/etag.html", "etag", httpcache.FROM_CACHE_REVALIDATED},
+		{"/fresh.html", "fresh", ""},
+		{"/fresh.html", "fresh", httpcache.FROM_CACHE_FRESH},
+		{"/nostore.html", "nostore", ""},
+		{"/nostore.html", "nostore", ""},
+	}
+	for i, c := range cases {
+		body, fromCache := download(c.path)
+		if body != c.body || fromCache != c.fromCache {
+			t.Fatalf("Inconsistent response: expected: %q (from cache: %q), actual: %q (from cache: %q) (index: %d)",
+				c.body, c.fromCache, body, fromCache, i)
+		}
+	}
+	if requests != 6 || fullResponses != 4 {
+		t.Fatalf("Inconsistent server counts: expected: requests: %d, full responses: %d, actual: requests: %d, full responses: %d",
+			6, 4, requests, fullResponses)
+	}
+	extra, ok := d.Summary().Extra.(extraSummaryStruct)
+	if !ok || extra.Cache == nil {
+		t.Fatalf("Inconsistent extra summary: expected: %T with cache summary, actual: %#v",
+			extraSummaryStruct{}, d.Summary().Extra)
+	}
+	expectedExtra := cacheSummaryStruct{Hits: 1, Revalidated: 2, Misses: 4}
+	if *extra.Cache != expectedExtra {
+		t.Fatalf("Inconsistent extra summary: expected: %#v, actual: %#v",
+			expectedExtra, *extra.Cache)
+	}
+	// 未使用缓存时，摘要信息中没有额外信息。
+	d, _ = New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil)
+	if extra := d.Summary().Extra; extra != nil {
+		t.Fatalf("Inconsistent extra summary: expected: %v, actual: %#v", nil, extra)
+	}
+}
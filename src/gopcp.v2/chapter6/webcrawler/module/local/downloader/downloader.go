@@ -1,10 +1,16 @@
 package downloader
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"reflect"
+	"time"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/module/stub"
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+	"gopcp.v2/chapter6/webcrawler/toolkit/warc"
 	"gopcp.v2/helper/log"
 )
 
@@ -15,7 +21,8 @@ var logger = log.DLogger()
 func New(
 	mid module.MID,
 	client *http.Client,
-	scoreCalculator module.CalculateScore) (module.Downloader, error) {
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Downloader, error) {
 	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
 	if err != nil {
 		return nil, err
@@ -23,10 +30,29 @@ func New(
 	if client == nil {
 		return nil, genParameterError("nil http client")
 	}
-	return &myDownloader{
+	downloader := &myDownloader{
 		ModuleInternal: moduleBase,
 		httpClient:     *client,
-	}, nil
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(downloader)
+		}
+	}
+	if downloader.proxyRules != nil {
+		proxies, err := newProxyPool(downloader.proxyRules,
+			downloader.maxProxyFailures, downloader.proxyCooldown)
+		if err != nil {
+			return nil, genParameterError(err.Error())
+		}
+		transport, err := proxyTransport(downloader.httpClient.Transport)
+		if err != nil {
+			return nil, genParameterError(err.Error())
+		}
+		downloader.httpClient.Transport = transport
+		downloader.proxies = proxies
+	}
+	return downloader, nil
 }
 
 // myDownloader 代表下载器的实现类型。
@@ -35,6 +61,28 @@ type myDownloader struct {
 	stub.ModuleInternal
 	// httpClient 代表下载用的HTTP客户端。
 	httpClient http.Client
+	// maxBodySize 代表响应体的最大字节数。0代表不限制。
+	maxBodySize int64
+	// cache 代表HTTP缓存。若为nil，则代表不使用缓存。
+	cache *httpCache
+	// warcWriter 代表WARC文件写入器。若为nil，则代表不记录HTTP交互。
+	warcWriter warc.Writer
+	// recorder 代表录制响应用的夹具存储。若为nil，则代表不录制。
+	recorder httpcache.Store
+	// headers 代表默认的请求头。
+	headers http.Header
+	// userAgents 代表用户代理池。若为nil，则代表不轮换用户代理。
+	userAgents *userAgentPool
+	// proxyRules 代表按主机选择代理的规则的列表。
+	proxyRules []ProxyRule
+	// maxProxyFailures 代表代理被标记为不健康之前允许的连续失败次数。
+	maxProxyFailures uint32
+	// proxyCooldown 代表不健康的代理恢复可用之前需要等待的时长。
+	proxyCooldown time.Duration
+	// proxies 代表代理池。若为nil，则代表不使用代理池。
+	proxies *proxyPool
+	// timeout 代表每个请求等待响应头的超时时间。0代表不限制。
+	timeout time.Duration
 }
 
 func (downloader *myDownloader) Download(req *module.Request) (*module.Response, error) {
@@ -50,10 +98,116 @@ func (downloader *myDownloader) Download(req *module.Request) (*module.Response,
 	}
 	downloader.ModuleInternal.IncrAcceptedCount()
 	logger.Infof("Do the request (URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
-	httpResp, err := downloader.httpClient.Do(httpReq)
+	httpReq = downloader.prepare(httpReq)
+	var httpResp *http.Response
+	var err error
+	if downloader.cache != nil {
+		httpResp, err = downloader.doWithCache(httpReq)
+	} else {
+		httpResp, err = downloader.do(httpReq)
+	}
 	if err != nil {
 		return nil, err
 	}
+	// 在下载器的层面录制，以便同时录制由缓存提供的响应。
+	if downloader.recorder != nil && httpResp.Body != nil &&
+		httpResp.StatusCode != http.StatusNotModified {
+		httpResp.Body = downloader.newFixtureBody(httpReq, httpResp)
+	}
 	downloader.ModuleInternal.IncrCompletedCount()
-	return module.NewResponse(httpResp, req.Depth()), nil
+	return module.NewResponse(httpResp, req.Depth()).WithMeta(req.Meta()), nil
+}
+
+// do 用于执行请求，并按照设置选择代理、限制超时时间以及限制响应体的最大字节数。
+func (downloader *myDownloader) do(httpReq *http.Request) (*http.Response, error) {
+	var p *proxy
+	if downloader.proxies != nil {
+		var err error
+		if p, err = downloader.proxies.pick(httpReq.URL.Hostname()); err != nil {
+			return nil, genError(fmt.Sprintf("%s (URL: %s)", err, httpReq.URL))
+		}
+	}
+	httpResp, err := downloader.send(httpReq, p)
+	if downloader.proxies != nil {
+		downloader.proxies.report(p,
+			err == nil && httpResp.StatusCode != http.StatusProxyAuthRequired)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if limit := downloader.maxBodySize; limit > 0 && httpResp.Body != nil {
+		if httpResp.ContentLength > limit {
+			httpResp.Body.Close()
+			errMsg := fmt.Sprintf("too large response body: %d bytes, limit: %d bytes (URL: %s)",
+				httpResp.ContentLength, limit, httpReq.URL)
+			return nil, genError(errMsg)
+		}
+		httpResp.Body = newLimitedBody(httpResp.Body, limit, httpReq.URL.String())
+	}
+	if downloader.warcWriter != nil && httpResp.Body != nil &&
+		httpResp.StatusCode != http.StatusNotModified {
+		httpResp.Body = newWARCBody(httpResp, downloader.warcWriter)
+	}
+	return httpResp, nil
+}
+
+// send 用于经由给定的代理发送请求，并按照设置限制超时时间。
+// 超时的上下文从请求的上下文派生，所以调度器停止时请求也会被取消。
+func (downloader *myDownloader) send(httpReq *http.Request, p *proxy) (*http.Response, error) {
+	if downloader.timeout <= 0 {
+		return downloader.httpClient.Do(withProxy(httpReq, p))
+	}
+	// 超时时间只限制建立连接和等待响应头的过程。
+	// 响应体可能会在响应缓冲池中等待很久才被读取，因此不能受其限制。
+	ctx, cancel := context.WithCancel(httpReq.Context())
+	timer := time.AfterFunc(downloader.timeout, cancel)
+	httpResp, err := downloader.httpClient.Do(withProxy(httpReq.WithContext(ctx), p))
+	if !timer.Stop() {
+		// 即使响应头恰好在超时的同时到达，其响应体也已无法读取。
+		if err == nil && httpResp.Body != nil {
+			httpResp.Body.Close()
+		}
+		cancel()
+		return nil, genError(fmt.Sprintf("no response header within %s (URL: %s)",
+			downloader.timeout, httpReq.URL))
+	}
+	if err != nil || httpResp.Body == nil {
+		cancel()
+		return httpResp, err
+	}
+	httpResp.Body = &cancelBody{ReadCloser: httpResp.Body, cancel: cancel}
+	return httpResp, nil
+}
+
+// extraSummaryStruct 代表下载器的额外摘要信息的类型。
+type extraSummaryStruct struct {
+	Cache           *cacheSummaryStruct  `json:"cache,omitempty"`
+	Headers         []string             `json:"default_headers,omitempty"`
+	UserAgents      int                  `json:"user_agents,omitempty"`
+	UserAgentPolicy string               `json:"user_agent_policy,omitempty"`
+	Proxies         []proxySummaryStruct `json:"proxies,omitempty"`
+	Timeout         string               `json:"request_timeout,omitempty"`
+}
+
+func (downloader *myDownloader) Summary() module.SummaryStruct {
+	summary := downloader.ModuleInternal.Summary()
+	extra := extraSummaryStruct{Headers: downloader.headerNames()}
+	if downloader.cache != nil {
+		cacheSummary := downloader.cache.summary()
+		extra.Cache = &cacheSummary
+	}
+	if pool := downloader.userAgents; pool != nil {
+		extra.UserAgents = len(pool.agents)
+		extra.UserAgentPolicy = pool.policy.String()
+	}
+	if downloader.proxies != nil {
+		extra.Proxies = downloader.proxies.summary()
+	}
+	if downloader.timeout > 0 {
+		extra.Timeout = downloader.timeout.String()
+	}
+	if !reflect.DeepEqual(extra, extraSummaryStruct{}) {
+		summary.Extra = extra
+	}
+	return summary
 }
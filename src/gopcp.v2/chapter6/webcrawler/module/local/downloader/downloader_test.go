@@ -2,7 +2,12 @@ package downloader
 
 import (
 	"bufio"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"gopcp.v2/chapter6/webcrawler/module"
@@ -37,6 +42,83 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestDownloadMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ok")
+		}))
+	defer server.Close()
+	d, _ := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil)
+	httpReq, _ := http.NewRequest("GET", server.URL, nil)
+	meta := module.Meta{
+		ParentURL: "https://github.com/gopcp",
+		Values:    map[string]string{"team": "gopcp"},
+	}
+	req := module.NewRequest(httpReq, 1).Retry().WithMeta(meta)
+	resp, err := d.Download(req)
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s (req: %#v)",
+			err, req)
+	}
+	defer resp.HTTPResp().Body.Close()
+	actual := resp.Meta()
+	if actual.ParentURL != meta.ParentURL || actual.Value("team") != "gopcp" {
+		t.Fatalf("Inconsistent meta: expected: %#v, actual: %#v", meta, actual)
+	}
+	if actual.Attempt != 2 {
+		t.Fatalf("Inconsistent attempt: expected: %d, actual: %d", 2, actual.Attempt)
+	}
+}
+
+func TestDownloadMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+			if r.URL.Query().Get("chunked") != "" {
+				// 不声明内容长度。
+				w.(http.Flusher).Flush()
+			}
+			w.Write([]byte(strings.Repeat("x", size)))
+		}))
+	defer server.Close()
+	d, _ := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithMaxBodySize(10))
+	// 响应体未超过最大字节数。
+	for _, query := range []string{"size=10", "size=10&chunked=1"} {
+		httpReq, _ := http.NewRequest("GET", server.URL+"/?"+query, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("An error occurs when downloading content: %s (query: %s)",
+				err, query)
+		}
+		body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+		resp.HTTPResp().Body.Close()
+		if err != nil || len(body) != 10 {
+			t.Fatalf("Inconsistent body: expected: %d bytes, actual: %d bytes, error: %v (query: %s)",
+				10, len(body), err, query)
+		}
+	}
+	// 响应头中声明的内容长度超过了最大字节数。
+	httpReq, _ := http.NewRequest("GET", server.URL+"/?size=11", nil)
+	if _, err := d.Download(module.NewRequest(httpReq, 0)); err == nil {
+		t.Fatalf("No error when downloading too large content!")
+	}
+	// 读取时才发现超过了最大字节数。
+	httpReq, _ = http.NewRequest("GET", server.URL+"/?size=11&chunked=1", nil)
+	resp, err := d.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	defer resp.HTTPResp().Body.Close()
+	body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+	if err == nil {
+		t.Fatalf("No error when reading too large body!")
+	}
+	if len(body) != 10 {
+		t.Fatalf("Inconsistent body size: expected: %d, actual: %d", 10, len(body))
+	}
+}
+
 func TestDownload(t *testing.T) {
 	mid := module.MID("D1|127.0.0.1:8080")
 	httpClient := &http.Client{}
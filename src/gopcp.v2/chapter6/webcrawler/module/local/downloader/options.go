@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"net/http"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+	"gopcp.v2/chapter6/webcrawler/toolkit/warc"
+)
+
+// Option 代表下载器的选项。
+type Option func(downloader *myDownloader)
+
+// WithMaxBodySize 用于设置响应体的最大字节数。
+// 若响应头中声明的内容长度超过该值，则下载会直接失败；
+// 否则在读取响应体时，一旦读取的字节数超过该值就会返回错误。
+// 参数size小于等于0时代表不限制。
+func WithMaxBodySize(size int64) Option {
+	return func(downloader *myDownloader) {
+		downloader.maxBodySize = size
+	}
+}
+
+// WithCache 用于设置下载器使用的HTTP缓存存储。
+// 设置后，下载器会根据Cache-Control、ETag和Last-Modified等响应头复用已下载的内容，
+// 并在摘要信息中记录缓存的命中情况。
+func WithCache(store httpcache.Store) Option {
+	return func(downloader *myDownloader) {
+		if store != nil {
+			downloader.cache = &httpCache{store: store}
+		}
+	}
+}
+
+// WithWARC 用于设置下载器使用的WARC文件写入器。
+// 设置后，从网络获取的每个响应都会在其响应体被完整读取或关闭后，
+// 连同对应的请求一起被写入WARC文件。由缓存直接提供的响应不会被写入。
+// 写入器需要由调用方在爬取结束后关闭。
+func WithWARC(writer warc.Writer) Option {
+	return func(downloader *myDownloader) {
+		downloader.warcWriter = writer
+	}
+}
+
+// WithHeaders 用于设置默认的请求头。
+// 它们只会被添加到没有同名请求头的请求中，原请求不会被改变。
+func WithHeaders(header http.Header) Option {
+	return func(downloader *myDownloader) {
+		downloader.headers = header.Clone()
+	}
+}
+
+// WithUserAgents 用于设置用户代理池及其轮换策略。
+// 用户代理只会被添加到没有User-Agent请求头的请求中，
+// 并且会覆盖默认的请求头中的User-Agent。参数agents为空时代表不轮换。
+func WithUserAgents(policy UserAgentPolicy, agents ...string) Option {
+	return func(downloader *myDownloader) {
+		if len(agents) == 0 {
+			downloader.userAgents = nil
+			return
+		}
+		downloader.userAgents = &userAgentPool{
+			policy:     policy,
+			agents:     append([]string(nil), agents...),
+			hostAgents: map[string]string{},
+		}
+	}
+}
+
+// WithProxies 用于设置按主机选择代理的规则。
+// 对于每个请求，第一个适用的规则中的健康代理会被轮流使用；
+// 若没有适用的规则，则直接连接。
+// 连续失败次数达到maxFailures（为0时视为1）的代理会被标记为不健康，
+// 并在冷却时长cooldown之后重新被尝试。cooldown为0时，不健康的代理不会再被使用。
+// 规则无效或HTTP客户端的传输不是*http.Transport类型时，创建下载器会失败。
+func WithProxies(rules []ProxyRule, maxFailures uint32, cooldown time.Duration) Option {
+	return func(downloader *myDownloader) {
+		downloader.proxyRules = append([]ProxyRule{}, rules...)
+		downloader.maxProxyFailures = maxFailures
+		downloader.proxyCooldown = cooldown
+	}
+}
+
+// WithTimeout 用于设置每个请求的超时时间，包括建立连接和等待响应头的时间，
+// 但不包括读取响应体的时间。
+// 请求的上下文从原请求的上下文派生，所以调度器停止时请求也会被取消。
+// 参数timeout小于等于0时代表不限制。
+func WithTimeout(timeout time.Duration) Option {
+	return func(downloader *myDownloader) {
+		downloader.timeout = timeout
+	}
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// UserAgentPolicy 代表用户代理的轮换策略的类型。
+type UserAgentPolicy uint8
+
+// 用户代理的轮换策略的常量。
+const (
+	// ROTATE_PER_REQUEST 代表每个请求都依次使用下一个用户代理。
+	ROTATE_PER_REQUEST UserAgentPolicy = iota
+	// ROTATE_PER_HOST 代表同一主机的请求总是使用同一个用户代理，
+	// 不同的主机依次使用下一个用户代理。
+	ROTATE_PER_HOST
+)
+
+// String 用于获取策略的名称。
+func (policy UserAgentPolicy) String() string {
+	switch policy {
+	case ROTATE_PER_REQUEST:
+		return "per_request"
+	case ROTATE_PER_HOST:
+		return "per_host"
+	default:
+		return "unknown"
+	}
+}
+
+// userAgentPool 代表用户代理池。
+type userAgentPool struct {
+	// policy 代表轮换策略。
+	policy UserAgentPolicy
+	// agents 代表用户代理的列表。
+	agents []string
+	// lock 代表保护轮换状态的互斥锁。
+	lock sync.Mutex
+	// next 代表下一个用户代理的索引。
+	next int
+	// hostAgents 代表主机与其用户代理的映射。
+	hostAgents map[string]string
+}
+
+// pick 用于为给定的主机选取用户代理。
+func (pool *userAgentPool) pick(host string) string {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.policy == ROTATE_PER_HOST {
+		if agent, ok := pool.hostAgents[host]; ok {
+			return agent
+		}
+	}
+	agent := pool.agents[pool.next]
+	pool.next = (pool.next + 1) % len(pool.agents)
+	if pool.policy == ROTATE_PER_HOST {
+		pool.hostAgents[host] = agent
+	}
+	return agent
+}
+
+// prepare 用于按照设置为请求添加默认的请求头和用户代理。
+// 请求中已有的请求头会被保留，默认的请求头中的用户代理会被用户代理池中的覆盖。
+// 若需要添加请求头，则会返回原请求的副本，原请求不会被改变。
+func (downloader *myDownloader) prepare(httpReq *http.Request) *http.Request {
+	if len(downloader.headers) == 0 && downloader.userAgents == nil {
+		return httpReq
+	}
+	prepared := httpReq.Clone(httpReq.Context())
+	for key, values := range downloader.headers {
+		if _, ok := prepared.Header[key]; !ok {
+			prepared.Header[key] = append([]string(nil), values...)
+		}
+	}
+	if pool := downloader.userAgents; pool != nil &&
+		httpReq.Header.Get("User-Agent") == "" {
+		prepared.Header.Set("User-Agent", pool.pick(httpReq.URL.Hostname()))
+	}
+	return prepared
+}
+
+// headerNames 用于获取已排序的默认请求头的名称的列表。
+func (downloader *myDownloader) headerNames() []string {
+	if len(downloader.headers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(downloader.headers))
+	for name := range downloader.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,159 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestDownloadWithHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("User-Agent") + "|" + r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+	header := http.Header{}
+	header.Set("Accept-Language", "zh-CN")
+	header.Set("User-Agent", "default")
+	d, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithHeaders(header), WithUserAgents(ROTATE_PER_REQUEST, "ua1", "ua2"))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	download := func(d module.Downloader, rawURL string, userAgent string) string {
+		httpReq, _ := http.NewRequest("GET", rawURL, nil)
+		if userAgent != "" {
+			httpReq.Header.Set("User-Agent", userAgent)
+		}
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Fatalf("An error occurs when downloading content: %s", err)
+		}
+		defer resp.HTTPResp().Body.Close()
+		body, _ := ioutil.ReadAll(resp.HTTPResp().Body)
+		if len(httpReq.Header) > 1 {
+			t.Fatalf("The original request has been changed! (header: %v)", httpReq.Header)
+		}
+		return string(body)
+	}
+	cases := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"", "ua1|zh-CN"},
+		{"", "ua2|zh-CN"},
+		{"mine", "mine|zh-CN"},
+		{"", "ua1|zh-CN"},
+	}
+	for i, c := range cases {
+		if actual := download(d, server.URL, c.userAgent); actual != c.expected {
+			t.Fatalf("Inconsistent headers: expected: %s, actual: %s (index: %d)",
+				c.expected, actual, i)
+		}
+	}
+	// 按主机轮换。
+	d, _ = New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithUserAgents(ROTATE_PER_HOST, "ua1", "ua2"))
+	localhostURL := fmt.Sprintf("http://localhost:%d",
+		server.Listener.Addr().(*net.TCPAddr).Port)
+	hostCases := []struct {
+		url      string
+		expected string
+	}{
+		{server.URL, "ua1|"},
+		{localhostURL, "ua2|"},
+		{server.URL, "ua1|"},
+		{localhostURL, "ua2|"},
+	}
+	for i, c := range hostCases {
+		if actual := download(d, c.url, ""); actual != c.expected {
+			t.Fatalf("Inconsistent user agent: expected: %s, actual: %s (index: %d)",
+				c.expected, actual, i)
+		}
+	}
+	extra, ok := d.Summary().Extra.(extraSummaryStruct)
+	if !ok || extra.UserAgents != 2 || extra.UserAgentPolicy != "per_host" {
+		t.Fatalf("Inconsistent extra summary: %#v", d.Summary().Extra)
+	}
+}
+
+func TestDownloadWithTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+		if r.URL.Path == "/slowbody" {
+			// 响应头及时到达，但响应体的读取时间超过了超时时间。
+			w.Write([]byte("o"))
+			w.(http.Flusher).Flush()
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("k"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	d, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	download := func(req *module.Request) error {
+		resp, err := d.Download(req)
+		if err != nil {
+			return err
+		}
+		defer resp.HTTPResp().Body.Close()
+		body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+		if err == nil && string(body) != "ok" {
+			err = fmt.Errorf("unexpected body %q", body)
+		}
+		return err
+	}
+	httpReq, _ := http.NewRequest("GET", server.URL+"/fast", nil)
+	if err := download(module.NewRequest(httpReq, 0)); err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	httpReq, _ = http.NewRequest("GET", server.URL+"/slow", nil)
+	if err := download(module.NewRequest(httpReq, 0)); err == nil {
+		t.Fatalf("No error when the request is timed out!")
+	}
+	// 超时时间不限制读取响应体的时间。
+	httpReq, _ = http.NewRequest("GET", server.URL+"/slowbody", nil)
+	if err := download(module.NewRequest(httpReq, 0)); err != nil {
+		t.Fatalf("An error occurs when downloading a slow body: %s", err)
+	}
+	// 响应体在被读取之前可以等待任意长的时间。
+	httpReq, _ = http.NewRequest("GET", server.URL+"/fast", nil)
+	resp, err := d.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+	resp.HTTPResp().Body.Close()
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("Inconsistent delayed body: expected: %q, actual: %q (error: %v)",
+			"ok", body, err)
+	}
+	// 超时的上下文从请求的上下文派生。
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	httpReq, _ = http.NewRequest("GET", server.URL+"/fast", nil)
+	if err := download(module.NewRequest(httpReq, 0).WithContext(ctx)); err == nil {
+		t.Fatalf("No error when the context is canceled!")
+	}
+	extra, ok := d.Summary().Extra.(extraSummaryStruct)
+	if !ok || extra.Timeout != "50ms" {
+		t.Fatalf("Inconsistent extra summary: %#v", d.Summary().Extra)
+	}
+}
@@ -0,0 +1,229 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+)
+
+// ProxyRule 代表按主机选择代理的规则。
+type ProxyRule struct {
+	// Hosts 代表适用的主机名的模式的列表，其语法与path.Match的相同，
+	// 如"*.example.com"。为空时代表适用于所有主机。
+	Hosts []string
+	// Proxies 代表可选的代理的URL的列表。为空时代表直接连接。
+	Proxies []string
+}
+
+// proxy 代表代理。
+type proxy struct {
+	// url 代表代理的URL。
+	url *url.URL
+	// failures 代表连续失败的次数。
+	failures uint32
+	// unhealthyUntil 代表代理恢复可用的时间。
+	unhealthyUntil time.Time
+	// requests 代表经由该代理的请求的总数。
+	requests uint64
+	// totalFailures 代表经由该代理的请求的失败总数。
+	totalFailures uint64
+}
+
+// proxyRule 代表已解析的按主机选择代理的规则。
+type proxyRule struct {
+	// hosts 代表适用的主机名的模式的列表。
+	hosts []string
+	// proxies 代表可选的代理的列表。
+	proxies []*proxy
+	// next 代表下一个被尝试的代理的索引。
+	next int
+}
+
+// match 用于判断规则是否适用于给定的主机。
+func (rule *proxyRule) match(host string) bool {
+	if len(rule.hosts) == 0 {
+		return true
+	}
+	for _, pattern := range rule.hosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// proxySummaryStruct 代表代理的摘要类型。
+type proxySummaryStruct struct {
+	URL      string `json:"url"`
+	Healthy  bool   `json:"healthy"`
+	Requests uint64 `json:"requests"`
+	Failures uint64 `json:"failures"`
+}
+
+// proxyPool 代表代理池。
+type proxyPool struct {
+	// rules 代表按主机选择代理的规则的列表。
+	rules []*proxyRule
+	// maxFailures 代表代理被标记为不健康之前允许的连续失败次数。
+	maxFailures uint32
+	// cooldown 代表不健康的代理恢复可用之前需要等待的时长。
+	cooldown time.Duration
+	// lock 代表保护代理状态的互斥锁。
+	lock sync.Mutex
+}
+
+// newProxyPool 用于创建一个代理池。
+func newProxyPool(rules []ProxyRule, maxFailures uint32, cooldown time.Duration) (*proxyPool, error) {
+	if maxFailures == 0 {
+		maxFailures = 1
+	}
+	pool := &proxyPool{maxFailures: maxFailures, cooldown: cooldown}
+	parsed := map[string]*proxy{}
+	for _, rule := range rules {
+		for _, pattern := range rule.Hosts {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid host pattern %q: %s", pattern, err)
+			}
+		}
+		newRule := &proxyRule{hosts: rule.Hosts}
+		for _, rawURL := range rule.Proxies {
+			// 同一个代理在多个规则中共享健康状态。
+			p, ok := parsed[rawURL]
+			if !ok {
+				proxyURL, err := url.Parse(rawURL)
+				if err != nil {
+					return nil, fmt.Errorf("invalid proxy URL %q: %s", rawURL, err)
+				}
+				if proxyURL.Scheme == "" || proxyURL.Host == "" {
+					return nil, fmt.Errorf("invalid proxy URL %q: missing scheme or host", rawURL)
+				}
+				p = &proxy{url: proxyURL}
+				parsed[rawURL] = p
+			}
+			newRule.proxies = append(newRule.proxies, p)
+		}
+		pool.rules = append(pool.rules, newRule)
+	}
+	return pool, nil
+}
+
+// pick 用于为给定的主机选取代理。
+// 若没有适用的规则或适用的规则中没有代理，则结果为nil，代表直接连接。
+// 若适用的规则中的代理都不健康，则会返回错误。
+func (pool *proxyPool) pick(host string) (*proxy, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	var rule *proxyRule
+	for _, r := range pool.rules {
+		if r.match(host) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil || len(rule.proxies) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	for i := 0; i < len(rule.proxies); i++ {
+		p := rule.proxies[(rule.next+i)%len(rule.proxies)]
+		if p.healthy(now, pool.cooldown) {
+			rule.next = (rule.next + i + 1) % len(rule.proxies)
+			p.requests++
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no healthy proxy for host %s", host)
+}
+
+// healthy 用于判断代理在给定的时间是否健康。
+// 冷却时长为0时，不健康的代理不会再恢复可用。
+func (p *proxy) healthy(now time.Time, cooldown time.Duration) bool {
+	if p.unhealthyUntil.IsZero() {
+		return true
+	}
+	return cooldown > 0 && !now.Before(p.unhealthyUntil)
+}
+
+// report 用于报告经由代理的请求的结果。
+// 连续失败的次数达到上限的代理会被标记为不健康。
+func (pool *proxyPool) report(p *proxy, ok bool) {
+	if p == nil {
+		return
+	}
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if ok {
+		p.failures = 0
+		p.unhealthyUntil = time.Time{}
+		return
+	}
+	p.failures++
+	p.totalFailures++
+	if p.failures >= pool.maxFailures {
+		p.unhealthyUntil = time.Now().Add(pool.cooldown)
+	}
+}
+
+// summary 用于获取代理池中所有代理的摘要。
+func (pool *proxyPool) summary() []proxySummaryStruct {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	now := time.Now()
+	seen := map[*proxy]bool{}
+	summaries := []proxySummaryStruct{}
+	for _, rule := range pool.rules {
+		for _, p := range rule.proxies {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			summaries = append(summaries, proxySummaryStruct{
+				URL:      p.url.Redacted(),
+				Healthy:  p.healthy(now, pool.cooldown),
+				Requests: p.requests,
+				Failures: p.totalFailures,
+			})
+		}
+	}
+	return summaries
+}
+
+// proxyKey 代表在请求的上下文中存放所选代理的键的类型。
+type proxyKey struct{}
+
+// proxyFromContext 用于从请求的上下文中获取所选代理的URL。
+// 它会被用作HTTP传输的代理函数。
+func proxyFromContext(httpReq *http.Request) (*url.URL, error) {
+	if p, ok := httpReq.Context().Value(proxyKey{}).(*proxy); ok && p != nil {
+		return p.url, nil
+	}
+	return nil, nil
+}
+
+// withProxy 用于生成一个其上下文携带所选代理的请求。
+func withProxy(httpReq *http.Request, p *proxy) *http.Request {
+	if p == nil {
+		return httpReq
+	}
+	return httpReq.WithContext(context.WithValue(httpReq.Context(), proxyKey{}, p))
+}
+
+// proxyTransport 用于生成一个使用代理池的HTTP传输。
+// 原传输不会被改变。目前只支持*http.Transport类型的传输。
+func proxyTransport(rt http.RoundTripper) (*http.Transport, error) {
+	var transport *http.Transport
+	switch t := rt.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("unsupported transport type %T for proxies", rt)
+	}
+	transport.Proxy = proxyFromContext
+	return transport, nil
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// genProxyServer 用于生成一个在响应体中标明自身名称的代理服务器。
+func genProxyServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + "|" + r.URL.String()))
+	}))
+}
+
+func TestDownloadWithProxies(t *testing.T) {
+	p1 := genProxyServer("p1")
+	defer p1.Close()
+	dead := genProxyServer("dead")
+	dead.Close()
+	direct := genProxyServer("direct")
+	defer direct.Close()
+	rules := []ProxyRule{
+		{Hosts: []string{"example.com", "*.example.com"}, Proxies: []string{p1.URL, dead.URL}},
+	}
+	d, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithProxies(rules, 2, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	download := func(d module.Downloader, rawURL string) (string, error) {
+		httpReq, _ := http.NewRequest("GET", rawURL, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			return "", err
+		}
+		defer resp.HTTPResp().Body.Close()
+		body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+		return string(body), err
+	}
+	cases := []struct {
+		url    string
+		via    string
+		failed bool
+	}{
+		{"http://www.example.com/a", "p1", false},
+		{"http://example.com/b", "", true},
+		{"http://www.example.com/c", "p1", false},
+		{"http://example.com/d", "", true},
+		// 连续失败两次之后，dead不再被使用。
+		{"http://example.com/e", "p1", false},
+		{"http://example.com/f", "p1", false},
+		// 没有适用的规则时直接连接。
+		{direct.URL + "/g", "direct", false},
+	}
+	for i, c := range cases {
+		body, err := download(d, c.url)
+		if (err != nil) != c.failed {
+			t.Fatalf("Inconsistent failure: expected: %v, actual: %v (index: %d)",
+				c.failed, err, i)
+		}
+		if !c.failed && !strings.HasPrefix(body, c.via+"|") {
+			t.Fatalf("Inconsistent route: expected: via %s, actual: %s (index: %d)",
+				c.via, body, i)
+		}
+	}
+	extra, ok := d.Summary().Extra.(extraSummaryStruct)
+	if !ok || len(extra.Proxies) != 2 {
+		t.Fatalf("Inconsistent extra summary: %#v", d.Summary().Extra)
+	}
+	expectedProxies := []proxySummaryStruct{
+		{URL: p1.URL, Healthy: true, Requests: 4, Failures: 0},
+		{URL: dead.URL, Healthy: false, Requests: 2, Failures: 2},
+	}
+	for i, expected := range expectedProxies {
+		if extra.Proxies[i] != expected {
+			t.Fatalf("Inconsistent proxy summary: expected: %#v, actual: %#v",
+				expected, extra.Proxies[i])
+		}
+	}
+	// 所有的代理都不健康时，下载会失败。
+	d, _ = New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithProxies([]ProxyRule{{Proxies: []string{dead.URL}}}, 1, 0))
+	download(d, "http://example.com/")
+	if _, err := download(d, "http://example.com/"); err == nil ||
+		!strings.Contains(err.Error(), "no healthy proxy") {
+		t.Fatalf("Inconsistent error: expected: no healthy proxy, actual: %v", err)
+	}
+	// 无效的设置。
+	invalidRules := [][]ProxyRule{
+		{{Proxies: []string{"127.0.0.1:8080"}}},
+		{{Hosts: []string{"["}, Proxies: []string{p1.URL}}},
+	}
+	for i, rules := range invalidRules {
+		if _, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+			WithProxies(rules, 1, 0)); err == nil {
+			t.Fatalf("No error when creating a downloader with invalid proxy rules! (index: %d)", i)
+		}
+	}
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+	if _, err := New(module.MID("D1|127.0.0.1:8080"), client, nil,
+		WithProxies(rules, 1, 0)); err == nil {
+		t.Fatalf("No error when creating a downloader with unsupported transport!")
+	}
+}
+
+// roundTripperFunc 代表以函数实现的HTTP传输。
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,195 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/stub"
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+)
+
+// NewRecorder 用于创建一个录制下载器。
+// 录制下载器与普通的下载器一样会访问网络，
+// 同时会把每次HTTP交互的响应以请求方法和URL为键存入参数dirPath代表的夹具目录中，
+// 以便之后由回放下载器离线重现。
+// 响应会在其响应体被完整读取或关闭之后才被存入，所以录制不会延迟响应的返回。
+func NewRecorder(
+	mid module.MID,
+	client *http.Client,
+	dirPath string,
+	scoreCalculator module.CalculateScore,
+	opts ...Option) (module.Downloader, error) {
+	store, err := httpcache.NewDiskStore(dirPath)
+	if err != nil {
+		return nil, genParameterError(fmt.Sprintf("invalid fixture directory: %s", err))
+	}
+	opts = append(opts, func(downloader *myDownloader) {
+		downloader.recorder = store
+	})
+	return New(mid, client, scoreCalculator, opts...)
+}
+
+// newFixtureBody 用于创建一个会在被完整读取或关闭后把响应存入夹具目录的响应体。
+// 键由原始请求生成，所以即使发生了重定向也可以按原始URL回放。
+// 由缓存提供的响应也会被存入，但其中的HEADER_FROM_CACHE头会被去掉。
+func (downloader *myDownloader) newFixtureBody(
+	httpReq *http.Request, httpResp *http.Response) io.ReadCloser {
+	key := httpcache.Key(httpReq)
+	storedAt := time.Now()
+	return newRecordingBody(httpResp.Body, func(body io.ReadSeeker) {
+		content, err := ioutil.ReadAll(body)
+		if err != nil {
+			logger.Warnf("Couldn't read the response to record: %s (key: %s)\n", err, key)
+			return
+		}
+		entry := httpcache.NewEntry(httpResp, content, storedAt)
+		entry.Header.Del(httpcache.HEADER_FROM_CACHE)
+		if err := downloader.recorder.Set(key, entry); err != nil {
+			logger.Warnf("Couldn't record the response: %s (key: %s)\n", err, key)
+		}
+	})
+}
+
+// ReplayOption 代表回放下载器的选项。
+type ReplayOption func(replayer *myReplayer)
+
+// WithLatency 用于设置回放下载器在返回每个响应之前的人为延迟。
+// 实际的延迟会在[latency, latency+jitter)的范围内随机选取。
+func WithLatency(latency time.Duration, jitter time.Duration) ReplayOption {
+	return func(replayer *myReplayer) {
+		if latency > 0 {
+			replayer.latency = latency
+		}
+		if jitter > 0 {
+			replayer.jitter = jitter
+		}
+	}
+}
+
+// WithFailureRate 用于设置回放下载器的故障注入比率。
+// 参数rate代表下载失败的概率，其取值范围是[0, 1]。
+func WithFailureRate(rate float64) ReplayOption {
+	return func(replayer *myReplayer) {
+		if rate < 0 {
+			rate = 0
+		} else if rate > 1 {
+			rate = 1
+		}
+		replayer.failureRate = rate
+	}
+}
+
+// WithSeed 用于设置回放下载器的随机数种子。
+// 相同的种子和相同的下载顺序会产生相同的延迟和故障。
+func WithSeed(seed int64) ReplayOption {
+	return func(replayer *myReplayer) {
+		replayer.random = rand.New(rand.NewSource(seed))
+	}
+}
+
+// NewReplayer 用于创建一个回放下载器。
+// 回放下载器不会访问网络，而是按照请求方法和URL
+// 从参数dirPath代表的夹具目录中查找由录制下载器存入的响应。
+// 找不到对应的响应时，下载会失败。
+func NewReplayer(
+	mid module.MID,
+	dirPath string,
+	scoreCalculator module.CalculateScore,
+	opts ...ReplayOption) (module.Downloader, error) {
+	moduleBase, err := stub.NewModuleInternal(mid, scoreCalculator)
+	if err != nil {
+		return nil, err
+	}
+	store, err := httpcache.NewDiskStore(dirPath)
+	if err != nil {
+		return nil, genParameterError(fmt.Sprintf("invalid fixture directory: %s", err))
+	}
+	replayer := &myReplayer{
+		ModuleInternal: moduleBase,
+		store:          store,
+		random:         rand.New(rand.NewSource(1)),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(replayer)
+		}
+	}
+	return replayer, nil
+}
+
+// myReplayer 代表回放下载器的实现类型。
+type myReplayer struct {
+	// stub.ModuleInternal 代表组件基础实例。
+	stub.ModuleInternal
+	// store 代表存放已录制的响应的存储。
+	store httpcache.Store
+	// latency 代表返回响应之前的最小延迟。
+	latency time.Duration
+	// jitter 代表延迟的随机浮动范围。
+	jitter time.Duration
+	// failureRate 代表下载失败的概率。
+	failureRate float64
+	// random 代表随机数生成器。
+	random *rand.Rand
+	// randomLock 代表保护随机数生成器的互斥锁。
+	randomLock sync.Mutex
+}
+
+func (replayer *myReplayer) Download(req *module.Request) (*module.Response, error) {
+	replayer.ModuleInternal.IncrHandlingNumber()
+	defer replayer.ModuleInternal.DecrHandlingNumber()
+	replayer.ModuleInternal.IncrCalledCount()
+	if req == nil {
+		return nil, genParameterError("nil request")
+	}
+	httpReq := req.HTTPReq()
+	if httpReq == nil {
+		return nil, genParameterError("nil HTTP request")
+	}
+	replayer.ModuleInternal.IncrAcceptedCount()
+	logger.Infof("Replay the request (URL: %s, depth: %d)... \n", httpReq.URL, req.Depth())
+	delay, fail := replayer.roll()
+	if delay > 0 {
+		// 请求的上下文被取消时不再等待，以免阻碍调度器的停止。
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-httpReq.Context().Done():
+			timer.Stop()
+			return nil, httpReq.Context().Err()
+		}
+	}
+	if fail {
+		return nil, genError(fmt.Sprintf("injected failure (URL: %s)", httpReq.URL))
+	}
+	key := httpcache.Key(httpReq)
+	entry, err := replayer.store.Get(key)
+	if err != nil {
+		return nil, genError(fmt.Sprintf("couldn't load the recorded response: %s (key: %s)", err, key))
+	}
+	if entry == nil {
+		return nil, genError(fmt.Sprintf("no recorded response (key: %s)", key))
+	}
+	httpResp := entry.Response(httpReq, "")
+	httpResp.Header.Del(httpcache.HEADER_FROM_CACHE)
+	replayer.ModuleInternal.IncrCompletedCount()
+	return module.NewResponse(httpResp, req.Depth()).WithMeta(req.Meta()), nil
+}
+
+// roll 用于生成本次下载的延迟以及是否注入故障。
+func (replayer *myReplayer) roll() (time.Duration, bool) {
+	replayer.randomLock.Lock()
+	defer replayer.randomLock.Unlock()
+	delay := replayer.latency
+	if replayer.jitter > 0 {
+		delay += time.Duration(replayer.random.Int63n(int64(replayer.jitter)))
+	}
+	fail := replayer.failureRate > 0 && replayer.random.Float64() < replayer.failureRate
+	return delay, fail
+}
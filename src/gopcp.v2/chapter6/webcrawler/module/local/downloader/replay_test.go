@@ -0,0 +1,215 @@
+package downloader
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/httpcache"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatalf("An error occurs when creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dirPath)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>index</html>"))
+	})
+	mux.HandleFunc("/old.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/index.html", http.StatusFound)
+	})
+	mux.HandleFunc("/missing.html", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	paths := []string{"/index.html", "/old.html", "/missing.html"}
+	expectedCodes := []int{200, 200, 404}
+	recorder, err := NewRecorder(module.MID("D1|127.0.0.1:8080"), &http.Client{}, dirPath, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a recorder: %s", err)
+	}
+	download := func(d module.Downloader, path string) (*http.Response, string, error) {
+		httpReq, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := d.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			return nil, "", err
+		}
+		httpResp := resp.HTTPResp()
+		defer httpResp.Body.Close()
+		body, err := ioutil.ReadAll(httpResp.Body)
+		return httpResp, string(body), err
+	}
+	expectedBodies := map[string]string{}
+	for i, path := range paths {
+		httpResp, body, err := download(recorder, path)
+		if err != nil {
+			t.Fatalf("An error occurs when recording: %s (path: %s)", err, path)
+		}
+		if httpResp.StatusCode != expectedCodes[i] {
+			t.Fatalf("Inconsistent status code: expected: %d, actual: %d (path: %s)",
+				expectedCodes[i], httpResp.StatusCode, path)
+		}
+		expectedBodies[path] = body
+	}
+	// 回放时不再需要访问网络。
+	server.Close()
+	replayer, err := NewReplayer(module.MID("D2|127.0.0.1:8080"), dirPath, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a replayer: %s", err)
+	}
+	for i, path := range paths {
+		httpResp, body, err := download(replayer, path)
+		if err != nil {
+			t.Fatalf("An error occurs when replaying: %s (path: %s)", err, path)
+		}
+		if httpResp.StatusCode != expectedCodes[i] || body != expectedBodies[path] {
+			t.Fatalf("Inconsistent replayed response: expected: %d %q, actual: %d %q (path: %s)",
+				expectedCodes[i], expectedBodies[path], httpResp.StatusCode, body, path)
+		}
+		if httpResp.Request.URL.Path != path {
+			t.Fatalf("Inconsistent request path: expected: %s, actual: %s",
+				path, httpResp.Request.URL.Path)
+		}
+	}
+	if _, _, err := download(replayer, "/unknown.html"); err == nil {
+		t.Fatalf("No error when replaying an unrecorded request!")
+	}
+	// 人为延迟。
+	replayer, _ = NewReplayer(module.MID("D2|127.0.0.1:8080"), dirPath, nil,
+		WithLatency(20*time.Millisecond, 10*time.Millisecond))
+	start := time.Now()
+	download(replayer, paths[0])
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Inconsistent latency: expected: at least %s, actual: %s",
+			20*time.Millisecond, elapsed)
+	}
+	// 人为延迟会在请求的上下文被取消时中止。
+	replayer, _ = NewReplayer(module.MID("D2|127.0.0.1:8080"), dirPath, nil,
+		WithLatency(time.Minute, 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	httpReq, _ := http.NewRequest("GET", server.URL+paths[0], nil)
+	start = time.Now()
+	_, err = replayer.Download(module.NewRequest(httpReq.WithContext(ctx), 0))
+	if err != context.Canceled {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v",
+			context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("The injected latency is not interrupted by the context! (elapsed: %s)",
+			elapsed)
+	}
+	// 故障注入。
+	replayer, _ = NewReplayer(module.MID("D2|127.0.0.1:8080"), dirPath, nil,
+		WithFailureRate(1))
+	if _, _, err := download(replayer, paths[0]); err == nil {
+		t.Fatalf("No error when the failure rate is 1!")
+	}
+	// 相同的种子会产生相同的故障序列。
+	failures := func() []bool {
+		replayer, _ := NewReplayer(module.MID("D2|127.0.0.1:8080"), dirPath, nil,
+			WithFailureRate(0.5), WithSeed(42))
+		result := []bool{}
+		for i := 0; i < 20; i++ {
+			_, _, err := download(replayer, paths[0])
+			result = append(result, err != nil)
+		}
+		return result
+	}
+	first, second := failures(), failures()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Inconsistent failure sequence: %v, %v", first, second)
+		}
+	}
+	if _, err := NewReplayer(module.MID("D2|127.0.0.1:8080"), "", nil); err == nil {
+		t.Fatalf("No error when creating a replayer with empty fixture directory!")
+	}
+}
+
+func TestRecordStreamingAndCached(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatalf("An error occurs when creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dirPath)
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("part1"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("part2"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	cache := httpcache.NewMemoryStore()
+	recorder, err := NewRecorder(module.MID("D1|127.0.0.1:8080"), &http.Client{},
+		filepath.Join(dirPath, "first"), nil, WithCache(cache))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a recorder: %s", err)
+	}
+	// 录制不会等待响应体被完整地下载。
+	httpReq, _ := http.NewRequest("GET", server.URL+"/stream.html", nil)
+	respCh := make(chan *module.Response, 1)
+	go func() {
+		resp, err := recorder.Download(module.NewRequest(httpReq, 0))
+		if err != nil {
+			t.Errorf("An error occurs when recording: %s", err)
+		}
+		respCh <- resp
+	}()
+	var resp *module.Response
+	select {
+	case resp = <-respCh:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatalf("The recorder waits for the whole response body!")
+	}
+	close(release)
+	if resp == nil {
+		t.FailNow()
+	}
+	body, err := ioutil.ReadAll(resp.HTTPResp().Body)
+	resp.HTTPResp().Body.Close()
+	if err != nil || string(body) != "part1part2" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q (error: %v)",
+			"part1part2", body, err)
+	}
+	// 由缓存提供的响应同样会被录制。
+	server.Close()
+	recorder, _ = NewRecorder(module.MID("D1|127.0.0.1:8080"), &http.Client{},
+		filepath.Join(dirPath, "second"), nil, WithCache(cache))
+	resp, err = recorder.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when recording a cached response: %s", err)
+	}
+	ioutil.ReadAll(resp.HTTPResp().Body)
+	resp.HTTPResp().Body.Close()
+	for _, name := range []string{"first", "second"} {
+		store, _ := httpcache.NewDiskStore(filepath.Join(dirPath, name))
+		entry, err := store.Get(httpcache.Key(httpReq))
+		if err != nil || entry == nil {
+			t.Fatalf("Couldn't find the recorded response: %v (fixtures: %s)", err, name)
+		}
+		if string(entry.Body) != "part1part2" {
+			t.Fatalf("Inconsistent recorded body: expected: %q, actual: %q (fixtures: %s)",
+				"part1part2", entry.Body, name)
+		}
+		if from := entry.Header.Get(httpcache.HEADER_FROM_CACHE); from != "" {
+			t.Fatalf("Inconsistent recorded header %s: expected: empty, actual: %q (fixtures: %s)",
+				httpcache.HEADER_FROM_CACHE, from, name)
+		}
+	}
+}
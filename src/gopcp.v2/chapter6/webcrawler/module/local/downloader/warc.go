@@ -0,0 +1,26 @@
+package downloader
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/toolkit/warc"
+)
+
+// newWARCBody 用于创建一个会在被完整读取或关闭后把HTTP交互写入WARC文件的响应体。
+func newWARCBody(httpResp *http.Response, writer warc.Writer) io.ReadCloser {
+	date := time.Now()
+	return newRecordingBody(httpResp.Body, func(body io.ReadSeeker) {
+		exchange := &warc.Exchange{
+			Request:  httpResp.Request,
+			Response: httpResp,
+			Body:     body,
+			Date:     date,
+		}
+		if err := writer.Write(exchange); err != nil {
+			logger.Warnf("Couldn't write WARC record: %s (URL: %s)\n",
+				err, httpResp.Request.URL)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/warc"
+)
+
+// fakeWARCWriter 代表用于测试的WARC文件写入器。
+type fakeWARCWriter struct {
+	lock   sync.Mutex
+	bodies map[string]string
+}
+
+func (w *fakeWARCWriter) Write(exchange *warc.Exchange) error {
+	body, err := ioutil.ReadAll(exchange.Body)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.bodies[exchange.Request.URL.Path] = string(body)
+	return nil
+}
+
+func (w *fakeWARCWriter) Files() []string { return nil }
+
+func (w *fakeWARCWriter) Close() error { return nil }
+
+func TestDownloadWithWARC(t *testing.T) {
+	large := strings.Repeat("x", recordingMemoryLimit+10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/small.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("small"))
+	})
+	mux.HandleFunc("/large.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	writer := &fakeWARCWriter{bodies: map[string]string{}}
+	d, err := New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithWARC(writer))
+	if err != nil {
+		t.Fatalf("An error occurs when creating a downloader: %s", err)
+	}
+	// 完整读取的响应体。
+	httpReq, _ := http.NewRequest("GET", server.URL+"/small.html", nil)
+	resp, err := d.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	ioutil.ReadAll(resp.HTTPResp().Body)
+	resp.HTTPResp().Body.Close()
+	// 只读取了一部分就被关闭的响应体。
+	httpReq, _ = http.NewRequest("GET", server.URL+"/large.html", nil)
+	resp, err = d.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatalf("An error occurs when downloading content: %s", err)
+	}
+	resp.HTTPResp().Body.Read(make([]byte, 10))
+	resp.HTTPResp().Body.Close()
+	expected := map[string]string{"/small.html": "small", "/large.html": large}
+	for path, body := range expected {
+		if writer.bodies[path] != body {
+			t.Fatalf("Inconsistent recorded body length: expected: %d, actual: %d (path: %s)",
+				len(body), len(writer.bodies[path]), path)
+		}
+	}
+	// 超过最大字节数的响应体不会被记录。
+	writer.bodies = map[string]string{}
+	d, _ = New(module.MID("D1|127.0.0.1:8080"), &http.Client{}, nil,
+		WithMaxBodySize(100), WithWARC(writer))
+	httpReq, _ = http.NewRequest("GET", server.URL+"/large.html", nil)
+	httpReq.Header.Set("Accept-Encoding", "identity")
+	resp, err = d.Download(module.NewRequest(httpReq, 0))
+	if err == nil {
+		resp.HTTPResp().Body.Close()
+	}
+	if len(writer.bodies) != 0 {
+		t.Fatalf("The too large response has been recorded!")
+	}
+}
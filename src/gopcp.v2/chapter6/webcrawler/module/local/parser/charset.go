@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Decoder 代表字符集解码函数的类型。
+// 它用于把给定字符集的内容转换为UTF-8编码的内容。
+type Decoder func(content []byte) ([]byte, error)
+
+// charsetPrescanSize 代表在HTML内容中查找字符集声明时扫描的最大字节数。
+const charsetPrescanSize = 1024
+
+// metaCharsetPattern 代表HTML内容中的字符集声明的正则表达式。
+// 它可以匹配<meta charset="...">和
+// <meta http-equiv="Content-Type" content="text/html; charset=...">两种形式。
+var metaCharsetPattern = regexp.MustCompile(
+	`(?is)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+// normalizeCharset 用于规范化字符集名称。
+// 它会按照WHATWG编码标准把字符集的标签转换为规范的名称，
+// 例如ISO-8859-1和US-ASCII都会被转换为windows-1252。
+// 若标签不在该标准中，则只会把它转换为小写。
+func normalizeCharset(label string) string {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	if _, name := charset.Lookup(label); name != "" {
+		return name
+	}
+	return label
+}
+
+// detectCharset 用于确定HTML内容的字符集，并返回规范化后的字符集名称。
+// 优先级依次为：字节顺序标记、响应头中的内容类型、HTML内容中的字符集声明。
+// 若均未给出，则视为UTF-8。
+func detectCharset(content []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(content, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(content, []byte("\xfe\xff")):
+		return "utf-16be"
+	}
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			if label := params["charset"]; label != "" {
+				return normalizeCharset(label)
+			}
+		}
+	}
+	head := content
+	if len(head) > charsetPrescanSize {
+		head = head[:charsetPrescanSize]
+	}
+	if matches := metaCharsetPattern.FindSubmatch(head); matches != nil {
+		name := normalizeCharset(string(matches[1]))
+		// 若内容本身能被正确解码，则其中的UTF-16声明必然是错误的。
+		if strings.HasPrefix(name, "utf-16") {
+			return "utf-8"
+		}
+		return name
+	}
+	return "utf-8"
+}
+
+// decodeContent 用于把给定字符集的内容转换为UTF-8编码的内容。
+// 它支持WHATWG编码标准中的所有字符集，如GBK、GB18030、Big5和Shift_JIS。
+// 参数decoders代表自定义的解码函数，其优先级高于内建的解码函数。
+func decodeContent(
+	content []byte, name string, decoders map[string]Decoder) ([]byte, error) {
+	for label, decoder := range decoders {
+		if normalizeCharset(label) == name {
+			return decoder(content)
+		}
+	}
+	switch name {
+	case "utf-8":
+		return bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), nil
+	case "utf-16le":
+		content = bytes.TrimPrefix(content, []byte("\xff\xfe"))
+	case "utf-16be":
+		content = bytes.TrimPrefix(content, []byte("\xfe\xff"))
+	}
+	encoding, _ := charset.Lookup(name)
+	if encoding == nil {
+		return nil, fmt.Errorf("unsupported charset %q", name)
+	}
+	return encoding.NewDecoder().Bytes(content)
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestDetectCharset(t *testing.T) {
+	cases := []struct {
+		content     string
+		contentType string
+		expected    string
+	}{
+		{"<html></html>", "", "utf-8"},
+		{"<html></html>", "text/html; charset=UTF8", "utf-8"},
+		{"<html></html>", "text/html; charset=\"Latin1\"", "windows-1252"},
+		{"<html></html>", "text/html; charset=GBK", "gbk"},
+		{"<html></html>", "text/html; charset=gb2312", "gbk"},
+		{"<html></html>", "text/html; charset=x-sjis", "shift_jis"},
+		{"<html></html>", "text/html; charset=x-unknown", "x-unknown"},
+		{"\xef\xbb\xbf<html></html>", "text/html; charset=gbk", "utf-8"},
+		{"\xff\xfe<\x00", "", "utf-16le"},
+		{"\xfe\xff\x00<", "", "utf-16be"},
+		{`<meta charset="Shift_JIS">`, "text/html", "shift_jis"},
+		{`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=iso-8859-1">`,
+			"", "windows-1252"},
+		{`<meta charset="utf-16">`, "", "utf-8"},
+	}
+	for _, c := range cases {
+		actual := detectCharset([]byte(c.content), c.contentType)
+		if actual != c.expected {
+			t.Fatalf("Inconsistent charset: expected: %q, actual: %q (content: %q, content type: %q)",
+				c.expected, actual, c.content, c.contentType)
+		}
+	}
+}
+
+func TestDecodeContent(t *testing.T) {
+	cases := []struct {
+		content  string
+		charset  string
+		expected string
+	}{
+		{"\xef\xbb\xbfabc", "utf-8", "abc"},
+		{"caf\xe9 \x80", "windows-1252", "café €"},
+		{"\xff\xfea\x00\xe9\x00", "utf-16le", "aé"},
+		{"\x00a\x00\xe9", "utf-16be", "aé"},
+		{"a\x00b", "utf-16le", "a\ufffd"},
+		{"\xc4\xe3\xba\xc3", "gbk", "你好"},
+		{"\xc4\xe3\xba\xc3\x81\x30\x81\x30", "gb18030", "你好\u0080"},
+		{"\xa7\x41\xa6\x6e", "big5", "你好"},
+		{"\x93\xfa\x96\x7b", "shift_jis", "日本"},
+		{"\x82\xa0", "windows-31j", "あ"},
+	}
+	for _, c := range cases {
+		actual, err := decodeContent([]byte(c.content), c.charset, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when decoding content: %s (charset: %s)",
+				err, c.charset)
+		}
+		if string(actual) != c.expected {
+			t.Fatalf("Inconsistent content: expected: %q, actual: %q (charset: %s)",
+				c.expected, actual, c.charset)
+		}
+	}
+	if _, err := decodeContent([]byte("abc"), "x-unknown", nil); err == nil {
+		t.Fatalf("No error when decoding content with unsupported charset!")
+	}
+	// 自定义的解码函数优先于内建的解码函数。
+	decoders := map[string]Decoder{
+		"GB2312": func(content []byte) ([]byte, error) {
+			return []byte("custom"), nil
+		},
+	}
+	if actual, err := decodeContent([]byte("abc"), "gbk", decoders); err != nil ||
+		string(actual) != "custom" {
+		t.Fatalf("Inconsistent content: expected: %q, actual: %q (error: %v)",
+			"custom", actual, err)
+	}
+}
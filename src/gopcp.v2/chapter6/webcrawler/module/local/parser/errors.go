@@ -0,0 +1,13 @@
+package parser
+
+import "gopcp.v2/chapter6/webcrawler/errors"
+
+// genError 用于生成爬虫错误值。
+func genError(errMsg string) error {
+	return errors.NewCrawlerError(errors.ERROR_TYPE_ANALYZER, errMsg)
+}
+
+// genErrorByError 用于基于给定的错误值生成爬虫错误值。
+func genErrorByError(err error) error {
+	return errors.NewCrawlerErrorBy(errors.ERROR_TYPE_ANALYZER, err)
+}
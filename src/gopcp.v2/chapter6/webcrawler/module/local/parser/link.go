@@ -0,0 +1,290 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// META_KEY_LINK_TAG 代表链接所在的HTML标签的名称在元数据中的键。
+// 由链接提取器生成的请求会在元数据的自定义键值对中携带该值。
+const META_KEY_LINK_TAG = "link.tag"
+
+// LinkRule 代表链接提取规则，即需要跟进的HTML标签及其属性。
+type LinkRule struct {
+	// Tag 代表标签的名称，如a。
+	Tag string
+	// Attr 代表标签中包含链接的属性的名称，如href。
+	// 若为srcset，则其中的每个候选地址都会被提取。
+	// 若标签为meta且属性为content，则只提取刷新（http-equiv="refresh"）的目标地址。
+	Attr string
+}
+
+// DefaultLinkRules 代表默认的链接提取规则。
+var DefaultLinkRules = []LinkRule{
+	{"a", "href"},
+	{"area", "href"},
+	{"link", "href"},
+	{"frame", "src"},
+	{"iframe", "src"},
+	{"img", "src"},
+	{"img", "srcset"},
+	{"source", "srcset"},
+	{"meta", "content"},
+}
+
+// LinkOptions 代表链接提取器的选项。
+type LinkOptions struct {
+	// Rules 代表链接提取规则。若为空，则使用DefaultLinkRules。
+	Rules []LinkRule
+	// FollowNofollow 代表是否跟进带有rel="nofollow"的链接，
+	// 以及页面的robots元标签中声明了nofollow时是否跟进其中的链接。
+	FollowNofollow bool
+	// Decoders 代表字符集名称与自定义解码函数的映射。
+	// 内建的解码函数支持WHATWG编码标准中的所有字符集，
+	// 自定义解码函数只用于覆盖内建的解码函数。字符集名称会按照该标准规范化。
+	// 对于不支持的字符集，其内容会被当作UTF-8处理。
+	Decoders map[string]Decoder
+}
+
+// linkExtractor 代表链接提取器。
+type linkExtractor struct {
+	// rules 代表链接提取规则。
+	rules []LinkRule
+	// followNofollow 代表是否跟进声明了nofollow的链接。
+	followNofollow bool
+	// decoders 代表自定义的解码函数。
+	decoders map[string]Decoder
+}
+
+// NewLinkParser 用于创建一个可以被用于任何分析器的链接提取函数。
+// 它会从HTML响应中提取链接并生成相应的请求。
+// 相对地址会基于<base href>（若有）或响应的URL被解析为绝对地址。
+// 对于非HTML的响应，它不会生成任何数据，也不会返回错误。
+func NewLinkParser(opts LinkOptions) module.ParseResponse {
+	rules := opts.Rules
+	if len(rules) == 0 {
+		rules = DefaultLinkRules
+	}
+	le := &linkExtractor{
+		rules:          make([]LinkRule, len(rules)),
+		followNofollow: opts.FollowNofollow,
+		decoders:       opts.Decoders,
+	}
+	for i, rule := range rules {
+		le.rules[i] = LinkRule{
+			Tag:  strings.ToLower(strings.TrimSpace(rule.Tag)),
+			Attr: strings.ToLower(strings.TrimSpace(rule.Attr)),
+		}
+	}
+	return le.parse
+}
+
+// defaultLinkParser 代表使用默认选项的链接提取函数。
+var defaultLinkParser = NewLinkParser(LinkOptions{})
+
+// ParseLink 代表使用默认选项的链接提取函数，可以被用于任何分析器。
+func ParseLink(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+	return defaultLinkParser(httpResp, respDepth)
+}
+
+// parse 用于从HTML响应中提取链接并生成相应的请求。
+func (le *linkExtractor) parse(
+	httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+	if httpResp == nil || httpResp.Request == nil ||
+		httpResp.Request.URL == nil || httpResp.Body == nil {
+		return nil, nil
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	reqURL := httpResp.Request.URL
+	content, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, []error{genErrorByError(err)}
+	}
+	contentType := httpResp.Header.Get("Content-Type")
+	if !isHTML(contentType, content) {
+		return nil, nil
+	}
+	charset := detectCharset(content, contentType)
+	decoded, err := decodeContent(content, charset, le.decoders)
+	if err != nil {
+		logger.Warnf("Couldn't decode the content: %s, treat it as UTF-8. (URL: %s)\n",
+			err, reqURL)
+		decoded = content
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(decoded))
+	if err != nil {
+		errMsg := fmt.Sprintf("couldn't parse HTML: %s (URL: %s)", err, reqURL)
+		return nil, []error{genError(errMsg)}
+	}
+	if !le.followNofollow && isPageNofollow(doc) {
+		return nil, nil
+	}
+	baseURL := reqURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			baseURL = reqURL.ResolveReference(u)
+		}
+	}
+	var dataList []module.Data
+	var errs []error
+	seen := map[string]bool{}
+	for _, rule := range le.rules {
+		doc.Find(rule.Tag).Each(func(index int, sel *goquery.Selection) {
+			if !le.followNofollow && hasNofollow(sel) {
+				return
+			}
+			value, ok := sel.Attr(rule.Attr)
+			if !ok {
+				return
+			}
+			for _, link := range extractLinks(rule, sel, value) {
+				linkURL, ok := resolveLink(baseURL, link)
+				if !ok || seen[linkURL] {
+					continue
+				}
+				seen[linkURL] = true
+				httpReq, err := http.NewRequest("GET", linkURL, nil)
+				if err != nil {
+					errs = append(errs, genErrorByError(err))
+					continue
+				}
+				req := module.NewRequest(httpReq, respDepth).WithMeta(module.Meta{
+					Values: map[string]string{META_KEY_LINK_TAG: rule.Tag},
+				})
+				dataList = append(dataList, req)
+			}
+		})
+	}
+	return dataList, errs
+}
+
+// isHTML 用于判断响应内容是否为HTML。
+// 若响应头中没有内容类型，则会根据内容本身进行判断。
+func isHTML(contentType string, content []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// isPageNofollow 用于判断页面的robots元标签中是否声明了nofollow。
+func isPageNofollow(doc *goquery.Document) bool {
+	var nofollow bool
+	doc.Find("meta[name]").EachWithBreak(func(index int, sel *goquery.Selection) bool {
+		name, _ := sel.Attr("name")
+		if !strings.EqualFold(strings.TrimSpace(name), "robots") {
+			return true
+		}
+		content, _ := sel.Attr("content")
+		for _, directive := range strings.Split(content, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "nofollow" || directive == "none" {
+				nofollow = true
+				return false
+			}
+		}
+		return true
+	})
+	return nofollow
+}
+
+// hasNofollow 用于判断标签的rel属性中是否包含nofollow。
+func hasNofollow(sel *goquery.Selection) bool {
+	rel, ok := sel.Attr("rel")
+	if !ok {
+		return false
+	}
+	for _, v := range strings.Fields(rel) {
+		if strings.EqualFold(v, "nofollow") {
+			return true
+		}
+	}
+	return false
+}
+
+// extractLinks 用于根据提取规则从属性值中提取链接。
+func extractLinks(rule LinkRule, sel *goquery.Selection, value string) []string {
+	switch {
+	case rule.Attr == "srcset":
+		return parseSrcset(value)
+	case rule.Tag == "meta" && rule.Attr == "content":
+		httpEquiv, _ := sel.Attr("http-equiv")
+		if !strings.EqualFold(strings.TrimSpace(httpEquiv), "refresh") {
+			return nil
+		}
+		if link := parseRefresh(value); link != "" {
+			return []string{link}
+		}
+		return nil
+	default:
+		return []string{value}
+	}
+}
+
+// parseSrcset 用于从srcset属性值中提取所有候选地址。
+func parseSrcset(srcset string) []string {
+	var links []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			links = append(links, fields[0])
+		}
+	}
+	return links
+}
+
+// parseRefresh 用于从刷新元标签的content属性值中提取目标地址，
+// 如“5; url=http://example.com/”。若没有目标地址，则返回空字符串。
+func parseRefresh(content string) string {
+	index := strings.IndexAny(content, ";,")
+	if index < 0 {
+		return ""
+	}
+	link := strings.TrimSpace(content[index+1:])
+	if len(link) >= 4 && strings.EqualFold(link[:3], "url") {
+		rest := strings.TrimSpace(link[3:])
+		if strings.HasPrefix(rest, "=") {
+			link = strings.TrimSpace(rest[1:])
+		}
+	}
+	return strings.Trim(link, `"'`)
+}
+
+// resolveLink 用于把链接解析为绝对地址，并去掉其中的片段。
+// 若链接为空、仅包含片段或者是不可被下载的地址（如javascript:），则第二个结果值为false。
+func resolveLink(baseURL *url.URL, link string) (string, bool) {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "#") {
+		return "", false
+	}
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		logger.Warnf("Ignore the illegal link %q: %s (base URL: %s)\n",
+			link, err, baseURL)
+		return "", false
+	}
+	switch strings.ToLower(linkURL.Scheme) {
+	case "javascript", "mailto", "tel", "data":
+		return "", false
+	}
+	if !linkURL.IsAbs() {
+		linkURL = baseURL.ResolveReference(linkURL)
+	}
+	linkURL.Fragment = ""
+	linkURL.RawFragment = ""
+	return linkURL.String(), true
+}
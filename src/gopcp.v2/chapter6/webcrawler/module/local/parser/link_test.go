@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// testingHTML 代表测试用的HTML页面。
+const testingHTML = `<html><head>
+<base href="http://example.com/dir/">
+<meta http-equiv="refresh" content="5; URL='refresh.html'">
+<link rel="stylesheet" href="/style.css">
+</head><body>
+<a href="a.html#top">a</a>
+<a href="a.html">duplicate</a>
+<a href="#top">fragment</a>
+<a href="javascript:void(0)">js</a>
+<a href="mailto:someone@example.com">mail</a>
+<a href="http://other.com/nofollow.html" rel="external nofollow">nofollow</a>
+<area href="/area.html">
+<iframe src="//cdn.example.com/frame.html"></iframe>
+<img src="img.png" srcset="img-1x.png 1x, /img-2x.png 2x">
+<picture><source srcset="pic.webp"></picture>
+</body></html>`
+
+func TestParseLink(t *testing.T) {
+	httpResp := genHTTPResp(t, "http://example.com/index.html",
+		strings.NewReader(testingHTML))
+	httpResp.Header = http.Header{"Content-Type": {"text/html"}}
+	dataList, errs := ParseLink(httpResp, 2)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing links: %s", errs[0])
+	}
+	expectedLinks := map[string]string{
+		"http://example.com/dir/a.html":       "a",
+		"http://example.com/area.html":        "area",
+		"http://example.com/style.css":        "link",
+		"http://cdn.example.com/frame.html":   "iframe",
+		"http://example.com/dir/img.png":      "img",
+		"http://example.com/dir/img-1x.png":   "img",
+		"http://example.com/img-2x.png":       "img",
+		"http://example.com/dir/pic.webp":     "source",
+		"http://example.com/dir/refresh.html": "meta",
+	}
+	actualLinks := map[string]string{}
+	for _, data := range dataList {
+		req, ok := data.(*module.Request)
+		if !ok {
+			t.Fatalf("Inconsistent data type: expected: %T, actual: %T",
+				&module.Request{}, data)
+		}
+		if req.Depth() != 2 {
+			t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 2, req.Depth())
+		}
+		u := req.HTTPReq().URL.String()
+		if _, ok := actualLinks[u]; ok {
+			t.Fatalf("Duplicate link: %s", u)
+		}
+		actualLinks[u] = req.Meta().Value(META_KEY_LINK_TAG)
+	}
+	if len(actualLinks) != len(expectedLinks) {
+		t.Fatalf("Inconsistent links: expected: %v, actual: %v",
+			expectedLinks, actualLinks)
+	}
+	for u, tag := range expectedLinks {
+		if actualTag, ok := actualLinks[u]; !ok || actualTag != tag {
+			t.Fatalf("Inconsistent tag for link %s: expected: %q, actual: %q",
+				u, tag, actualTag)
+		}
+	}
+}
+
+func TestNewLinkParser(t *testing.T) {
+	parse := NewLinkParser(LinkOptions{
+		Rules:          []LinkRule{{Tag: "A", Attr: "HREF"}},
+		FollowNofollow: true,
+	})
+	httpResp := genHTTPResp(t, "http://example.com/index.html",
+		strings.NewReader(testingHTML))
+	dataList, errs := parse(httpResp, 0)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing links: %s", errs[0])
+	}
+	expectedURLs := []string{
+		"http://example.com/dir/a.html",
+		"http://other.com/nofollow.html",
+	}
+	if len(dataList) != len(expectedURLs) {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d",
+			len(expectedURLs), len(dataList))
+	}
+	for i, expectedURL := range expectedURLs {
+		if u := dataList[i].(*module.Request).HTTPReq().URL.String(); u != expectedURL {
+			t.Fatalf("Inconsistent URL: expected: %s, actual: %s", expectedURL, u)
+		}
+	}
+	// 页面的robots元标签中声明了nofollow。
+	html := `<html><head><meta name="ROBOTS" content="noindex, NOFOLLOW"></head>` +
+		`<body><a href="/a.html">a</a></body></html>`
+	httpResp = genHTTPResp(t, "http://example.com/index.html", strings.NewReader(html))
+	if dataList, _ := ParseLink(httpResp, 0); len(dataList) != 0 {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d", 0, len(dataList))
+	}
+	httpResp = genHTTPResp(t, "http://example.com/index.html", strings.NewReader(html))
+	if dataList, _ := parse(httpResp, 0); len(dataList) != 1 {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d", 1, len(dataList))
+	}
+}
+
+func TestParseLinkCharset(t *testing.T) {
+	// “é”在windows-1252中为0xE9。
+	html := []byte("<html><head><meta charset=\"ISO-8859-1\"></head>" +
+		"<body><a href=\"/caf\xe9.html\">caf\xe9</a></body></html>")
+	httpResp := genHTTPResp(t, "http://example.com/index.html", bytes.NewReader(html))
+	httpResp.Header = http.Header{"Content-Type": {"text/html"}}
+	dataList, errs := ParseLink(httpResp, 0)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing links: %s", errs[0])
+	}
+	expectedURL := "http://example.com/caf%C3%A9.html"
+	if len(dataList) != 1 {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d", 1, len(dataList))
+	}
+	if u := dataList[0].(*module.Request).HTTPReq().URL.String(); u != expectedURL {
+		t.Fatalf("Inconsistent URL: expected: %s, actual: %s", expectedURL, u)
+	}
+	// 自定义的解码函数。
+	var decoded bool
+	parse := NewLinkParser(LinkOptions{
+		Decoders: map[string]Decoder{
+			"GBK": func(content []byte) ([]byte, error) {
+				decoded = true
+				return content, nil
+			},
+		},
+	})
+	httpResp = genHTTPResp(t, "http://example.com/index.html",
+		strings.NewReader(`<a href="/a.html">a</a>`))
+	httpResp.Header = http.Header{"Content-Type": {"text/html; charset=gbk"}}
+	if dataList, _ := parse(httpResp, 0); len(dataList) != 1 || !decoded {
+		t.Fatalf("Inconsistent parsing result: data number: %d, decoded: %v",
+			len(dataList), decoded)
+	}
+}
+
+func TestParseLinkIgnored(t *testing.T) {
+	httpResp := genHTTPResp(t, "http://example.com/a.png",
+		strings.NewReader("\x89PNG\r\n\x1a\n"))
+	if dataList, errs := ParseLink(httpResp, 0); len(dataList) != 0 || len(errs) != 0 {
+		t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s)",
+			len(dataList), len(errs))
+	}
+	httpResp = genHTTPResp(t, "http://example.com/a.json",
+		strings.NewReader(`{"href": "<a href='/a.html'>"}`))
+	httpResp.Header = http.Header{"Content-Type": {"application/json"}}
+	if dataList, errs := ParseLink(httpResp, 0); len(dataList) != 0 || len(errs) != 0 {
+		t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s)",
+			len(dataList), len(errs))
+	}
+	httpResp = genHTTPResp(t, "http://example.com/index.html",
+		strings.NewReader(testingHTML))
+	httpResp.StatusCode = http.StatusNotFound
+	if dataList, errs := ParseLink(httpResp, 0); len(dataList) != 0 || len(errs) != 0 {
+		t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s)",
+			len(dataList), len(errs))
+	}
+}
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/robots"
+	"gopcp.v2/helper/log"
+)
+
+// logger 代表日志记录器。
+var logger = log.DLogger()
+
+// 站点地图相关的元数据的键。
+// 由站点地图生成的请求会在元数据的自定义键值对中携带这些值。
+// 这些值不会被由该请求的响应解析出的子请求继承。
+const (
+	// META_KEY_SITEMAP_URL 代表声明该URL的站点地图的URL。
+	META_KEY_SITEMAP_URL = module.META_KEY_PREFIX_SITEMAP + "url"
+	// META_KEY_SITEMAP_LASTMOD 代表站点地图中的lastmod，即该URL的最后修改时间。
+	META_KEY_SITEMAP_LASTMOD = module.META_KEY_PREFIX_SITEMAP + "lastmod"
+	// META_KEY_SITEMAP_CHANGEFREQ 代表站点地图中的changefreq，即该URL的预期变更频率。
+	META_KEY_SITEMAP_CHANGEFREQ = module.META_KEY_PREFIX_SITEMAP + "changefreq"
+	// META_KEY_SITEMAP_PRIORITY 代表站点地图中的priority，即该URL的相对优先级。
+	META_KEY_SITEMAP_PRIORITY = module.META_KEY_PREFIX_SITEMAP + "priority"
+)
+
+// MaxSitemapSize 代表会被解析的（解压后的）站点地图内容的最大字节数。
+// 超出部分会被忽略。
+const MaxSitemapSize = 50 * 1024 * 1024
+
+// MaxSitemapURLs 代表从单个站点地图中提取的URL的最大数量。
+const MaxSitemapURLs = 50000
+
+// defaultSitemapPriority 代表站点地图中未声明priority时的默认优先级。
+const defaultSitemapPriority = 0.5
+
+// sitemapEntry 代表站点地图或站点地图索引中的一个条目。
+type sitemapEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// sitemapDoc 代表站点地图（urlset）或站点地图索引（sitemapindex）文档。
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// ParseSitemap 代表站点地图的响应解析函数，可以被用于任何分析器。
+// 它可以解析以下3种响应：
+//   - robots.txt：其中以“Sitemap:”声明的每个站点地图都会生成一个请求；
+//   - 站点地图索引（sitemapindex）：其中的每个站点地图都会生成一个请求；
+//   - 站点地图（urlset）：其中的每个URL都会生成一个请求。
+//
+// 站点地图可以是经过gzip压缩的。
+// 由站点地图生成的请求会在元数据中携带lastmod、changefreq和priority，
+// 其中priority还会被换算为请求的优先级（priority乘以10后取整）。
+// 对于其他的响应，该函数不会生成任何数据，也不会返回错误。
+func ParseSitemap(httpResp *http.Response, respDepth uint32) ([]module.Data, []error) {
+	if httpResp == nil || httpResp.Request == nil ||
+		httpResp.Request.URL == nil || httpResp.Body == nil {
+		return nil, nil
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	reqURL := httpResp.Request.URL
+	if strings.EqualFold(reqURL.Path, "/robots.txt") {
+		return parseRobotsSitemaps(httpResp.Body, reqURL)
+	}
+	body, err := sitemapReader(httpResp.Body)
+	if err != nil {
+		return nil, []error{genErrorByError(err)}
+	}
+	if !isXML(body) {
+		return nil, nil
+	}
+	var doc sitemapDoc
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		errMsg := fmt.Sprintf("couldn't parse sitemap: %s (URL: %s)", err, reqURL)
+		return nil, []error{genError(errMsg)}
+	}
+	var entries []sitemapEntry
+	switch doc.XMLName.Local {
+	case "urlset":
+		entries = doc.URLs
+	case "sitemapindex":
+		entries = doc.Sitemaps
+	default:
+		return nil, nil
+	}
+	if len(entries) > MaxSitemapURLs {
+		logger.Warnf("Too many URLs in sitemap: %d, only the first %d will be used. (URL: %s)\n",
+			len(entries), MaxSitemapURLs, reqURL)
+		entries = entries[:MaxSitemapURLs]
+	}
+	var dataList []module.Data
+	var errs []error
+	for _, entry := range entries {
+		req, err := newSitemapRequest(entry, reqURL, respDepth)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if req != nil {
+			dataList = append(dataList, req)
+		}
+	}
+	return dataList, errs
+}
+
+// parseRobotsSitemaps 用于解析robots.txt中声明的站点地图，并生成相应的请求。
+func parseRobotsSitemaps(body io.Reader, reqURL *url.URL) ([]module.Data, []error) {
+	rules, err := robots.Parse(body, "*")
+	if err != nil {
+		return nil, []error{genErrorByError(err)}
+	}
+	var dataList []module.Data
+	var errs []error
+	for _, loc := range rules.Sitemaps() {
+		req, err := newSitemapRequest(sitemapEntry{Loc: loc}, reqURL, 0)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if req != nil {
+			dataList = append(dataList, req)
+		}
+	}
+	return dataList, errs
+}
+
+// sitemapReader 用于生成读取站点地图内容的读取器。
+// 若内容是经过gzip压缩的，则返回的读取器会读取解压后的内容。
+// 返回的读取器最多只会读取MaxSitemapSize个字节。
+func sitemapReader(body io.Reader) (*bufio.Reader, error) {
+	br := bufio.NewReader(body)
+	magic, _ := br.Peek(2)
+	var r io.Reader = br
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	}
+	return bufio.NewReader(io.LimitReader(r, MaxSitemapSize)), nil
+}
+
+// isXML 用于判断给定读取器中的内容是否像是XML文档。
+func isXML(r *bufio.Reader) bool {
+	head, _ := r.Peek(512)
+	head = bytes.TrimLeft(head, "\xef\xbb\xbf \t\r\n")
+	return bytes.HasPrefix(head, []byte("<"))
+}
+
+// newSitemapRequest 用于根据站点地图中的条目生成请求。
+// 若条目中的URL为空，则返回nil。
+func newSitemapRequest(
+	entry sitemapEntry, sitemapURL *url.URL, respDepth uint32) (*module.Request, error) {
+	loc := strings.TrimSpace(entry.Loc)
+	if loc == "" {
+		return nil, nil
+	}
+	locURL, err := url.Parse(loc)
+	if err != nil {
+		errMsg := fmt.Sprintf("illegal URL %q in sitemap: %s (URL: %s)",
+			loc, err, sitemapURL)
+		return nil, genError(errMsg)
+	}
+	if !locURL.IsAbs() {
+		locURL = sitemapURL.ResolveReference(locURL)
+	}
+	httpReq, err := http.NewRequest("GET", locURL.String(), nil)
+	if err != nil {
+		return nil, genErrorByError(err)
+	}
+	values := map[string]string{META_KEY_SITEMAP_URL: sitemapURL.String()}
+	if lastMod := strings.TrimSpace(entry.LastMod); lastMod != "" {
+		values[META_KEY_SITEMAP_LASTMOD] = lastMod
+	}
+	if changeFreq := strings.TrimSpace(entry.ChangeFreq); changeFreq != "" {
+		values[META_KEY_SITEMAP_CHANGEFREQ] = strings.ToLower(changeFreq)
+	}
+	priority := defaultSitemapPriority
+	if p := strings.TrimSpace(entry.Priority); p != "" {
+		if v, err := strconv.ParseFloat(p, 64); err == nil && v >= 0 && v <= 1 {
+			priority = v
+			values[META_KEY_SITEMAP_PRIORITY] = p
+		} else {
+			logger.Warnf("Ignore the illegal priority %q in sitemap. (URL: %s)\n",
+				p, sitemapURL)
+		}
+	}
+	req := module.NewPriorityRequest(
+		httpReq, respDepth, int(math.Round(priority*10)))
+	return req.WithMeta(module.Meta{Values: values}), nil
+}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// testingSitemap 代表测试用的站点地图。
+const testingSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>http://example.com/</loc>
+    <lastmod>2017-01-01</lastmod>
+    <changefreq>Daily</changefreq>
+    <priority>0.8</priority>
+  </url>
+  <url>
+    <loc> /about.html </loc>
+  </url>
+  <url>
+    <loc>http://example.com/news.html</loc>
+    <priority>2.0</priority>
+  </url>
+  <url>
+    <loc></loc>
+  </url>
+</urlset>`
+
+// testingSitemapIndex 代表测试用的站点地图索引。
+const testingSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>http://example.com/sitemap1.xml.gz</loc>
+    <lastmod>2017-02-01T18:23:17+00:00</lastmod>
+  </sitemap>
+  <sitemap>
+    <loc>http://example.com/sitemap2.xml</loc>
+  </sitemap>
+</sitemapindex>`
+
+func TestParseSitemap(t *testing.T) {
+	httpResp := genHTTPResp(t, "http://example.com/sitemap.xml",
+		strings.NewReader(testingSitemap))
+	dataList, errs := ParseSitemap(httpResp, 1)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing sitemap: %s", errs[0])
+	}
+	expectedReqs := []struct {
+		url      string
+		priority int
+		values   map[string]string
+	}{
+		{"http://example.com/", 8, map[string]string{
+			META_KEY_SITEMAP_LASTMOD:    "2017-01-01",
+			META_KEY_SITEMAP_CHANGEFREQ: "daily",
+			META_KEY_SITEMAP_PRIORITY:   "0.8",
+		}},
+		{"http://example.com/about.html", 5, nil},
+		{"http://example.com/news.html", 5, nil},
+	}
+	if len(dataList) != len(expectedReqs) {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d",
+			len(expectedReqs), len(dataList))
+	}
+	for i, expected := range expectedReqs {
+		req, ok := dataList[i].(*module.Request)
+		if !ok {
+			t.Fatalf("Inconsistent data type: expected: %T, actual: %T",
+				&module.Request{}, dataList[i])
+		}
+		if u := req.HTTPReq().URL.String(); u != expected.url {
+			t.Fatalf("Inconsistent URL: expected: %s, actual: %s", expected.url, u)
+		}
+		if req.Priority() != expected.priority {
+			t.Fatalf("Inconsistent priority: expected: %d, actual: %d (URL: %s)",
+				expected.priority, req.Priority(), expected.url)
+		}
+		if req.Depth() != 1 {
+			t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 1, req.Depth())
+		}
+		meta := req.Meta()
+		if v := meta.Value(META_KEY_SITEMAP_URL); v != "http://example.com/sitemap.xml" {
+			t.Fatalf("Inconsistent sitemap URL: expected: %s, actual: %s",
+				"http://example.com/sitemap.xml", v)
+		}
+		for k, v := range expected.values {
+			if meta.Value(k) != v {
+				t.Fatalf("Inconsistent meta value: expected: %q, actual: %q (key: %s)",
+					v, meta.Value(k), k)
+			}
+		}
+		if expected.values == nil && meta.Value(META_KEY_SITEMAP_PRIORITY) != "" {
+			t.Fatalf("Inconsistent meta value: expected: %q, actual: %q (key: %s)",
+				"", meta.Value(META_KEY_SITEMAP_PRIORITY), META_KEY_SITEMAP_PRIORITY)
+		}
+	}
+}
+
+func TestParseSitemapIndex(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write([]byte(testingSitemapIndex))
+	gw.Close()
+	httpResp := genHTTPResp(t, "http://example.com/sitemap_index.xml.gz", &buf)
+	dataList, errs := ParseSitemap(httpResp, 0)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing sitemap index: %s", errs[0])
+	}
+	expectedURLs := []string{
+		"http://example.com/sitemap1.xml.gz",
+		"http://example.com/sitemap2.xml",
+	}
+	if len(dataList) != len(expectedURLs) {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d",
+			len(expectedURLs), len(dataList))
+	}
+	for i, expectedURL := range expectedURLs {
+		req := dataList[i].(*module.Request)
+		if u := req.HTTPReq().URL.String(); u != expectedURL {
+			t.Fatalf("Inconsistent URL: expected: %s, actual: %s", expectedURL, u)
+		}
+	}
+	lastMod := dataList[0].(*module.Request).Meta().Value(META_KEY_SITEMAP_LASTMOD)
+	if lastMod != "2017-02-01T18:23:17+00:00" {
+		t.Fatalf("Inconsistent lastmod: expected: %s, actual: %s",
+			"2017-02-01T18:23:17+00:00", lastMod)
+	}
+}
+
+func TestParseSitemapFromRobots(t *testing.T) {
+	robotsTxt := "User-agent: *\nDisallow: /private/\n" +
+		"Sitemap: http://example.com/sitemap.xml\n" +
+		"Sitemap: /sitemap-news.xml\n"
+	httpResp := genHTTPResp(t, "http://example.com/robots.txt",
+		strings.NewReader(robotsTxt))
+	dataList, errs := ParseSitemap(httpResp, 0)
+	if len(errs) != 0 {
+		t.Fatalf("An error occurs when parsing robots.txt: %s", errs[0])
+	}
+	expectedURLs := []string{
+		"http://example.com/sitemap.xml",
+		"http://example.com/sitemap-news.xml",
+	}
+	if len(dataList) != len(expectedURLs) {
+		t.Fatalf("Inconsistent data number: expected: %d, actual: %d",
+			len(expectedURLs), len(dataList))
+	}
+	for i, expectedURL := range expectedURLs {
+		req := dataList[i].(*module.Request)
+		if u := req.HTTPReq().URL.String(); u != expectedURL {
+			t.Fatalf("Inconsistent URL: expected: %s, actual: %s", expectedURL, u)
+		}
+	}
+}
+
+func TestParseSitemapIgnored(t *testing.T) {
+	bodies := map[string]string{
+		"http://example.com/index.html": "<html><body></body></html>",
+		"http://example.com/a.txt":      "plain text",
+		"http://example.com/feed.xml":   "<rss><channel></channel></rss>",
+	}
+	for u, body := range bodies {
+		httpResp := genHTTPResp(t, u, strings.NewReader(body))
+		dataList, errs := ParseSitemap(httpResp, 0)
+		if len(dataList) != 0 || len(errs) != 0 {
+			t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s) (URL: %s)",
+				len(dataList), len(errs), u)
+		}
+	}
+	httpResp := genHTTPResp(t, "http://example.com/sitemap.xml",
+		strings.NewReader(testingSitemap))
+	httpResp.StatusCode = http.StatusNotFound
+	if dataList, errs := ParseSitemap(httpResp, 0); len(dataList) != 0 || len(errs) != 0 {
+		t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s)",
+			len(dataList), len(errs))
+	}
+	if dataList, errs := ParseSitemap(nil, 0); len(dataList) != 0 || len(errs) != 0 {
+		t.Fatalf("Inconsistent parsing result: expected: no data and no error, actual: %d data and %d error(s)",
+			len(dataList), len(errs))
+	}
+	httpResp = genHTTPResp(t, "http://example.com/sitemap.xml",
+		strings.NewReader("<urlset><url><loc>http://example.com/</url>"))
+	if _, errs := ParseSitemap(httpResp, 0); len(errs) != 1 {
+		t.Fatalf("Inconsistent error number: expected: %d, actual: %d", 1, len(errs))
+	}
+}
+
+// genHTTPResp 用于生成测试用的HTTP响应。
+func genHTTPResp(t *testing.T, u string, body io.Reader) *http.Response {
+	httpReq, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating HTTP request: %s (URL: %s)", err, u)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Request:    httpReq,
+		Body:       ioutil.NopCloser(body),
+	}
+}
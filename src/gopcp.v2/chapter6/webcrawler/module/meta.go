@@ -0,0 +1,80 @@
+package module
+
+import (
+	"context"
+	"net/http"
+)
+
+// META_KEY_PREFIX_SITEMAP 代表站点地图相关的自定义键的前缀。
+// 这类键只描述站点地图中声明的那个URL本身，因此不会被子请求继承。
+const META_KEY_PREFIX_SITEMAP = "sitemap."
+
+// META_KEY_SCOPE 代表存放种子的爬取范围的自定义键。
+// 它由调度器为按主机或路径前缀限定范围的种子设置，并随键值对被子请求继承。
+const META_KEY_SCOPE = "scope"
+
+// ITEM_KEY_META 代表条目中存放元数据的键。
+// 分析器会把响应的元数据以此键放入解析出的条目中（若条目中尚无此键）。
+const ITEM_KEY_META = "_meta"
+
+// Meta 代表请求和响应携带的元数据的类型。
+type Meta struct {
+	// ParentURL 代表发现该请求的页面的URL。首次请求的此字段为空。
+	ParentURL string `json:"parent_url,omitempty"`
+	// Referrer 代表该请求的来源页面的URL。默认与ParentURL相同。
+	Referrer string `json:"referrer,omitempty"`
+	// Parser 代表生成该请求的响应解析函数的名称。
+	Parser string `json:"parser,omitempty"`
+	// Attempt 代表请求的尝试次数。首次尝试时为1。
+	// 该字段只读，设置元数据时会被忽略。
+	Attempt uint32 `json:"attempt,omitempty"`
+	// Values 代表用户自定义的键值对。
+	// 子请求会继承父请求的键值对（以META_KEY_PREFIX_SITEMAP为前缀的除外），
+	// 并可以覆盖其中的值。
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Value 用于获取给定键对应的用户自定义的值。若不存在则返回空字符串。
+func (meta Meta) Value(key string) string {
+	return meta.Values[key]
+}
+
+// Copy 用于生成当前元数据的副本。其中的键值对也会被复制。
+func (meta Meta) Copy() Meta {
+	if meta.Values != nil {
+		values := make(map[string]string, len(meta.Values))
+		for k, v := range meta.Values {
+			values[k] = v
+		}
+		meta.Values = values
+	}
+	return meta
+}
+
+// metaContextKey 代表在上下文中存放元数据的键的类型。
+type metaContextKey struct{}
+
+// ContextWithMeta 用于生成一个携带给定元数据的上下文。
+func ContextWithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaContextKey{}, meta)
+}
+
+// MetaFromContext 用于从给定的上下文中获取元数据。
+// 第二个结果值代表是否找到了元数据。
+func MetaFromContext(ctx context.Context) (Meta, bool) {
+	if ctx == nil {
+		return Meta{}, false
+	}
+	meta, ok := ctx.Value(metaContextKey{}).(Meta)
+	return meta, ok
+}
+
+// MetaFromResponse 用于在响应解析函数中获取与HTTP响应对应的元数据。
+// 分析器在调用响应解析函数之前会把元数据放入HTTP响应所属请求的上下文中。
+// 第二个结果值代表是否找到了元数据。
+func MetaFromResponse(httpResp *http.Response) (Meta, bool) {
+	if httpResp == nil || httpResp.Request == nil {
+		return Meta{}, false
+	}
+	return MetaFromContext(httpResp.Request.Context())
+}
@@ -0,0 +1,56 @@
+package module
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestMeta(t *testing.T) {
+	meta := Meta{
+		ParentURL: "https://github.com/gopcp",
+		Values:    map[string]string{"team": "gopcp"},
+	}
+	if meta.Value("team") != "gopcp" {
+		t.Fatalf("Inconsistent meta value: expected: %q, actual: %q",
+			"gopcp", meta.Value("team"))
+	}
+	if meta.Value("unknown") != "" {
+		t.Fatalf("Inconsistent meta value: expected: %q, actual: %q",
+			"", meta.Value("unknown"))
+	}
+	another := meta.Copy()
+	another.Values["team"] = "other"
+	if meta.Value("team") != "gopcp" {
+		t.Fatalf("The original meta has been changed by its copy! (meta: %#v)", meta)
+	}
+	if (Meta{}).Copy().Values != nil {
+		t.Fatal("Inconsistent values of copied empty meta: expected: nil")
+	}
+}
+
+func TestMetaContext(t *testing.T) {
+	if _, ok := MetaFromContext(context.Background()); ok {
+		t.Fatal("It still can get meta from empty context!")
+	}
+	meta := Meta{ParentURL: "https://github.com/gopcp"}
+	ctx := ContextWithMeta(context.Background(), meta)
+	actual, ok := MetaFromContext(ctx)
+	if !ok || actual.ParentURL != meta.ParentURL {
+		t.Fatalf("Inconsistent meta from context: expected: %#v, actual: %#v",
+			meta, actual)
+	}
+	if _, ok := MetaFromResponse(nil); ok {
+		t.Fatal("It still can get meta from nil HTTP response!")
+	}
+	if _, ok := MetaFromResponse(&http.Response{}); ok {
+		t.Fatal("It still can get meta from HTTP response without request!")
+	}
+	httpReq, _ := http.NewRequest("GET", "https://github.com/gopcp", nil)
+	httpResp := &http.Response{Request: httpReq.WithContext(ctx)}
+	actual, ok = MetaFromResponse(httpResp)
+	if !ok || actual.ParentURL != meta.ParentURL {
+		t.Fatalf("Inconsistent meta from HTTP response: expected: %#v, actual: %#v",
+			meta, actual)
+	}
+}
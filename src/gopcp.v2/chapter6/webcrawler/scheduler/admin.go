@@ -0,0 +1,232 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// metricsContentType 代表Prometheus文本格式的内容类型。
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// NewAdminHandler 用于创建一个调度器的管理处理器。
+// 它可以被嵌入到任何HTTP服务器中，例如：
+//
+//	http.Handle("/crawler/", http.StripPrefix("/crawler", NewAdminHandler(sched)))
+//
+// 它提供以下路径：
+//   - GET /summary：以JSON格式返回调度器的摘要信息，
+//     其中包括各个组件和缓冲池的摘要信息以及最近发生的错误；
+//   - GET /metrics：以Prometheus的文本格式返回调度器的指标；
+//   - POST /pause、POST /resume：暂停或恢复调度器；
+//   - POST /stop：停止调度器。
+func NewAdminHandler(sched Scheduler) http.Handler {
+	if sched == nil {
+		panic(genParameterError("nil scheduler"))
+	}
+	handler := &adminHandler{sched: sched, mux: http.NewServeMux()}
+	handler.mux.HandleFunc("/summary", handler.handleSummary)
+	handler.mux.HandleFunc("/metrics", handler.handleMetrics)
+	handler.mux.HandleFunc("/pause", handler.handleAction)
+	handler.mux.HandleFunc("/resume", handler.handleAction)
+	handler.mux.HandleFunc("/stop", handler.handleAction)
+	return handler
+}
+
+// adminHandler 代表调度器的管理处理器的实现类型。
+type adminHandler struct {
+	// sched 代表调度器。
+	sched Scheduler
+	// mux 代表请求的多路复用器。
+	mux *http.ServeMux
+}
+
+func (handler *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.mux.ServeHTTP(w, r)
+}
+
+// handleSummary 用于处理获取摘要信息的请求。
+func (handler *adminHandler) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("unsupported method %q", r.Method))
+		return
+	}
+	summary := handler.sched.Summary()
+	if summary == nil {
+		writeJSONError(w, http.StatusServiceUnavailable,
+			"the scheduler has not yet been initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, summary.Struct())
+}
+
+// handleMetrics 用于处理获取指标的请求。
+func (handler *adminHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("unsupported method %q", r.Method))
+		return
+	}
+	summary := handler.sched.Summary()
+	if summary == nil {
+		writeJSONError(w, http.StatusServiceUnavailable,
+			"the scheduler has not yet been initialized")
+		return
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(genMetrics(summary.Struct()))
+}
+
+// handleAction 用于处理暂停、恢复或停止调度器的请求。
+func (handler *adminHandler) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("unsupported method %q", r.Method))
+		return
+	}
+	action := strings.TrimPrefix(r.URL.Path, "/")
+	var err error
+	switch action {
+	case "stop":
+		err = handler.sched.Stop()
+	case "pause":
+		err = handler.sched.Pause()
+	case "resume":
+		err = handler.sched.Resume()
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"action": action,
+		"status": GetStatusDescription(handler.sched.Status()),
+	})
+}
+
+// writeJSON 用于以JSON格式写入给定的值。
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
+
+// writeJSONError 用于以JSON格式写入错误信息。
+func writeJSONError(w http.ResponseWriter, statusCode int, errMsg string) {
+	b, _ := json.Marshal(map[string]string{"error": errMsg})
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
+
+// metricsWriter 代表Prometheus文本格式的指标的写入器。
+type metricsWriter struct {
+	// buf 代表存放指标的缓冲区。
+	buf bytes.Buffer
+	// written 代表已写入过说明的指标名称的集合。
+	written map[string]bool
+}
+
+// write 用于写入一个指标的样本。
+// 同名指标的说明和类型只会在第一次写入时写入。
+// 参数labels中的元素应该成对出现，依次为标签名和标签值。
+func (mw *metricsWriter) write(
+	name, metricType, help string, value float64, labels ...string) {
+	if !mw.written[name] {
+		mw.written[name] = true
+		fmt.Fprintf(&mw.buf, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&mw.buf, "# TYPE %s %s\n", name, metricType)
+	}
+	mw.buf.WriteString(name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+		}
+		mw.buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	fmt.Fprintf(&mw.buf, " %v\n", value)
+}
+
+// genMetrics 用于根据给定的摘要信息生成Prometheus文本格式的指标。
+func genMetrics(summary SummaryStruct) []byte {
+	mw := &metricsWriter{written: map[string]bool{}}
+	mw.write("webcrawler_status", "gauge",
+		"Current status of the scheduler.", 1, "status", summary.Status)
+	moduleSummaryMap := map[module.Type][]module.SummaryStruct{
+		module.TYPE_DOWNLOADER: summary.Downloaders,
+		module.TYPE_ANALYZER:   summary.Analyzers,
+		module.TYPE_PIPELINE:   summary.Pipelines,
+	}
+	for _, mType := range []module.Type{
+		module.TYPE_DOWNLOADER, module.TYPE_ANALYZER, module.TYPE_PIPELINE} {
+		for _, ms := range moduleSummaryMap[mType] {
+			labels := []string{"type", string(mType), "mid", string(ms.ID)}
+			mw.write("webcrawler_module_called_total", "counter",
+				"Number of calls to the module.", float64(ms.Called), labels...)
+			mw.write("webcrawler_module_accepted_total", "counter",
+				"Number of calls accepted by the module.", float64(ms.Accepted), labels...)
+			mw.write("webcrawler_module_completed_total", "counter",
+				"Number of calls completed by the module.", float64(ms.Completed), labels...)
+			mw.write("webcrawler_module_handling", "gauge",
+				"Number of calls being handled by the module.", float64(ms.Handling), labels...)
+		}
+	}
+	mw.write("webcrawler_frontier_length", "gauge",
+		"Number of requests in the frontier.", float64(summary.Frontier.Len))
+	mw.write("webcrawler_frontier_capacity", "gauge",
+		"Capacity of the frontier.", float64(summary.Frontier.Cap))
+	poolSummaryMap := map[string]BufferPoolSummaryStruct{
+		"response": summary.RespBufferPool,
+		"item":     summary.ItemBufferPool,
+		"error":    summary.ErrorBufferPool,
+	}
+	for _, pool := range []string{"response", "item", "error"} {
+		ps := poolSummaryMap[pool]
+		mw.write("webcrawler_buffer_pool_total", "gauge",
+			"Number of data in the buffer pool.", float64(ps.Total), "pool", pool)
+		mw.write("webcrawler_buffer_pool_buffers", "gauge",
+			"Number of buffers in the buffer pool.", float64(ps.BufferNumber), "pool", pool)
+	}
+	workerSummaryMap := map[string]WorkerSummaryStruct{
+		"download": summary.DownloadWorkers,
+		"analyze":  summary.AnalyzeWorkers,
+		"pick":     summary.PickWorkers,
+	}
+	for _, stage := range []string{"download", "analyze", "pick"} {
+		ws := workerSummaryMap[stage]
+		mw.write("webcrawler_workers", "gauge",
+			"Max number of workers in the stage.", float64(ws.Total), "stage", stage)
+		mw.write("webcrawler_workers_active", "gauge",
+			"Number of active workers in the stage.", float64(ws.Active), "stage", stage)
+	}
+	mw.write("webcrawler_urls_total", "counter",
+		"Number of URLs accepted by the scheduler.", float64(summary.NumURL))
+	reasons := make([]string, 0, len(summary.Rejected))
+	for reason := range summary.Rejected {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		mw.write("webcrawler_rejected_total", "counter",
+			"Number of requests rejected by the scheduler.",
+			float64(summary.Rejected[reason]), "reason", reason)
+	}
+	mw.write("webcrawler_retries_total", "counter",
+		"Number of scheduled download retries.", float64(summary.Retries))
+	mw.write("webcrawler_failures_total", "counter",
+		"Number of requests which finally failed to download.", float64(summary.Failures))
+	return mw.buf.Bytes()
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<html><body></body></html>")
+		}))
+	defer server.Close()
+	sched := NewScheduler()
+	handler := NewAdminHandler(sched)
+	// 调度器未初始化时无法获取摘要信息。
+	for _, path := range []string{"/summary", "/metrics"} {
+		resp := serveAdmin(handler, "GET", path)
+		if resp.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Inconsistent status code: expected: %d, actual: %d (path: %s)",
+				http.StatusServiceUnavailable, resp.Code, path)
+		}
+	}
+	err := sched.Init(genRequestArgs([]string{}, 0), genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	for i := 0; i < recentErrorNumber+1; i++ {
+		mySched.sendError(fmt.Errorf("error %d", i), "")
+	}
+	// 测试摘要信息。
+	resp := serveAdmin(handler, "GET", "/summary")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d",
+			http.StatusOK, resp.Code)
+	}
+	var summary SummaryStruct
+	if err := json.Unmarshal(resp.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("An error occurs when decoding summary: %s", err)
+	}
+	expectedStatus := GetStatusDescription(SCHED_STATUS_INITIALIZED)
+	if summary.Status != expectedStatus {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			expectedStatus, summary.Status)
+	}
+	if n := len(summary.RecentErrors); n != recentErrorNumber {
+		t.Fatalf("Inconsistent recent error number: expected: %d, actual: %d",
+			recentErrorNumber, n)
+	}
+	expectedErr := "crawler error: scheduler error: error 1"
+	if e := summary.RecentErrors[0]; e != expectedErr {
+		t.Fatalf("Inconsistent recent error: expected: %q, actual: %q", expectedErr, e)
+	}
+	// 测试指标。
+	resp = serveAdmin(handler, "GET", "/metrics")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d",
+			http.StatusOK, resp.Code)
+	}
+	if ct := resp.Header().Get("Content-Type"); ct != metricsContentType {
+		t.Fatalf("Inconsistent content type: expected: %q, actual: %q",
+			metricsContentType, ct)
+	}
+	metrics := resp.Body.String()
+	downloaderID := summary.Downloaders[0].ID
+	expectedLines := []string{
+		"# TYPE webcrawler_module_called_total counter",
+		fmt.Sprintf("webcrawler_status{status=%q} 1", expectedStatus),
+		fmt.Sprintf("webcrawler_module_called_total{type=\"downloader\",mid=%q} 0", downloaderID),
+		"webcrawler_buffer_pool_total{pool=\"response\"} 0",
+		"webcrawler_frontier_capacity 20",
+		"webcrawler_workers{stage=\"download\"} 1",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Fatalf("Not found line %q in metrics:\n%s", line, metrics)
+		}
+	}
+	if n := strings.Count(metrics, "# TYPE webcrawler_module_called_total "); n != 1 {
+		t.Fatalf("Inconsistent metric type line number: expected: %d, actual: %d", 1, n)
+	}
+	// 测试管理操作。
+	statusCodeMap := map[string]int{
+		"GET /stop":    http.StatusMethodNotAllowed,
+		"POST /stop":   http.StatusConflict,
+		"POST /pause":  http.StatusConflict,
+		"POST /resume": http.StatusConflict,
+		"POST /other":  http.StatusNotFound,
+	}
+	for req, expected := range statusCodeMap {
+		parts := strings.Split(req, " ")
+		if resp := serveAdmin(handler, parts[0], parts[1]); resp.Code != expected {
+			t.Fatalf("Inconsistent status code: expected: %d, actual: %d (request: %s)",
+				expected, resp.Code, req)
+		}
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL, nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	for _, action := range []string{"pause", "resume"} {
+		resp = serveAdmin(handler, "POST", "/"+action)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("Inconsistent status code: expected: %d, actual: %d (action: %s, body: %s)",
+				http.StatusOK, resp.Code, action, resp.Body)
+		}
+	}
+	resp = serveAdmin(handler, "POST", "/stop")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d (body: %s)",
+			http.StatusOK, resp.Code, resp.Body)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED), GetStatusDescription(status))
+	}
+}
+
+func TestErrorRecorder(t *testing.T) {
+	er := newErrorRecorder()
+	if errs := er.snapshot(); errs != nil {
+		t.Fatalf("Inconsistent recent errors: expected: %v, actual: %v", nil, errs)
+	}
+	for i := 0; i < recentErrorNumber*2; i++ {
+		er.record(errors.New(fmt.Sprint(i)))
+	}
+	errs := er.snapshot()
+	if len(errs) != recentErrorNumber {
+		t.Fatalf("Inconsistent recent error number: expected: %d, actual: %d",
+			recentErrorNumber, len(errs))
+	}
+	for i, e := range errs {
+		if expected := fmt.Sprint(recentErrorNumber + i); e != expected {
+			t.Fatalf("Inconsistent recent error: expected: %s, actual: %s", expected, e)
+		}
+	}
+}
+
+// serveAdmin 用于使用给定的管理处理器处理请求，并返回记录的响应。
+func serveAdmin(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
@@ -1,6 +1,11 @@
 package scheduler
 
-import "gopcp.v2/chapter6/webcrawler/module"
+import (
+	"fmt"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
 
 // Args 代表参数容器的接口类型。
 type Args interface {
@@ -13,16 +18,97 @@ type Args interface {
 type RequestArgs struct {
 	// AcceptedDomains 代表可以接受的URL的主域名的列表。
 	// URL主域名不在列表中的请求都会被忽略，
+	// 其中的主域名会根据公共后缀列表计算，例如“example.co.uk”。
+	// 除了主域名，列表中还可以包含只匹配单个主机的主机名（如“docs.example.com”）、
+	// 匹配所有子域名的通配符（如“*.example.com”）
+	// 以及带有端口的主机名或IP地址（如“127.0.0.1:8080”）。
 	AcceptedDomains []string `json:"accepted_primary_domains"`
 	// maxDepth 代表了需要被爬取的最大深度。
 	// 实际深度大于此值的请求都会被忽略。
 	MaxDepth uint32 `json:"max_depth"`
+	// MaxRequestsPerSecond 代表针对每个主域名每秒最多发出的请求数。
+	// 若为0，则不限制请求频率。
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second,omitempty"`
+	// MaxInFlightPerHost 代表针对每个主机同时进行中的请求的最大数量。
+	// 若为0，则不限制并发请求数。
+	MaxInFlightPerHost uint32 `json:"max_in_flight_per_host,omitempty"`
+	// CrawlDelay 代表针对同一主域名的相邻两次请求之间的最小间隔时间。
+	// 若与MaxRequestsPerSecond同时设定，则以两者中较长的间隔为准。
+	CrawlDelay time.Duration `json:"crawl_delay,omitempty"`
+	// ObeyRobots 代表是否遵守各主机的robots.txt。
+	// 若为true，则被robots.txt禁止的请求都会被忽略，
+	// 且其中的Crawl-delay会作为相应主域名的最小请求间隔。
+	ObeyRobots bool `json:"obey_robots,omitempty"`
+	// RobotsUserAgent 代表匹配robots.txt规则时使用的用户代理。
+	// 获取robots.txt时也会使用它作为User-Agent头。
+	// 若为空，则只使用“*”组的规则。
+	RobotsUserAgent string `json:"robots_user_agent,omitempty"`
+	// IgnoredQueryParams 代表在URL去重时需要忽略的查询参数的列表。
+	// 以“*”结尾的名称会按前缀匹配，例如“utm_*”。
+	// 常见的跟踪用参数可参见DefaultTrackingParams。
+	IgnoredQueryParams []string `json:"ignored_query_params,omitempty"`
+	// MaxAttempts 代表每个请求的最大下载尝试次数（含首次尝试）。
+	// 若不大于1，则下载失败时不会重试。
+	MaxAttempts uint32 `json:"max_attempts,omitempty"`
+	// RetryBackoff 代表初始的重试间隔时间。之后每次重试的间隔时间都会翻倍，
+	// 并带有随机抖动。若为0，则使用默认值。
+	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
+	// MaxRetryBackoff 代表最大的重试间隔时间。
+	// 它不限制响应中的Retry-After头指定的时间。若为0，则使用默认值。
+	MaxRetryBackoff time.Duration `json:"max_retry_backoff,omitempty"`
+	// MaxRetryAfter 代表可以接受的Retry-After头指定的最长等待时间。
+	// 若Retry-After头指定的时间超过此值，则放弃重试。若为0，则使用默认值。
+	MaxRetryAfter time.Duration `json:"max_retry_after,omitempty"`
+	// RetryStatusCodes 代表需要重试的HTTP状态码的列表。
+	// 若为nil，则使用默认的列表，其中包括429和503等状态码。
+	RetryStatusCodes []int `json:"retry_status_codes,omitempty"`
+	// IncludePatterns 代表URL的包含模式的列表。
+	// 若不为空，则URL的路径和查询部分（如“/search?q=golang”）
+	// 必须匹配其中至少一个模式，否则请求会被忽略。
+	// 以“re:”开头的模式为正则表达式，其他的模式为需要完整匹配的通配符模式，
+	// 其中的“*”可匹配任意数量的任意字符，“?”可匹配单个任意字符。
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	// ExcludePatterns 代表URL的排除模式的列表，格式与IncludePatterns相同。
+	// URL的路径和查询部分匹配了其中任一模式的请求都会被忽略。
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	// MaxURLsPerHost 代表每个主机最多接受的URL的数量。
+	// 若为0，则不限制。
+	MaxURLsPerHost uint64 `json:"max_urls_per_host,omitempty"`
+	// MaxQueryParams 代表URL中最多可以有的查询参数的数量。
+	// 查询参数过多的请求都会被忽略。若为0，则不限制。
+	MaxQueryParams uint32 `json:"max_query_params,omitempty"`
+	// BlockedExtensions 代表被禁止的文件扩展名的列表，如“.pdf”或“zip”。
+	// URL路径以其中任一扩展名结尾的请求都会被忽略。不区分大小写。
+	BlockedExtensions []string `json:"blocked_extensions,omitempty"`
 }
 
 func (args *RequestArgs) Check() error {
 	if args.AcceptedDomains == nil {
 		return genError("nil accepted primary domain list")
 	}
+	for _, domain := range args.AcceptedDomains {
+		if _, err := normalizeDomainRule(domain); err != nil {
+			return err
+		}
+	}
+	if args.MaxRequestsPerSecond < 0 {
+		return genError("negative max requests per second")
+	}
+	if args.CrawlDelay < 0 {
+		return genError("negative crawl delay")
+	}
+	if args.RetryBackoff < 0 {
+		return genError("negative retry backoff")
+	}
+	if args.MaxRetryBackoff < 0 {
+		return genError("negative max retry backoff")
+	}
+	if args.MaxRetryAfter < 0 {
+		return genError("negative max retry after")
+	}
+	if _, err := newFilterChain(*args); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -34,6 +120,46 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 	if another.MaxDepth != args.MaxDepth {
 		return false
 	}
+	if another.MaxRequestsPerSecond != args.MaxRequestsPerSecond ||
+		another.MaxInFlightPerHost != args.MaxInFlightPerHost ||
+		another.CrawlDelay != args.CrawlDelay {
+		return false
+	}
+	if another.ObeyRobots != args.ObeyRobots ||
+		another.RobotsUserAgent != args.RobotsUserAgent {
+		return false
+	}
+	if another.MaxAttempts != args.MaxAttempts ||
+		another.RetryBackoff != args.RetryBackoff ||
+		another.MaxRetryBackoff != args.MaxRetryBackoff ||
+		another.MaxRetryAfter != args.MaxRetryAfter {
+		return false
+	}
+	if len(another.RetryStatusCodes) != len(args.RetryStatusCodes) {
+		return false
+	}
+	for i, code := range another.RetryStatusCodes {
+		if code != args.RetryStatusCodes[i] {
+			return false
+		}
+	}
+	if another.MaxURLsPerHost != args.MaxURLsPerHost ||
+		another.MaxQueryParams != args.MaxQueryParams {
+		return false
+	}
+	if !sameStrings(another.IncludePatterns, args.IncludePatterns) ||
+		!sameStrings(another.ExcludePatterns, args.ExcludePatterns) ||
+		!sameStrings(another.BlockedExtensions, args.BlockedExtensions) {
+		return false
+	}
+	if len(another.IgnoredQueryParams) != len(args.IgnoredQueryParams) {
+		return false
+	}
+	for i, param := range another.IgnoredQueryParams {
+		if param != args.IgnoredQueryParams[i] {
+			return false
+		}
+	}
 	anotherDomains := another.AcceptedDomains
 	anotherDomainsLen := len(anotherDomains)
 	if anotherDomainsLen != len(args.AcceptedDomains) {
@@ -49,9 +175,23 @@ func (args *RequestArgs) Same(another *RequestArgs) bool {
 	return true
 }
 
+// sameStrings 用于判断两个字符串列表是否相同。
+func sameStrings(one, another []string) bool {
+	if len(one) != len(another) {
+		return false
+	}
+	for i, s := range one {
+		if s != another[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // DataArgs 代表数据相关的参数容器的类型。
 type DataArgs struct {
 	// ReqBufferCap 代表请求缓冲器的容量。
+	// 它与ReqMaxBufferNumber的乘积即为URL边界的容量。
 	ReqBufferCap uint32 `json:"req_buffer_cap"`
 	// ReqMaxBufferNumber 代表请求缓冲器的最大数量。
 	ReqMaxBufferNumber uint32 `json:"req_max_buffer_number"`
@@ -67,6 +207,27 @@ type DataArgs struct {
 	ErrorBufferCap uint32 `json:"error_buffer_cap"`
 	// ErrorMaxBufferNumber 代表错误缓冲器的最大数量。
 	ErrorMaxBufferNumber uint32 `json:"error_max_buffer_number"`
+	// FrontierType 代表URL边界的类型，它决定了请求被下载的顺序。
+	// 若为空，则使用广度优先类型。
+	FrontierType FrontierType `json:"frontier_type,omitempty"`
+	// DeduperType 代表URL去重器的类型。
+	// 若为空，则使用基于并发安全字典的类型。
+	DeduperType DeduperType `json:"deduper_type,omitempty"`
+	// BloomCapacity 代表布隆过滤器预期容纳的URL的数量。
+	// 仅在DeduperType为DEDUPER_TYPE_BLOOM时有效。若为0，则使用默认值。
+	BloomCapacity uint64 `json:"bloom_capacity,omitempty"`
+	// BloomFalsePositiveRate 代表布隆过滤器可接受的误判率。
+	// 仅在DeduperType为DEDUPER_TYPE_BLOOM时有效。若为0，则使用默认值。
+	BloomFalsePositiveRate float64 `json:"bloom_false_positive_rate,omitempty"`
+	// DownloadWorkers 代表下载阶段的工作协程的最大数量。
+	// 若为0，则只使用1个工作协程。
+	DownloadWorkers uint32 `json:"download_workers,omitempty"`
+	// AnalyzeWorkers 代表分析阶段的工作协程的最大数量。
+	// 若为0，则只使用1个工作协程。
+	AnalyzeWorkers uint32 `json:"analyze_workers,omitempty"`
+	// PickWorkers 代表条目处理阶段的工作协程的最大数量。
+	// 若为0，则只使用1个工作协程。
+	PickWorkers uint32 `json:"pick_workers,omitempty"`
 }
 
 func (args *DataArgs) Check() error {
@@ -94,6 +255,16 @@ func (args *DataArgs) Check() error {
 	if args.ErrorMaxBufferNumber == 0 {
 		return genError("zero max error buffer number")
 	}
+	if !LegalFrontierType(args.FrontierType) {
+		return genError(fmt.Sprintf("illegal frontier type: %q", args.FrontierType))
+	}
+	if !LegalDeduperType(args.DeduperType) {
+		return genError(fmt.Sprintf("illegal deduper type: %q", args.DeduperType))
+	}
+	if args.BloomFalsePositiveRate < 0 || args.BloomFalsePositiveRate >= 1 {
+		return genError(fmt.Sprintf("illegal bloom filter false positive rate: %v",
+			args.BloomFalsePositiveRate))
+	}
 	return nil
 }
 
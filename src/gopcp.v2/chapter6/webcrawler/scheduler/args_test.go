@@ -76,6 +76,106 @@ func TestArgsRequest(t *testing.T) {
 		t.Fatalf("Inconsistent request arguments sameness with different accepted domains: expected: %v, actual: %v",
 			false, same)
 	}
+	// 测试礼貌爬取相关的参数。
+	one = genRequestArgs([]string{"bing.com"}, 0)
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.MaxRequestsPerSecond = 2
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different max requests per second: expected: %v, actual: %v",
+			false, same)
+	}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.MaxInFlightPerHost = 2
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different max in-flight per host: expected: %v, actual: %v",
+			false, same)
+	}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.CrawlDelay = time.Second
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different crawl delay: expected: %v, actual: %v",
+			false, same)
+	}
+	// 测试robots.txt相关的参数。
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.ObeyRobots = true
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different robots obedience: expected: %v, actual: %v",
+			false, same)
+	}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.RobotsUserAgent = "gopcp-crawler"
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different robots user agent: expected: %v, actual: %v",
+			false, same)
+	}
+	// 测试重试相关的参数。
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.MaxRetryAfter = time.Hour
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different max retry after: expected: %v, actual: %v",
+			false, same)
+	}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.MaxAttempts = 3
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different max attempts: expected: %v, actual: %v",
+			false, same)
+	}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.RetryStatusCodes = []int{503}
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different retry status codes: expected: %v, actual: %v",
+			false, same)
+	}
+	// 测试URL去重相关的参数。
+	one.IgnoredQueryParams = []string{"utm_*"}
+	another = genRequestArgs([]string{"bing.com"}, 0)
+	another.IgnoredQueryParams = []string{"gclid"}
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different ignored query parameters: expected: %v, actual: %v",
+			false, same)
+	}
+	another.IgnoredQueryParams = nil
+	same = one.Same(&another)
+	if same {
+		t.Fatalf("Inconsistent request arguments sameness with different ignored query parameters: expected: %v, actual: %v",
+			false, same)
+	}
+	requestArgs = genRequestArgs([]string{}, 0)
+	requestArgs.MaxRequestsPerSecond = -1
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with negative max requests per second!")
+	}
+	requestArgs = genRequestArgs([]string{}, 0)
+	requestArgs.CrawlDelay = -time.Second
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with negative crawl delay!")
+	}
+	requestArgs = genRequestArgs([]string{}, 0)
+	requestArgs.RetryBackoff = -time.Second
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with negative retry backoff!")
+	}
+	requestArgs = genRequestArgs([]string{}, 0)
+	requestArgs.MaxRetryBackoff = -time.Second
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with negative max retry backoff!")
+	}
+	requestArgs = genRequestArgs([]string{}, 0)
+	requestArgs.MaxRetryAfter = -time.Second
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when check request arguments with negative max retry after!")
+	}
 }
 
 func TestArgsData(t *testing.T) {
@@ -91,6 +191,15 @@ func TestArgsData(t *testing.T) {
 		dataArgsList = append(
 			dataArgsList, genDataArgsByDetail(values))
 	}
+	dataArgs.FrontierType = "unknown"
+	dataArgsList = append(dataArgsList, dataArgs)
+	dataArgs = genDataArgs(10, 2, 1)
+	dataArgs.DeduperType = "unknown"
+	dataArgsList = append(dataArgsList, dataArgs)
+	dataArgs = genDataArgs(10, 2, 1)
+	dataArgs.DeduperType = DEDUPER_TYPE_BLOOM
+	dataArgs.BloomFalsePositiveRate = 1
+	dataArgsList = append(dataArgsList, dataArgs)
 	for _, dataArgs := range dataArgsList {
 		if err := dataArgs.Check(); err == nil {
 			t.Fatalf("No error when check data arguments! (dataArgs: %#v)",
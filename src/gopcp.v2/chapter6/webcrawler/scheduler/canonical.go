@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams 代表常见的跟踪用查询参数的列表。
+// 可以把它赋给RequestArgs的IgnoredQueryParams字段。
+var DefaultTrackingParams = []string{
+	"utm_*", "gclid", "fbclid", "msclkid", "spm", "from",
+}
+
+// canonicalizer 代表URL规范化器。
+// 规范化后的URL会被用作去重的键。
+type canonicalizer struct {
+	// ignoredParams 代表需要精确忽略的查询参数的集合。
+	ignoredParams map[string]struct{}
+	// ignoredPrefixes 代表需要按前缀忽略的查询参数的列表。
+	ignoredPrefixes []string
+}
+
+// newCanonicalizer 用于创建一个URL规范化器。
+// 参数ignoredParams代表需要忽略的查询参数的名称的列表。
+// 以“*”结尾的名称会按前缀匹配，例如“utm_*”。
+func newCanonicalizer(ignoredParams []string) *canonicalizer {
+	c := &canonicalizer{ignoredParams: map[string]struct{}{}}
+	for _, param := range ignoredParams {
+		if param == "" {
+			continue
+		}
+		if strings.HasSuffix(param, "*") {
+			c.ignoredPrefixes = append(c.ignoredPrefixes,
+				strings.TrimSuffix(param, "*"))
+			continue
+		}
+		c.ignoredParams[param] = struct{}{}
+	}
+	return c
+}
+
+// canonicalize 用于生成给定URL的规范形式。
+// 它会把scheme和主机名转为小写、去掉默认端口和片段、
+// 去掉需要忽略的查询参数，并对其余的查询参数进行排序。
+func (c *canonicalizer) canonicalize(u *url.URL) string {
+	cu := *u
+	cu.Scheme = strings.ToLower(u.Scheme)
+	cu.Host = strings.ToLower(u.Host)
+	if host, port, err := net.SplitHostPort(cu.Host); err == nil {
+		if (cu.Scheme == "http" && port == "80") ||
+			(cu.Scheme == "https" && port == "443") {
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+			cu.Host = host
+		}
+	}
+	cu.Fragment = ""
+	cu.RawFragment = ""
+	cu.ForceQuery = false
+	if cu.Path == "" && cu.Opaque == "" {
+		cu.Path = "/"
+		cu.RawPath = ""
+	}
+	if cu.RawQuery != "" {
+		if values, err := url.ParseQuery(cu.RawQuery); err == nil {
+			for key, vs := range values {
+				if c.ignored(key) {
+					delete(values, key)
+					continue
+				}
+				sort.Strings(vs)
+			}
+			cu.RawQuery = values.Encode()
+		}
+	}
+	return cu.String()
+}
+
+// ignored 用于判断给定的查询参数是否需要被忽略。
+func (c *canonicalizer) ignored(param string) bool {
+	if _, ok := c.ignoredParams[param]; ok {
+		return true
+	}
+	for _, prefix := range c.ignoredPrefixes {
+		if strings.HasPrefix(param, prefix) {
+			return true
+		}
+	}
+	return false
+}
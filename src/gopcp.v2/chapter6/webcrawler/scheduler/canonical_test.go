@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := newCanonicalizer([]string{"utm_*", "gclid", ""})
+	urlMap := map[string]string{
+		"http://a.com/x?b=1&a=2":                   "http://a.com/x?a=2&b=1",
+		"http://A.com/x?a=2&b=1#frag":              "http://a.com/x?a=2&b=1",
+		"HTTP://a.COM:80/x?a=2&b=1":                "http://a.com/x?a=2&b=1",
+		"https://a.com:443/x":                      "https://a.com/x",
+		"https://a.com:8443/x":                     "https://a.com:8443/x",
+		"http://a.com":                             "http://a.com/",
+		"http://a.com/?":                           "http://a.com/",
+		"http://a.com/x?a=2&a=1":                   "http://a.com/x?a=1&a=2",
+		"http://a.com/x?utm_source=x&utm_medium=y": "http://a.com/x",
+		"http://a.com/x?gclid=abc&q=go":            "http://a.com/x?q=go",
+		"http://[::1]:80/x":                        "http://[::1]/x",
+		"http://a.com/X":                           "http://a.com/X",
+	}
+	for rawURL, expected := range urlMap {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("An error occurs when parsing URL %q: %s", rawURL, err)
+		}
+		original := u.String()
+		if actual := c.canonicalize(u); actual != expected {
+			t.Fatalf("Inconsistent canonical URL: expected: %s, actual: %s (URL: %s)",
+				expected, actual, rawURL)
+		}
+		if u.String() != original {
+			t.Fatalf("The original URL has been changed: expected: %s, actual: %s",
+				original, u.String())
+		}
+	}
+	c = newCanonicalizer(nil)
+	u, _ := url.Parse("http://a.com/x?utm_source=x")
+	if actual := c.canonicalize(u); actual != "http://a.com/x?utm_source=x" {
+		t.Fatalf("Inconsistent canonical URL: expected: %s, actual: %s",
+			"http://a.com/x?utm_source=x", actual)
+	}
+}
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"gopcp.v2/chapter5/cmap"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// CheckpointStruct 代表爬取检查点的结构。
+// 它包含了恢复一次爬取所需的全部信息。
+type CheckpointStruct struct {
+	// RequestArgs 代表请求相关的参数。
+	RequestArgs RequestArgs `json:"request_args"`
+	// DataArgs 代表数据相关的参数。
+	DataArgs DataArgs `json:"data_args"`
+	// AcceptedDomains 代表实际可接受的主域名的列表。
+	// 其中还包含了在启动时根据首次请求添加的主域名。
+	AcceptedDomains []string `json:"accepted_primary_domains"`
+	// Deduper 代表记录已处理的URL的去重器的状态。
+	Deduper DeduperDump `json:"deduper"`
+	// HostCounts 代表各个主机已接受的URL的数量。
+	// 只有在设定了每个主机的URL的最大数量时才会被记录。
+	HostCounts map[string]uint64 `json:"host_counts,omitempty"`
+	// Requests 代表尚未完成下载的请求的列表。
+	Requests []CheckpointRequest `json:"requests"`
+}
+
+// CheckpointRequest 代表检查点中的请求的结构。
+type CheckpointRequest struct {
+	// Method 代表HTTP请求的方法。
+	Method string `json:"method"`
+	// URL 代表HTTP请求的URL。
+	URL string `json:"url"`
+	// Header 代表HTTP请求的头部。
+	Header http.Header `json:"header,omitempty"`
+	// Depth 代表请求的深度。
+	Depth uint32 `json:"depth"`
+	// Priority 代表请求的优先级。
+	Priority int `json:"priority,omitempty"`
+	// Meta 代表请求携带的元数据。
+	Meta module.Meta `json:"meta"`
+}
+
+func (sched *myScheduler) Checkpoint(w io.Writer) error {
+	if w == nil {
+		return genParameterError("nil writer")
+	}
+	sched.statusLock.RLock()
+	status := sched.status
+	sched.statusLock.RUnlock()
+	if status == SCHED_STATUS_UNINITIALIZED ||
+		status == SCHED_STATUS_INITIALIZING {
+		return genError("the scheduler has not yet been initialized!")
+	}
+	cp := CheckpointStruct{
+		RequestArgs:     sched.requestArgs,
+		DataArgs:        sched.dataArgs,
+		AcceptedDomains: sortedKeys(sched.acceptedDomainMap),
+		Deduper:         sched.deduper.Dump(),
+		Requests:        []CheckpointRequest{},
+	}
+	if sched.hostLimiter != nil {
+		cp.HostCounts = sched.hostLimiter.dump()
+	}
+	sched.pendingReqMap.Range(func(key string, element interface{}) bool {
+		req, ok := element.(*module.Request)
+		if !ok || !req.Valid() {
+			return true
+		}
+		httpReq := req.HTTPReq()
+		cp.Requests = append(cp.Requests, CheckpointRequest{
+			Method:   httpReq.Method,
+			URL:      httpReq.URL.String(),
+			Header:   httpReq.Header,
+			Depth:    req.Depth(),
+			Priority: req.Priority(),
+			Meta:     req.Meta(),
+		})
+		return true
+	})
+	sort.Slice(cp.Requests, func(i, j int) bool {
+		return cp.Requests[i].URL < cp.Requests[j].URL
+	})
+	if err := json.NewEncoder(w).Encode(cp); err != nil {
+		return genError(fmt.Sprintf("couldn't write checkpoint: %s", err))
+	}
+	logger.Infof("Checkpoint has been written. (URL number: %d, request number: %d)",
+		sched.deduper.Len(), len(cp.Requests))
+	return nil
+}
+
+func (sched *myScheduler) Restore(r io.Reader) error {
+	if r == nil {
+		return genParameterError("nil reader")
+	}
+	var cp CheckpointStruct
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return genError(fmt.Sprintf("couldn't read checkpoint: %s", err))
+	}
+	if err := cp.RequestArgs.Check(); err != nil {
+		return err
+	}
+	if err := cp.DataArgs.Check(); err != nil {
+		return err
+	}
+	reqs := make([]*module.Request, 0, len(cp.Requests))
+	for _, cpReq := range cp.Requests {
+		httpReq, err := http.NewRequest(cpReq.Method, cpReq.URL, nil)
+		if err != nil {
+			return genError(fmt.Sprintf("couldn't restore request: %s", err))
+		}
+		for k, v := range cpReq.Header {
+			httpReq.Header[k] = v
+		}
+		req := module.NewPriorityRequest(
+			httpReq, cpReq.Depth, cpReq.Priority).WithMeta(cpReq.Meta)
+		// 恢复请求的尝试次数，以免已用掉的重试次数被重新计算。
+		for i := uint32(1); i < cpReq.Meta.Attempt; i++ {
+			req = req.Retry()
+		}
+		reqs = append(reqs, req)
+	}
+	deduper, err := NewDeduper(cp.DataArgs)
+	if err != nil {
+		return err
+	}
+	if err := deduper.Load(cp.Deduper); err != nil {
+		return err
+	}
+	// 恢复只能在调度器已初始化且未启动时进行。
+	sched.statusLock.Lock()
+	defer sched.statusLock.Unlock()
+	if sched.status != SCHED_STATUS_INITIALIZED &&
+		sched.status != SCHED_STATUS_STOPPED {
+		return genError(fmt.Sprintf("couldn't restore the scheduler in status %q!",
+			GetStatusDescription(sched.status)))
+	}
+	logger.Info("Restore scheduler from checkpoint...")
+	sched.initRequestArgs(cp.RequestArgs)
+	for _, domain := range cp.AcceptedDomains {
+		sched.acceptedDomainMap.Put(domain, struct{}{})
+	}
+	sched.dataArgs = cp.DataArgs
+	sched.initBufferPool(cp.DataArgs)
+	sched.initWorkers(cp.DataArgs)
+	sched.deduper = deduper
+	if sched.hostLimiter != nil {
+		sched.hostLimiter.load(cp.HostCounts)
+	}
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	for _, req := range reqs {
+		sched.pendingReqMap.Put(req.HTTPReq().URL.String(), req)
+	}
+	sched.restoredReqs = reqs
+	sched.summary = newSchedSummary(
+		cp.RequestArgs, cp.DataArgs, sched.moduleArgs, sched)
+	logger.Infof("Scheduler has been restored. (URL number: %d, request number: %d)",
+		sched.deduper.Len(), len(reqs))
+	return nil
+}
+
+// sortedKeys 用于获取给定字典中所有键的有序列表。
+func sortedKeys(cm cmap.ConcurrentMap) []string {
+	keys := []string{}
+	if cm == nil {
+		return keys
+	}
+	cm.Range(func(key string, element interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
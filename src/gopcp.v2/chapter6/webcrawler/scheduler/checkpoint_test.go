@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestCheckpointAndRestore(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"bing.com"}, 3)
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.DownloadWorkers = 3
+	dataArgs.AnalyzeWorkers = 2
+	dataArgs.PickWorkers = 4
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+	// 测试未初始化状态下的检查点写入。
+	buf := new(bytes.Buffer)
+	if err := sched.Checkpoint(buf); err == nil {
+		t.Fatal("No error when write checkpoint before initialize!")
+	}
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.Checkpoint(nil); err == nil {
+		t.Fatal("No error when write checkpoint with nil writer!")
+	}
+	mySched := sched.(*myScheduler)
+	urls := []string{
+		"http://cn.bing.com/search?q=golang",
+		"http://cn.bing.com/images/search?q=golang",
+		"http://www.bing.com/news/search?q=golang",
+	}
+	for i, url := range urls {
+		httpReq, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+				err, url)
+		}
+		httpReq.Header.Set("User-Agent", "checkpoint-test")
+		req := module.NewRequest(httpReq, uint32(i)).WithMeta(module.Meta{
+			ParentURL: "http://cn.bing.com",
+			Values:    map[string]string{"index": url},
+		})
+		// 模拟最后一个请求已经被重试过的情况。
+		if i == len(urls)-1 {
+			req = req.Retry().Retry()
+		}
+		if !mySched.sendReq(req) {
+			t.Fatalf("Couldn't send request! (request: %#v)", req)
+		}
+	}
+	// 模拟第一个请求已经完成下载的情况。
+	mySched.pendingReqMap.Delete(urls[0])
+	buf.Reset()
+	if err := sched.Checkpoint(buf); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	content := buf.String()
+
+	// 在新的调度器中恢复。
+	another := NewScheduler()
+	anotherArgs := genRequestArgs([]string{}, 0)
+	if err := another.Init(anotherArgs, genDataArgs(10, 2, 1), moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := another.Restore(nil); err == nil {
+		t.Fatal("No error when restore scheduler with nil reader!")
+	}
+	if err := another.Restore(strings.NewReader("{")); err == nil {
+		t.Fatal("No error when restore scheduler with broken checkpoint!")
+	}
+	if err := another.Restore(strings.NewReader(content)); err != nil {
+		t.Fatalf("An error occurs when restoring scheduler: %s", err)
+	}
+	anotherSched := another.(*myScheduler)
+	if anotherSched.maxDepth != requestArgs.MaxDepth {
+		t.Fatalf("Inconsistent max depth: expected: %d, actual: %d",
+			requestArgs.MaxDepth, anotherSched.maxDepth)
+	}
+	workers := [][2]uint32{
+		{dataArgs.DownloadWorkers, anotherSched.downloadTickets.Total()},
+		{dataArgs.AnalyzeWorkers, anotherSched.analyzeTickets.Total()},
+		{dataArgs.PickWorkers, anotherSched.pickTickets.Total()},
+	}
+	for _, w := range workers {
+		if w[0] != w[1] {
+			t.Fatalf("Inconsistent worker number: expected: %d, actual: %d",
+				w[0], w[1])
+		}
+	}
+	if anotherSched.acceptedDomainMap.Get("bing.com") == nil {
+		t.Fatalf("Not found accepted primary domain %q after restoring!", "bing.com")
+	}
+	if anotherSched.deduper.Len() != uint64(len(urls)) {
+		t.Fatalf("Inconsistent URL number: expected: %d, actual: %d",
+			len(urls), anotherSched.deduper.Len())
+	}
+	for _, url := range urls {
+		if !anotherSched.deduper.Contains(url) {
+			t.Fatalf("Not found URL %q after restoring!", url)
+		}
+	}
+	restoredReqs := anotherSched.restoredReqs
+	if len(restoredReqs) != len(urls)-1 {
+		t.Fatalf("Inconsistent restored request number: expected: %d, actual: %d",
+			len(urls)-1, len(restoredReqs))
+	}
+	for _, req := range restoredReqs {
+		httpReq := req.HTTPReq()
+		url := httpReq.URL.String()
+		var expectedDepth, expectedAttempt uint32
+		switch url {
+		case urls[1]:
+			expectedDepth, expectedAttempt = 1, 1
+		case urls[2]:
+			expectedDepth, expectedAttempt = 2, 3
+		default:
+			t.Fatalf("Unexpected restored request with URL %q!", url)
+		}
+		if req.Depth() != expectedDepth {
+			t.Fatalf("Inconsistent depth: expected: %d, actual: %d (URL: %s)",
+				expectedDepth, req.Depth(), url)
+		}
+		if req.Attempt() != expectedAttempt {
+			t.Fatalf("Inconsistent attempt: expected: %d, actual: %d (URL: %s)",
+				expectedAttempt, req.Attempt(), url)
+		}
+		if meta := req.Meta(); meta.ParentURL != "http://cn.bing.com" || meta.Value("index") != url {
+			t.Fatalf("Inconsistent meta: %#v (URL: %s)", meta, url)
+		}
+		if ua := httpReq.Header.Get("User-Agent"); ua != "checkpoint-test" {
+			t.Fatalf("Inconsistent header: expected: %q, actual: %q (URL: %s)",
+				"checkpoint-test", ua, url)
+		}
+	}
+	summary := another.Summary().Struct()
+	if !summary.RequestArgs.Same(&requestArgs) {
+		t.Fatalf("Inconsistent request arguments in summary: expected: %#v, actual: %#v",
+			requestArgs, summary.RequestArgs)
+	}
+	// 测试已恢复的调度器在没有首次请求时的启动。
+	if err := another.Start(nil); err != nil {
+		t.Fatalf("An error occurs when starting restored scheduler: %s", err)
+	}
+	if len(anotherSched.restoredReqs) != 0 {
+		t.Fatalf("Inconsistent restored request number after start: expected: %d, actual: %d",
+			0, len(anotherSched.restoredReqs))
+	}
+	// 测试已启动状态下的恢复。
+	if err := another.Restore(strings.NewReader(content)); err == nil {
+		t.Fatal("No error when restore scheduler after start!")
+	}
+	if err := another.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+	// 测试已停止状态下的检查点写入。
+	buf.Reset()
+	if err := another.Checkpoint(buf); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint after stop: %s", err)
+	}
+}
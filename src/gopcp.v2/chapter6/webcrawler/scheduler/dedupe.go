@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+
+	"gopcp.v2/chapter5/cmap"
+)
+
+// DeduperType 代表去重器的类型。
+type DeduperType string
+
+const (
+	// DEDUPER_TYPE_MAP 代表基于并发安全字典的去重器。
+	// 它会精确地记录所有的键，占用的内存会随着键的增多而增长。
+	DEDUPER_TYPE_MAP DeduperType = "map"
+	// DEDUPER_TYPE_BLOOM 代表基于布隆过滤器的去重器。
+	// 它占用的内存是固定的，但有一定概率把未添加过的键误判为已存在。
+	DEDUPER_TYPE_BLOOM DeduperType = "bloom"
+)
+
+const (
+	// defaultBloomCapacity 代表布隆过滤器默认的预期键数量。
+	defaultBloomCapacity = 1 << 20
+	// defaultBloomFalsePositiveRate 代表布隆过滤器默认的误判率。
+	defaultBloomFalsePositiveRate = 0.001
+)
+
+// LegalDeduperType 用于判断给定的去重器类型是否合法。
+// 空字符串会被视为基于并发安全字典的类型。
+func LegalDeduperType(deduperType DeduperType) bool {
+	switch deduperType {
+	case "", DEDUPER_TYPE_MAP, DEDUPER_TYPE_BLOOM:
+		return true
+	}
+	return false
+}
+
+// Deduper 代表去重器的接口类型。
+// 调度器使用它来记录已处理的URL。
+type Deduper interface {
+	// Type 用于获取去重器的类型。
+	Type() DeduperType
+	// Add 用于添加键。
+	// 若该键之前未被添加过则返回true，否则返回false。
+	Add(key string) bool
+	// Contains 用于判断给定的键是否已被添加过。
+	Contains(key string) bool
+	// Len 用于获取已添加的键的数量。
+	Len() uint64
+	// Dump 用于导出去重器的状态，以便写入检查点。
+	Dump() DeduperDump
+	// Load 用于从导出的状态中恢复去重器。
+	// 之前添加的键都会被清除。
+	Load(dump DeduperDump) error
+}
+
+// DeduperDump 代表导出的去重器状态的结构。
+type DeduperDump struct {
+	// Type 代表去重器的类型。
+	Type DeduperType `json:"type"`
+	// Keys 代表已添加的键的有序列表。仅用于基于字典的去重器。
+	Keys []string `json:"keys,omitempty"`
+	// Bits 代表布隆过滤器的位数组。
+	Bits []byte `json:"bits,omitempty"`
+	// HashNumber 代表布隆过滤器使用的哈希函数的数量。
+	HashNumber uint32 `json:"hash_number,omitempty"`
+	// Count 代表布隆过滤器中已添加的键的数量。
+	Count uint64 `json:"count,omitempty"`
+}
+
+// NewDeduper 用于根据数据相关的参数创建一个去重器。
+func NewDeduper(dataArgs DataArgs) (Deduper, error) {
+	switch dataArgs.DeduperType {
+	case "", DEDUPER_TYPE_MAP:
+		return NewMapDeduper(), nil
+	case DEDUPER_TYPE_BLOOM:
+		capacity := dataArgs.BloomCapacity
+		if capacity == 0 {
+			capacity = defaultBloomCapacity
+		}
+		rate := dataArgs.BloomFalsePositiveRate
+		if rate == 0 {
+			rate = defaultBloomFalsePositiveRate
+		}
+		return NewBloomDeduper(capacity, rate)
+	}
+	errMsg := fmt.Sprintf("illegal deduper type: %q", dataArgs.DeduperType)
+	return nil, genParameterError(errMsg)
+}
+
+// NewMapDeduper 用于创建一个基于并发安全字典的去重器。
+func NewMapDeduper() Deduper {
+	m, _ := cmap.NewConcurrentMap(16, nil)
+	return &mapDeduper{m: m}
+}
+
+// mapDeduper 代表基于并发安全字典的去重器的实现类型。
+type mapDeduper struct {
+	// m 代表存放键的并发安全字典。
+	m cmap.ConcurrentMap
+}
+
+func (md *mapDeduper) Type() DeduperType {
+	return DEDUPER_TYPE_MAP
+}
+
+func (md *mapDeduper) Add(key string) bool {
+	ok, _ := md.m.Put(key, struct{}{})
+	return ok
+}
+
+func (md *mapDeduper) Contains(key string) bool {
+	return md.m.Get(key) != nil
+}
+
+func (md *mapDeduper) Len() uint64 {
+	return md.m.Len()
+}
+
+func (md *mapDeduper) Dump() DeduperDump {
+	keys := []string{}
+	md.m.Range(func(key string, element interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Strings(keys)
+	return DeduperDump{Type: DEDUPER_TYPE_MAP, Keys: keys}
+}
+
+func (md *mapDeduper) Load(dump DeduperDump) error {
+	if dump.Type != DEDUPER_TYPE_MAP {
+		errMsg := fmt.Sprintf("inconsistent deduper type: expected: %s, actual: %s",
+			DEDUPER_TYPE_MAP, dump.Type)
+		return genParameterError(errMsg)
+	}
+	m, _ := cmap.NewConcurrentMap(16, nil)
+	for _, key := range dump.Keys {
+		m.Put(key, struct{}{})
+	}
+	md.m = m
+	return nil
+}
+
+// NewBloomDeduper 用于创建一个基于布隆过滤器的去重器。
+// 参数capacity代表预期的键数量。
+// 参数falsePositiveRate代表在键数量达到预期时可接受的误判率，
+// 其值必须在0和1之间（不含）。
+// 位数组的大小和哈希函数的数量会据此计算得出。
+func NewBloomDeduper(capacity uint64, falsePositiveRate float64) (Deduper, error) {
+	if capacity == 0 {
+		return nil, genParameterError("zero bloom filter capacity")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		errMsg := fmt.Sprintf("illegal bloom filter false positive rate: %v",
+			falsePositiveRate)
+		return nil, genParameterError(errMsg)
+	}
+	bitNumber := uint64(math.Ceil(-float64(capacity) *
+		math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashNumber := uint32(math.Round(float64(bitNumber) / float64(capacity) * math.Ln2))
+	if hashNumber == 0 {
+		hashNumber = 1
+	}
+	return &bloomDeduper{
+		bits:       make([]byte, (bitNumber+7)/8),
+		hashNumber: hashNumber,
+	}, nil
+}
+
+// bloomDeduper 代表基于布隆过滤器的去重器的实现类型。
+type bloomDeduper struct {
+	// bits 代表位数组。
+	bits []byte
+	// hashNumber 代表哈希函数的数量。
+	hashNumber uint32
+	// count 代表已添加的键的数量。
+	count uint64
+	// lock 代表保护位数组的互斥锁。
+	lock sync.Mutex
+}
+
+func (bd *bloomDeduper) Type() DeduperType {
+	return DEDUPER_TYPE_BLOOM
+}
+
+func (bd *bloomDeduper) Add(key string) bool {
+	h1, h2 := bloomHash(key)
+	bitNumber := uint64(len(bd.bits)) * 8
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	added := false
+	for i := uint32(0); i < bd.hashNumber; i++ {
+		pos := (h1 + uint64(i)*h2) % bitNumber
+		mask := byte(1) << (pos % 8)
+		if bd.bits[pos/8]&mask == 0 {
+			bd.bits[pos/8] |= mask
+			added = true
+		}
+	}
+	if added {
+		bd.count++
+	}
+	return added
+}
+
+func (bd *bloomDeduper) Contains(key string) bool {
+	h1, h2 := bloomHash(key)
+	bitNumber := uint64(len(bd.bits)) * 8
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	for i := uint32(0); i < bd.hashNumber; i++ {
+		pos := (h1 + uint64(i)*h2) % bitNumber
+		if bd.bits[pos/8]&(byte(1)<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (bd *bloomDeduper) Len() uint64 {
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	return bd.count
+}
+
+func (bd *bloomDeduper) Dump() DeduperDump {
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	bits := make([]byte, len(bd.bits))
+	copy(bits, bd.bits)
+	return DeduperDump{
+		Type:       DEDUPER_TYPE_BLOOM,
+		Bits:       bits,
+		HashNumber: bd.hashNumber,
+		Count:      bd.count,
+	}
+}
+
+func (bd *bloomDeduper) Load(dump DeduperDump) error {
+	if dump.Type != DEDUPER_TYPE_BLOOM {
+		errMsg := fmt.Sprintf("inconsistent deduper type: expected: %s, actual: %s",
+			DEDUPER_TYPE_BLOOM, dump.Type)
+		return genParameterError(errMsg)
+	}
+	bd.lock.Lock()
+	defer bd.lock.Unlock()
+	if len(dump.Bits) != len(bd.bits) || dump.HashNumber != bd.hashNumber {
+		errMsg := fmt.Sprintf("inconsistent bloom filter: expected: %d bytes and %d hashes, actual: %d bytes and %d hashes",
+			len(bd.bits), bd.hashNumber, len(dump.Bits), dump.HashNumber)
+		return genParameterError(errMsg)
+	}
+	copy(bd.bits, dump.Bits)
+	bd.count = dump.Count
+	return nil
+}
+
+// bloomHash 用于计算给定键的两个哈希值。
+// 布隆过滤器会用它们组合出所需数量的哈希值。
+func bloomHash(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h = fnv.New64()
+	h.Write([]byte(key))
+	// 保证第二个哈希值为奇数，以免组合出的哈希值重复。
+	h2 := h.Sum64() | 1
+	return h1, h2
+}
@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestDeduperNew(t *testing.T) {
+	dataArgs := genDataArgs(10, 2, 1)
+	deduper, err := NewDeduper(dataArgs)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a deduper: %s", err)
+	}
+	if deduper.Type() != DEDUPER_TYPE_MAP {
+		t.Fatalf("Inconsistent deduper type: expected: %s, actual: %s",
+			DEDUPER_TYPE_MAP, deduper.Type())
+	}
+	dataArgs.DeduperType = DEDUPER_TYPE_BLOOM
+	deduper, err = NewDeduper(dataArgs)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a deduper: %s", err)
+	}
+	if deduper.Type() != DEDUPER_TYPE_BLOOM {
+		t.Fatalf("Inconsistent deduper type: expected: %s, actual: %s",
+			DEDUPER_TYPE_BLOOM, deduper.Type())
+	}
+	dataArgs.DeduperType = "unknown"
+	if _, err := NewDeduper(dataArgs); err == nil {
+		t.Fatal("No error when create a deduper with illegal type!")
+	}
+	if _, err := NewBloomDeduper(0, 0.01); err == nil {
+		t.Fatal("No error when create a bloom deduper with zero capacity!")
+	}
+	for _, rate := range []float64{0, 1, -0.1} {
+		if _, err := NewBloomDeduper(100, rate); err == nil {
+			t.Fatalf("No error when create a bloom deduper with illegal false positive rate %v!",
+				rate)
+		}
+	}
+}
+
+func TestDeduperAddAndContains(t *testing.T) {
+	bloomDeduper, _ := NewBloomDeduper(1000, 0.001)
+	dedupers := []Deduper{NewMapDeduper(), bloomDeduper}
+	number := 1000
+	for _, deduper := range dedupers {
+		for i := 0; i < number; i++ {
+			key := fmt.Sprintf("http://cn.bing.com/search?p=%d", i)
+			if deduper.Contains(key) {
+				continue
+			}
+			if !deduper.Add(key) {
+				t.Fatalf("Couldn't add key %q! (type: %s)", key, deduper.Type())
+			}
+			if deduper.Add(key) {
+				t.Fatalf("It still can add repeated key %q! (type: %s)",
+					key, deduper.Type())
+			}
+			if !deduper.Contains(key) {
+				t.Fatalf("Not found key %q! (type: %s)", key, deduper.Type())
+			}
+		}
+		// 布隆过滤器可能存在误判，因此只检查数量的下限。
+		minLen := uint64(number) * 99 / 100
+		if deduper.Len() < minLen || deduper.Len() > uint64(number) {
+			t.Fatalf("Inconsistent deduper length: expected: [%d, %d], actual: %d (type: %s)",
+				minLen, number, deduper.Len(), deduper.Type())
+		}
+	}
+	// 测试布隆过滤器的误判率。
+	var falsePositives int
+	for i := number; i < number*11; i++ {
+		if bloomDeduper.Contains(fmt.Sprintf("http://cn.bing.com/search?p=%d", i)) {
+			falsePositives++
+		}
+	}
+	if maxFalsePositives := number * 10 / 100; falsePositives > maxFalsePositives {
+		t.Fatalf("Too many false positives: expected: <= %d, actual: %d",
+			maxFalsePositives, falsePositives)
+	}
+}
+
+func TestDeduperDumpAndLoad(t *testing.T) {
+	bloomDeduper, _ := NewBloomDeduper(100, 0.01)
+	anotherBloomDeduper, _ := NewBloomDeduper(100, 0.01)
+	pairs := [][2]Deduper{
+		{NewMapDeduper(), NewMapDeduper()},
+		{bloomDeduper, anotherBloomDeduper},
+	}
+	keys := []string{
+		"http://cn.bing.com/search?q=golang",
+		"http://cn.bing.com/images/search?q=golang",
+	}
+	for _, pair := range pairs {
+		one, another := pair[0], pair[1]
+		for _, key := range keys {
+			one.Add(key)
+		}
+		if err := another.Load(one.Dump()); err != nil {
+			t.Fatalf("An error occurs when loading deduper: %s (type: %s)",
+				err, one.Type())
+		}
+		if another.Len() != one.Len() {
+			t.Fatalf("Inconsistent deduper length: expected: %d, actual: %d (type: %s)",
+				one.Len(), another.Len(), one.Type())
+		}
+		for _, key := range keys {
+			if !another.Contains(key) {
+				t.Fatalf("Not found key %q after loading! (type: %s)",
+					key, one.Type())
+			}
+		}
+	}
+	// 测试状态不匹配的情况。
+	if err := NewMapDeduper().Load(bloomDeduper.Dump()); err == nil {
+		t.Fatal("No error when load map deduper with bloom filter dump!")
+	}
+	if err := bloomDeduper.Load(NewMapDeduper().Dump()); err == nil {
+		t.Fatal("No error when load bloom deduper with map dump!")
+	}
+	smallBloomDeduper, _ := NewBloomDeduper(10, 0.01)
+	if err := smallBloomDeduper.Load(bloomDeduper.Dump()); err == nil {
+		t.Fatal("No error when load bloom deduper with different size!")
+	}
+}
+
+// slowDeduper 代表会在每次操作之前让出执行权的去重器，
+// 用于放大并发操作之间的竞态窗口。
+type slowDeduper struct {
+	Deduper
+}
+
+func (sd slowDeduper) Add(key string) bool {
+	time.Sleep(time.Millisecond)
+	return sd.Deduper.Add(key)
+}
+
+func (sd slowDeduper) Contains(key string) bool {
+	time.Sleep(time.Millisecond)
+	return sd.Deduper.Contains(key)
+}
+
+func TestSchedSendReqConcurrently(t *testing.T) {
+	for _, deduperType := range []DeduperType{DEDUPER_TYPE_MAP, DEDUPER_TYPE_BLOOM} {
+		dataArgs := genDataArgs(10, 2, 1)
+		dataArgs.DeduperType = deduperType
+		sched := &myScheduler{}
+		err := sched.Init(genRequestArgs([]string{"example.com"}, 1),
+			dataArgs, genSimpleModuleArgs(1, 1, 1, t))
+		if err != nil {
+			t.Fatalf("An error occurs when initializing scheduler: %s", err)
+		}
+		sched.deduper = slowDeduper{sched.deduper}
+		number := 100
+		var accepted uint32
+		var wg sync.WaitGroup
+		wg.Add(number)
+		start := make(chan struct{})
+		for i := 0; i < number; i++ {
+			// 规范化之后相同的URL。
+			rawURL := "http://example.com/a.html"
+			if i%2 == 1 {
+				rawURL = "http://EXAMPLE.com:80/a.html#top"
+			}
+			go func(rawURL string) {
+				defer wg.Done()
+				<-start
+				httpReq, _ := http.NewRequest("GET", rawURL, nil)
+				if sched.sendReq(module.NewRequest(httpReq, 0)) {
+					atomic.AddUint32(&accepted, 1)
+				}
+			}(rawURL)
+		}
+		close(start)
+		wg.Wait()
+		if accepted != 1 {
+			t.Fatalf("Inconsistent accepted number: expected: %d, actual: %d (type: %s)",
+				1, accepted, deduperType)
+		}
+		if n := sched.Summary().Struct().Rejected[rejectReasonDuplicate]; n != uint64(number-1) {
+			t.Fatalf("Inconsistent rejected count: expected: %d, actual: %d (type: %s)",
+				number-1, n, deduperType)
+		}
+	}
+}
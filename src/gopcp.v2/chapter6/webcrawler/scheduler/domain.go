@@ -1,60 +1,119 @@
 package scheduler
 
 import (
-	"regexp"
+	"fmt"
+	"net"
+	"net/http"
 	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"gopcp.v2/chapter5/cmap"
 )
 
-var regexpForIP = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
-
-var regexpForDomains = []*regexp.Regexp{
-	// *.xx or *.xxx.xx
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
+// splitHostPort 用于把给定的主机拆分为主机名和端口。
+// 主机名会被转为小写，并去掉末尾的“.”以及IPv6地址两侧的方括号。
+// 若没有端口，则端口为空字符串。
+func splitHostPort(hostport string) (host, port string) {
+	hostport = strings.ToLower(strings.TrimSpace(hostport))
+	host = hostport
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return
 }
 
 // getPrimaryDomain 用于获取给定主机名的主域名。
+// 主域名即根据公共后缀列表（Public Suffix List）得出的eTLD+1，
+// 例如“www.example.co.uk”的主域名为“example.co.uk”。
+// 主机名可以带有端口，端口不会包含在结果中。
+// 对于IP地址（包括IPv6地址），主域名即为该地址本身。
 func getPrimaryDomain(host string) (string, error) {
-	host = strings.TrimSpace(host)
+	host, _ = splitHostPort(host)
 	if host == "" {
 		return "", genError("empty host")
 	}
-	if regexpForIP.MatchString(host) {
+	if net.ParseIP(host) != nil {
 		return host, nil
 	}
-	var suffixIndex int
-	for _, re := range regexpForDomains {
-		pos := re.FindStringIndex(host)
-		if pos != nil {
-			suffixIndex = pos[0]
-			break
+	pd, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", genError(fmt.Sprintf("unrecognized host: %s", err))
+	}
+	return pd, nil
+}
+
+// normalizeDomainRule 用于检查并规范化给定的可接受主域名的规则。
+// 规则可以是以下几种形式之一：
+//   - 主域名，如“example.com”，可匹配该主域名下的所有主机；
+//   - 主机名，如“docs.example.com”，只可匹配该主机；
+//   - 通配符，如“*.example.com”，可匹配该域名的所有子域名，但不包括其本身；
+//   - 带有端口的主机名或IP地址，如“127.0.0.1:8080”，只可匹配该主机和端口。
+func normalizeDomainRule(rule string) (string, error) {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	if strings.HasPrefix(rule, "*.") {
+		suffix := strings.TrimSuffix(rule[2:], ".")
+		if suffix == "" || strings.ContainsAny(suffix, "*:/") {
+			return "", genError(fmt.Sprintf("illegal domain rule: %q", rule))
 		}
+		return "*." + suffix, nil
 	}
-	if suffixIndex > 0 {
-		var pdIndex int
-		firstPart := host[:suffixIndex]
-		index := strings.LastIndex(firstPart, ".")
-		if index < 0 {
-			pdIndex = 0
-		} else {
-			pdIndex = index + 1
+	host, port := splitHostPort(rule)
+	if host == "" || strings.ContainsAny(host, "*/") {
+		return "", genError(fmt.Sprintf("illegal domain rule: %q", rule))
+	}
+	if port != "" {
+		return net.JoinHostPort(host, port), nil
+	}
+	return host, nil
+}
+
+// matchDomainRules 用于判断给定的主机是否符合字典中的任一可接受主域名的规则。
+// 字典中的键应该都是经过规范化的规则。
+func matchDomainRules(ruleMap cmap.ConcurrentMap, hostport string) bool {
+	host, port := splitHostPort(hostport)
+	if host == "" {
+		return false
+	}
+	keys := []string{host}
+	if port != "" {
+		keys = append(keys, net.JoinHostPort(host, port))
+	}
+	if pd, err := getPrimaryDomain(host); err == nil && pd != host {
+		keys = append(keys, pd)
+	}
+	if net.ParseIP(host) == nil {
+		for suffix := host; ; {
+			index := strings.Index(suffix, ".")
+			if index < 0 {
+				break
+			}
+			suffix = suffix[index+1:]
+			keys = append(keys, "*."+suffix)
+		}
+	}
+	for _, key := range keys {
+		if ruleMap.Get(key) != nil {
+			return true
 		}
-		return host[pdIndex:], nil
 	}
-	return "", genError("unrecognized host")
+	return false
+}
+
+// getHostAndDomain 用于获取给定HTTP请求的主机名及其主域名。
+// 若无法识别主域名，则以主机名代替。
+func getHostAndDomain(httpReq *http.Request) (host string, domain string) {
+	if httpReq == nil {
+		return
+	}
+	host = httpReq.Host
+	if host == "" && httpReq.URL != nil {
+		host = httpReq.URL.Host
+	}
+	var err error
+	if domain, err = getPrimaryDomain(host); err != nil {
+		domain = host
+	}
+	return
 }
@@ -1,6 +1,10 @@
 package scheduler
 
-import "testing"
+import (
+	"testing"
+
+	"gopcp.v2/chapter5/cmap"
+)
 
 func TestGetPrimaryDomain(t *testing.T) {
 	host := "127.0.0.1"
@@ -28,9 +32,94 @@ func TestGetPrimaryDomain(t *testing.T) {
 	if err == nil {
 		t.Fatal("It still can get primary domain for a empty host!")
 	}
-	host = "123.abc"
-	_, err = getPrimaryDomain(host)
-	if err == nil {
-		t.Fatalf("It still can get primary domain for a unrecognized host %q!", host)
+	for _, host := range []string{"localhost", "co.uk", "github.io"} {
+		_, err = getPrimaryDomain(host)
+		if err == nil {
+			t.Fatalf("It still can get primary domain for a unrecognized host %q!", host)
+		}
+	}
+	hostMap := map[string]string{
+		"127.0.0.1:8080":         "127.0.0.1",
+		"[::1]:8080":             "::1",
+		"[2001:db8::1]":          "2001:db8::1",
+		"WWW.Example.COM:443":    "example.com",
+		"www.example.com.":       "example.com",
+		"www.bbc.co.uk":          "bbc.co.uk",
+		"gopcp.github.io":        "gopcp.github.io",
+		"a.b.gopcp.github.io":    "gopcp.github.io",
+		"blog.example.app":       "example.app",
+		"news.example.com.cn:80": "example.com.cn",
+	}
+	for host, expectedPD := range hostMap {
+		pd, err := getPrimaryDomain(host)
+		if err != nil {
+			t.Fatalf("An error occurs when getting primary domain: %s (host: %s)",
+				err, host)
+		}
+		if pd != expectedPD {
+			t.Fatalf("Inconsistent primary domain: expected: %s, actual: %s (host: %s)",
+				expectedPD, pd, host)
+		}
+	}
+}
+
+func TestNormalizeDomainRule(t *testing.T) {
+	ruleMap := map[string]string{
+		"Example.COM":       "example.com",
+		" docs.example.com": "docs.example.com",
+		"*.Example.com.":    "*.example.com",
+		"127.0.0.1:8080":    "127.0.0.1:8080",
+		"[::1]:8080":        "[::1]:8080",
+		"::1":               "::1",
+	}
+	for rule, expected := range ruleMap {
+		actual, err := normalizeDomainRule(rule)
+		if err != nil {
+			t.Fatalf("An error occurs when normalizing domain rule: %s (rule: %q)",
+				err, rule)
+		}
+		if actual != expected {
+			t.Fatalf("Inconsistent domain rule: expected: %q, actual: %q",
+				expected, actual)
+		}
+	}
+	for _, rule := range []string{"", "*", "*.", "*.*.com", "example.com/path"} {
+		if _, err := normalizeDomainRule(rule); err == nil {
+			t.Fatalf("No error when normalizing illegal domain rule %q!", rule)
+		}
+	}
+}
+
+func TestMatchDomainRules(t *testing.T) {
+	ruleMap, _ := cmap.NewConcurrentMap(1, nil)
+	for _, rule := range []string{
+		"example.co.uk", "docs.example.com", "*.example.org",
+		"127.0.0.1:8080", "::1",
+	} {
+		ruleMap.Put(rule, struct{}{})
+	}
+	hostMap := map[string]bool{
+		"example.co.uk":          true,
+		"www.example.co.uk:8080": true,
+		"other.co.uk":            false,
+		"docs.example.com":       true,
+		"DOCS.example.com:443":   true,
+		"www.example.com":        false,
+		"example.com":            false,
+		"www.example.org":        true,
+		"a.b.example.org":        true,
+		"example.org":            false,
+		"127.0.0.1:8080":         true,
+		"127.0.0.1:8081":         false,
+		"127.0.0.1":              false,
+		"[::1]:8080":             true,
+		"[::1]":                  true,
+		"":                       false,
+	}
+	for host, expected := range hostMap {
+		if actual := matchDomainRules(ruleMap, host); actual != expected {
+			t.Fatalf("Inconsistent matching result: expected: %v, actual: %v (host: %q)",
+				expected, actual, host)
+		}
 	}
 }
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// drainer 代表进行中的下载、分析和条目处理工作的跟踪器。
+// 它用于在优雅停止调度器时等待这些工作全部完成。
+type drainer struct {
+	// lock 代表保护各个字段的互斥锁。
+	lock sync.Mutex
+	// draining 代表是否正在排空。
+	draining bool
+	// active 代表正在下载的请求、待分析的响应和待处理的条目的数量。
+	active int64
+	// idleCh 代表排空完成时会被关闭的通道。
+	idleCh chan struct{}
+}
+
+// reset 用于重置跟踪器。
+func (d *drainer) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.draining = false
+	d.active = 0
+	d.idleCh = nil
+}
+
+// begin 用于在开始下载之前登记一个工作。
+// 若正在排空，则不会登记并返回false，此时不应再开始下载。
+func (d *drainer) begin() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+// add 用于登记一个由已登记的工作产生的工作，例如下载得到的响应或分析得到的条目。
+// 无论是否正在排空都会登记。
+// 注意！它必须在产生该工作的父工作被注销之前调用。
+func (d *drainer) add() {
+	d.lock.Lock()
+	d.active++
+	d.lock.Unlock()
+}
+
+// done 用于注销一个已登记的工作。
+func (d *drainer) done() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.active--
+	if d.draining && d.active <= 0 && d.idleCh != nil {
+		close(d.idleCh)
+		d.idleCh = nil
+	}
+}
+
+// isDraining 用于判断是否正在排空。
+func (d *drainer) isDraining() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.draining
+}
+
+// count 用于获取已登记但尚未注销的工作的数量。
+func (d *drainer) count() int64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.active
+}
+
+// drain 用于开始排空，并返回一个在所有已登记的工作都被注销之后关闭的通道。
+func (d *drainer) drain() <-chan struct{} {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.draining = true
+	ch := make(chan struct{})
+	if d.active <= 0 {
+		close(ch)
+	} else {
+		d.idleCh = ch
+	}
+	return ch
+}
+
+// StopGracefully 用于优雅地停止调度器。
+// 它会先停止下载新的请求，并等待正在下载的请求完成下载、
+// 已下载的响应被分析以及已生成的条目被处理，然后再停止调度器。
+// URL边界中尚未开始下载的请求以及在此期间分析得到的新请求都会被保留在检查点中。
+// 若在这些工作完成之前ctx已被取消，则调度器会被立即停止，且结果值为ctx.Err()。
+func (sched *myScheduler) StopGracefully(ctx context.Context) (err error) {
+	if ctx == nil {
+		return genParameterError("nil context")
+	}
+	logger.Info("Stop scheduler gracefully...")
+	// 检查状态。
+	logger.Info("Check status for stop...")
+	if _, err = sched.checkAndSetStatus(SCHED_STATUS_STOPPING); err != nil {
+		return
+	}
+	defer func() {
+		sched.statusLock.Lock()
+		sched.status = SCHED_STATUS_STOPPED
+		sched.statusLock.Unlock()
+	}()
+	idleCh := sched.drainer.drain()
+	// 已暂停的调度器也需要处理完已有的响应和条目。
+	sched.gate.open()
+	logger.Info("Wait for in-flight work to be drained...")
+	select {
+	case <-idleCh:
+		logger.Info("All in-flight work has been drained.")
+	case <-ctx.Done():
+		err = ctx.Err()
+		logger.Warnf("Stop draining: %s (in-flight: %d)\n",
+			err, sched.drainer.count())
+	}
+	sched.shutdown()
+	logger.Info("Scheduler has been stopped.")
+	return
+}
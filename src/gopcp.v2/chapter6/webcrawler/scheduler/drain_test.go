@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedStopGracefully(t *testing.T) {
+	releaseCh := make(chan struct{})
+	var indexHits, otherHits int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&indexHits, 1)
+		<-releaseCh
+		fmt.Fprint(w, "<html><body>")
+		for _, link := range []string{"/a.html", "/b.html", "/c.html"} {
+			fmt.Fprintf(w, "<a href=%q>link</a>", link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&otherHits, 1)
+		fmt.Fprint(w, "<html><body></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	sched := &myScheduler{}
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 1),
+		genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.StopGracefully(context.Background()); err == nil {
+		t.Fatalf("No error when stopping a scheduler which has not been started!")
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&indexHits) == 1
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sched.StopGracefully(ctx)
+	}()
+	waitFor(t, func() bool {
+		return sched.Status() == SCHED_STATUS_STOPPING
+	})
+	if err := sched.Stop(); err == nil {
+		t.Fatalf("No error when stopping a scheduler which is being stopped!")
+	}
+	// 正在下载的响应会被分析，由其生成的条目也都会被处理。
+	close(releaseCh)
+	if err := <-errCh; err != nil {
+		t.Fatalf("An error occurs when stopping scheduler gracefully: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED), GetStatusDescription(status))
+	}
+	summary := sched.Summary().Struct()
+	if n := summary.Pipelines[0].Completed; n != 3 {
+		t.Fatalf("Inconsistent completed item number: expected: %d, actual: %d", 3, n)
+	}
+	// 分析得到的新请求都不会被下载，但会被保留在检查点中。
+	if n := atomic.LoadInt64(&otherHits); n != 0 {
+		t.Fatalf("Inconsistent hit number: expected: %d, actual: %d", 0, n)
+	}
+	if summary.NumURL != 4 {
+		t.Fatalf("Inconsistent URL number: expected: %d, actual: %d",
+			4, summary.NumURL)
+	}
+	var buf bytes.Buffer
+	if err := sched.Checkpoint(&buf); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	var cp CheckpointStruct
+	if err := json.Unmarshal(buf.Bytes(), &cp); err != nil {
+		t.Fatalf("An error occurs when decoding checkpoint: %s", err)
+	}
+	expectedURLs := []string{
+		server.URL + "/a.html",
+		server.URL + "/b.html",
+		server.URL + "/c.html",
+	}
+	if len(cp.Requests) != len(expectedURLs) {
+		t.Fatalf("Inconsistent checkpoint request number: expected: %d, actual: %d",
+			len(expectedURLs), len(cp.Requests))
+	}
+	for i, u := range expectedURLs {
+		if cp.Requests[i].URL != u {
+			t.Fatalf("Inconsistent checkpoint request URL: expected: %s, actual: %s",
+				u, cp.Requests[i].URL)
+		}
+	}
+}
+
+func TestSchedStopGracefullyTimeout(t *testing.T) {
+	blockCh := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blockCh:
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer close(blockCh)
+	serverURL, _ := url.Parse(server.URL)
+	sched := &myScheduler{}
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 0),
+		genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL, nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	waitFor(t, func() bool {
+		return sched.drainer.count() == 1
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := sched.StopGracefully(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v",
+			context.DeadlineExceeded, err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED), GetStatusDescription(status))
+	}
+}
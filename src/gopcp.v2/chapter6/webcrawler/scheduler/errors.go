@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"sync"
+
 	"gopcp.v2/chapter6/webcrawler/errors"
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
@@ -33,22 +35,8 @@ func sendError(err error, mid module.MID, errorBufferPool buffer.Pool) bool {
 	var ok bool
 	crawlerError, ok = err.(errors.CrawlerError)
 	if !ok {
-		var moduleType module.Type
-		var errorType errors.ErrorType
-		ok, moduleType = module.GetType(mid)
-		if !ok {
-			errorType = errors.ERROR_TYPE_SCHEDULER
-		} else {
-			switch moduleType {
-			case module.TYPE_DOWNLOADER:
-				errorType = errors.ERROR_TYPE_DOWNLOADER
-			case module.TYPE_ANALYZER:
-				errorType = errors.ERROR_TYPE_ANALYZER
-			case module.TYPE_PIPELINE:
-				errorType = errors.ERROR_TYPE_PIPELINE
-			}
-		}
-		crawlerError = errors.NewCrawlerError(errorType, err.Error())
+		crawlerError = errors.NewCrawlerErrorWithContext(
+			getErrorType(mid), err, errors.ErrorContext{MID: string(mid)})
 	}
 	if errorBufferPool.Closed() {
 		return false
@@ -60,3 +48,121 @@ func sendError(err error, mid module.MID, errorBufferPool buffer.Pool) bool {
 	}(crawlerError)
 	return true
 }
+
+// getErrorType 用于根据给定的组件ID获取相应的错误类型。
+// 若组件ID不合法，则返回调度器错误的类型。
+func getErrorType(mid module.MID) errors.ErrorType {
+	ok, moduleType := module.GetType(mid)
+	if !ok {
+		return errors.ERROR_TYPE_SCHEDULER
+	}
+	var errorType errors.ErrorType
+	switch moduleType {
+	case module.TYPE_DOWNLOADER:
+		errorType = errors.ERROR_TYPE_DOWNLOADER
+	case module.TYPE_ANALYZER:
+		errorType = errors.ERROR_TYPE_ANALYZER
+	case module.TYPE_PIPELINE:
+		errorType = errors.ERROR_TYPE_PIPELINE
+	}
+	return errorType
+}
+
+// getReqErrorContext 用于生成处理给定请求时出错的上下文信息。
+func getReqErrorContext(mid module.MID, req *module.Request) errors.ErrorContext {
+	errCtx := errors.ErrorContext{MID: string(mid)}
+	if req == nil {
+		return errCtx
+	}
+	if httpReq := req.HTTPReq(); httpReq != nil && httpReq.URL != nil {
+		errCtx.URL = httpReq.URL.String()
+	}
+	errCtx.Depth = req.Depth()
+	return errCtx
+}
+
+// getRespErrorContext 用于生成处理给定响应时出错的上下文信息。
+func getRespErrorContext(mid module.MID, resp *module.Response) errors.ErrorContext {
+	errCtx := errors.ErrorContext{MID: string(mid)}
+	if resp == nil {
+		return errCtx
+	}
+	if httpResp := resp.HTTPResp(); httpResp != nil &&
+		httpResp.Request != nil && httpResp.Request.URL != nil {
+		errCtx.URL = httpResp.Request.URL.String()
+	}
+	errCtx.Depth = resp.Depth()
+	return errCtx
+}
+
+// getItemErrorContext 用于生成处理给定条目时出错的上下文信息。
+// 其中的URL代表生成该条目的页面的URL，取自条目中的元数据。
+func getItemErrorContext(mid module.MID, item module.Item) errors.ErrorContext {
+	errCtx := errors.ErrorContext{MID: string(mid)}
+	if meta, ok := item[module.ITEM_KEY_META].(module.Meta); ok {
+		errCtx.URL = meta.ParentURL
+	}
+	return errCtx
+}
+
+// recentErrorNumber 代表最多保留的最近发生的错误的数量。
+const recentErrorNumber = 20
+
+// errorRecorder 代表最近发生的错误的记录器。
+type errorRecorder struct {
+	// lock 代表保护错误列表的互斥锁。
+	lock sync.Mutex
+	// errs 代表最近发生的错误的信息的列表，按发生的先后排列。
+	errs []string
+}
+
+// newErrorRecorder 用于创建一个最近发生的错误的记录器。
+func newErrorRecorder() *errorRecorder {
+	return &errorRecorder{}
+}
+
+// record 用于记录给定的错误。
+// 若已记录的错误过多，则最早的错误会被丢弃。
+func (er *errorRecorder) record(err error) {
+	er.lock.Lock()
+	defer er.lock.Unlock()
+	if len(er.errs) >= recentErrorNumber {
+		copy(er.errs, er.errs[1:])
+		er.errs = er.errs[:len(er.errs)-1]
+	}
+	er.errs = append(er.errs, err.Error())
+}
+
+// snapshot 用于获取已记录的错误的信息的快照。
+// 若尚无任何错误，则返回nil。
+func (er *errorRecorder) snapshot() []string {
+	er.lock.Lock()
+	defer er.lock.Unlock()
+	if len(er.errs) == 0 {
+		return nil
+	}
+	errs := make([]string, len(er.errs))
+	copy(errs, er.errs)
+	return errs
+}
+
+// sendError 用于记录错误值并向错误缓冲池发送它。
+func (sched *myScheduler) sendError(err error, mid module.MID) bool {
+	return sched.sendContextError(err, errors.ErrorContext{MID: string(mid)})
+}
+
+// sendContextError 用于为错误值补充给定的上下文信息，
+// 然后记录它、发出相应的事件并向错误缓冲池发送它。
+func (sched *myScheduler) sendContextError(
+	err error, errCtx errors.ErrorContext) bool {
+	if err == nil {
+		return false
+	}
+	mid := module.MID(errCtx.MID)
+	crawlerError := errors.NewCrawlerErrorWithContext(getErrorType(mid), err, errCtx)
+	if sched.recentErrors != nil {
+		sched.recentErrors.record(crawlerError)
+	}
+	sched.emit(&ErrorEvent{At: crawlerError.Time(), MID: mid, Err: crawlerError})
+	return sendError(crawlerError, mid, sched.errorBufferPool)
+}
@@ -1,12 +1,19 @@
 package scheduler
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	werrors "gopcp.v2/chapter6/webcrawler/errors"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
 	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/module/local/pipeline"
 )
 
 func TestErrorGen(t *testing.T) {
@@ -106,3 +113,104 @@ func TestErrorSend(t *testing.T) {
 		t.Fatalf("It still can send error with closed buffer!")
 	}
 }
+
+func TestSchedErrorContext(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><a href="/a.html">a</a></body></html>`)
+	})
+	mux.HandleFunc("/a.html", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	sched := NewScheduler()
+	errCh, cancelErrors := sched.SubscribeErrors(10, werrors.ERROR_TYPE_ANALYZER)
+	defer cancelErrors()
+	downloaderErrCh, cancelDownloaderErrors :=
+		sched.SubscribeErrors(10, werrors.ERROR_TYPE_DOWNLOADER)
+	defer cancelDownloaderErrors()
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 1),
+		genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	if _, err := sched.Run(ctx, firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when running scheduler: %s", err)
+	}
+	// 状态码为404的响应会使分析器产生错误。
+	var ce werrors.CrawlerError
+	select {
+	case ce = <-errCh:
+	default:
+		t.Fatalf("No analyzer error!")
+	}
+	if ce.Type() != werrors.ERROR_TYPE_ANALYZER {
+		t.Fatalf("Inconsistent error type: expected: %q, actual: %q",
+			werrors.ERROR_TYPE_ANALYZER, ce.Type())
+	}
+	analyzerID := sched.Summary().Struct().Analyzers[0].ID
+	if ce.MID() != string(analyzerID) {
+		t.Fatalf("Inconsistent MID: expected: %q, actual: %q", analyzerID, ce.MID())
+	}
+	if expected := server.URL + "/a.html"; ce.URL() != expected {
+		t.Fatalf("Inconsistent URL: expected: %q, actual: %q", expected, ce.URL())
+	}
+	if ce.Depth() != 1 {
+		t.Fatalf("Inconsistent depth: expected: %d, actual: %d", 1, ce.Depth())
+	}
+	if ce.Time().IsZero() {
+		t.Fatalf("Zero error time!")
+	}
+	if ce.Unwrap() == nil {
+		t.Fatalf("No wrapped error!")
+	}
+	if len(downloaderErrCh) != 0 {
+		t.Fatalf("Inconsistent downloader error number: expected: %d, actual: %d",
+			0, len(downloaderErrCh))
+	}
+}
+
+func TestSchedPipelineErrorContext(t *testing.T) {
+	failItem := func(item module.Item) (module.Item, error) {
+		return nil, errors.New("failed to process item")
+	}
+	p, err := pipeline.New(module.MID("P1"), []module.ProcessItem{failItem}, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a pipeline: %s", err)
+	}
+	moduleArgs := genSimpleModuleArgs(1, 1, 0, t)
+	moduleArgs.Pipelines = []module.Pipeline{p}
+	sched := &myScheduler{}
+	errCh, cancelErrors := sched.SubscribeErrors(10, werrors.ERROR_TYPE_PIPELINE)
+	defer cancelErrors()
+	err = sched.Init(genRequestArgs([]string{"example.com"}, 1),
+		genDataArgs(10, 2, 1), moduleArgs)
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	parentURL := "http://example.com/index.html"
+	item := module.Item{
+		"url":                parentURL,
+		module.ITEM_KEY_META: module.Meta{ParentURL: parentURL},
+	}
+	sched.incrPending()
+	sched.drainer.add()
+	sched.pickOne(item)
+	var ce werrors.CrawlerError
+	select {
+	case ce = <-errCh:
+	case <-time.After(time.Second):
+		t.Fatalf("No pipeline error!")
+	}
+	if ce.MID() != "P1" {
+		t.Fatalf("Inconsistent MID: expected: %q, actual: %q", "P1", ce.MID())
+	}
+	if ce.URL() != parentURL {
+		t.Fatalf("Inconsistent URL: expected: %q, actual: %q", parentURL, ce.URL())
+	}
+}
@@ -0,0 +1,344 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// EventType 代表调度器事件的类型。
+type EventType string
+
+// 调度器事件的类型常量。
+const (
+	// EVENT_TYPE_URL_ACCEPTED 代表URL被接受的事件。
+	EVENT_TYPE_URL_ACCEPTED EventType = "url_accepted"
+	// EVENT_TYPE_URL_FILTERED 代表URL被过滤的事件。
+	EVENT_TYPE_URL_FILTERED EventType = "url_filtered"
+	// EVENT_TYPE_DOWNLOADED 代表下载完成的事件。
+	EVENT_TYPE_DOWNLOADED EventType = "downloaded"
+	// EVENT_TYPE_ITEM_EMITTED 代表分析器生成了条目的事件。
+	EVENT_TYPE_ITEM_EMITTED EventType = "item_emitted"
+	// EVENT_TYPE_ERROR 代表发生错误的事件。
+	EVENT_TYPE_ERROR EventType = "error"
+)
+
+// Event 代表调度器事件的接口类型。
+// 可以通过类型断言获得具体的事件类型，如*URLFilteredEvent。
+type Event interface {
+	// Type 用于获取事件的类型。
+	Type() EventType
+	// Time 用于获取事件发生的时间。
+	Time() time.Time
+}
+
+// URLAcceptedEvent 代表URL被接受并被放入URL边界的事件。
+type URLAcceptedEvent struct {
+	// At 代表事件发生的时间。
+	At time.Time
+	// URL 代表被接受的URL。
+	URL string
+	// Depth 代表请求的深度。
+	Depth uint32
+}
+
+func (e *URLAcceptedEvent) Type() EventType {
+	return EVENT_TYPE_URL_ACCEPTED
+}
+
+func (e *URLAcceptedEvent) Time() time.Time {
+	return e.At
+}
+
+// URLFilteredEvent 代表URL被过滤的事件。
+type URLFilteredEvent struct {
+	// At 代表事件发生的时间。
+	At time.Time
+	// URL 代表被过滤的URL。
+	URL string
+	// Depth 代表请求的深度。
+	Depth uint32
+	// Reason 代表被过滤的原因，与摘要信息中被过滤请求的计数的键一致。
+	Reason string
+	// Detail 代表被过滤的详细说明。
+	Detail string
+}
+
+func (e *URLFilteredEvent) Type() EventType {
+	return EVENT_TYPE_URL_FILTERED
+}
+
+func (e *URLFilteredEvent) Time() time.Time {
+	return e.At
+}
+
+// DownloadedEvent 代表一次下载完成的事件，无论成功与否。
+type DownloadedEvent struct {
+	// At 代表事件发生的时间。
+	At time.Time
+	// URL 代表下载的URL。
+	URL string
+	// MID 代表执行下载的下载器的ID。
+	MID module.MID
+	// Attempt 代表本次下载是第几次尝试。
+	Attempt uint32
+	// StatusCode 代表HTTP响应的状态码。若下载失败，则为0。
+	StatusCode int
+	// Latency 代表下载所用的时长。
+	Latency time.Duration
+	// Err 代表下载失败时的错误。若下载成功，则为nil。
+	Err error
+}
+
+func (e *DownloadedEvent) Type() EventType {
+	return EVENT_TYPE_DOWNLOADED
+}
+
+func (e *DownloadedEvent) Time() time.Time {
+	return e.At
+}
+
+// ItemEmittedEvent 代表分析器生成了条目的事件。
+type ItemEmittedEvent struct {
+	// At 代表事件发生的时间。
+	At time.Time
+	// URL 代表生成该条目的响应的URL。
+	URL string
+	// MID 代表生成该条目的分析器的ID。
+	MID module.MID
+	// Item 代表生成的条目。订阅者不应该修改它。
+	Item module.Item
+}
+
+func (e *ItemEmittedEvent) Type() EventType {
+	return EVENT_TYPE_ITEM_EMITTED
+}
+
+func (e *ItemEmittedEvent) Time() time.Time {
+	return e.At
+}
+
+// ErrorEvent 代表调度器或组件发生错误的事件。
+type ErrorEvent struct {
+	// At 代表事件发生的时间。
+	At time.Time
+	// MID 代表发生错误的组件的ID。若为空，则代表错误由调度器产生。
+	MID module.MID
+	// Err 代表发生的错误。它带有出错的组件、请求的URL和深度等上下文信息。
+	Err errors.CrawlerError
+}
+
+func (e *ErrorEvent) Type() EventType {
+	return EVENT_TYPE_ERROR
+}
+
+func (e *ErrorEvent) Time() time.Time {
+	return e.At
+}
+
+// Subscriber 代表事件订阅者的接口类型。
+type Subscriber interface {
+	// OnEvent 会在事件发生时被同步地调用。
+	// 它会阻塞调度器的处理流程，所以应该尽快返回。
+	OnEvent(event Event)
+}
+
+// SubscriberFunc 代表函数形式的事件订阅者。
+type SubscriberFunc func(event Event)
+
+func (f SubscriberFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// subscription 代表一个订阅。
+type subscription struct {
+	// subscriber 代表订阅者。
+	subscriber Subscriber
+	// types 代表订阅的事件类型的集合。若为nil，则代表订阅所有类型的事件。
+	types map[EventType]bool
+}
+
+// eventHub 代表事件的分发器。零值的分发器可以直接使用。
+type eventHub struct {
+	// lock 代表保护订阅字典的读写锁。
+	lock sync.RWMutex
+	// nextID 代表下一个订阅的ID。
+	nextID uint64
+	// subs 代表订阅ID与订阅的映射。
+	subs map[uint64]*subscription
+}
+
+// subscribe 用于添加订阅，并返回用于取消订阅的函数。
+func (hub *eventHub) subscribe(
+	subscriber Subscriber, types []EventType) (cancel func()) {
+	sub := &subscription{subscriber: subscriber}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	if hub.subs == nil {
+		hub.subs = map[uint64]*subscription{}
+	}
+	id := hub.nextID
+	hub.nextID++
+	hub.subs[id] = sub
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			hub.lock.Lock()
+			delete(hub.subs, id)
+			hub.lock.Unlock()
+		})
+	}
+}
+
+// publish 用于把事件同步地分发给所有订阅了该类型事件的订阅者。
+func (hub *eventHub) publish(event Event) {
+	hub.lock.RLock()
+	if len(hub.subs) == 0 {
+		hub.lock.RUnlock()
+		return
+	}
+	subscribers := make([]Subscriber, 0, len(hub.subs))
+	for _, sub := range hub.subs {
+		if sub.types == nil || sub.types[event.Type()] {
+			subscribers = append(subscribers, sub.subscriber)
+		}
+	}
+	hub.lock.RUnlock()
+	// 在锁之外调用，以允许订阅者在回调中取消订阅。
+	for _, subscriber := range subscribers {
+		subscriber.OnEvent(event)
+	}
+}
+
+// chanSubscriber 代表以带缓冲的通道接收事件的订阅者。
+type chanSubscriber struct {
+	// lock 代表保护通道状态的互斥锁。
+	lock sync.Mutex
+	// ch 代表事件通道。
+	ch chan Event
+	// closed 代表通道是否已关闭。
+	closed bool
+}
+
+// OnEvent 会把事件非阻塞地放入通道。若通道已满，则该事件会被丢弃。
+func (cs *chanSubscriber) OnEvent(event Event) {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if cs.closed {
+		return
+	}
+	select {
+	case cs.ch <- event:
+	default:
+		logger.Warnf("The event channel is full. Drop the %q event.\n", event.Type())
+	}
+}
+
+// close 用于关闭通道。
+func (cs *chanSubscriber) close() {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	if !cs.closed {
+		cs.closed = true
+		close(cs.ch)
+	}
+}
+
+func (sched *myScheduler) Subscribe(
+	subscriber Subscriber, types ...EventType) (cancel func()) {
+	if subscriber == nil {
+		panic(genParameterError("nil subscriber"))
+	}
+	return sched.events.subscribe(subscriber, types)
+}
+
+func (sched *myScheduler) SubscribeChan(
+	bufferCap uint32, types ...EventType) (<-chan Event, func()) {
+	cs := &chanSubscriber{ch: make(chan Event, bufferCap)}
+	unsubscribe := sched.events.subscribe(cs, types)
+	return cs.ch, func() {
+		unsubscribe()
+		cs.close()
+	}
+}
+
+// errorChanSubscriber 代表以带缓冲的通道接收给定类型的错误的订阅者。
+type errorChanSubscriber struct {
+	// lock 代表保护通道状态的互斥锁。
+	lock sync.Mutex
+	// ch 代表错误通道。
+	ch chan errors.CrawlerError
+	// closed 代表通道是否已关闭。
+	closed bool
+	// errTypes 代表订阅的错误类型的集合。若为nil，则代表订阅所有类型的错误。
+	errTypes map[errors.ErrorType]bool
+}
+
+// OnEvent 会把给定类型的错误非阻塞地放入通道。若通道已满，则该错误会被丢弃。
+func (ecs *errorChanSubscriber) OnEvent(event Event) {
+	e, ok := event.(*ErrorEvent)
+	if !ok || e.Err == nil {
+		return
+	}
+	if ecs.errTypes != nil && !ecs.errTypes[e.Err.Type()] {
+		return
+	}
+	ecs.lock.Lock()
+	defer ecs.lock.Unlock()
+	if ecs.closed {
+		return
+	}
+	select {
+	case ecs.ch <- e.Err:
+	default:
+		logger.Warnf("The error channel is full. Drop the error: %s\n", e.Err)
+	}
+}
+
+// close 用于关闭通道。
+func (ecs *errorChanSubscriber) close() {
+	ecs.lock.Lock()
+	defer ecs.lock.Unlock()
+	if !ecs.closed {
+		ecs.closed = true
+		close(ecs.ch)
+	}
+}
+
+func (sched *myScheduler) SubscribeErrors(
+	bufferCap uint32, errTypes ...errors.ErrorType) (<-chan errors.CrawlerError, func()) {
+	ecs := &errorChanSubscriber{ch: make(chan errors.CrawlerError, bufferCap)}
+	if len(errTypes) > 0 {
+		ecs.errTypes = make(map[errors.ErrorType]bool, len(errTypes))
+		for _, errType := range errTypes {
+			ecs.errTypes[errType] = true
+		}
+	}
+	unsubscribe := sched.events.subscribe(ecs, []EventType{EVENT_TYPE_ERROR})
+	return ecs.ch, func() {
+		unsubscribe()
+		ecs.close()
+	}
+}
+
+// emit 用于记录并分发给定的事件。
+func (sched *myScheduler) emit(event Event) {
+	logEvent(event)
+	sched.events.publish(event)
+}
+
+// logEvent 用于在日志中记录需要关注的事件。
+func logEvent(event Event) {
+	switch e := event.(type) {
+	case *URLFilteredEvent:
+		logger.Warnf("Ignore the request! %s (URL: %s)\n", e.Detail, e.URL)
+	}
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	werrors "gopcp.v2/chapter6/webcrawler/errors"
+)
+
+func TestEventHub(t *testing.T) {
+	var hub eventHub
+	// 发布时没有订阅者也不应该出错。
+	hub.publish(&ErrorEvent{At: time.Now()})
+	var all, filtered []Event
+	cancelAll := hub.subscribe(SubscriberFunc(func(e Event) {
+		all = append(all, e)
+	}), nil)
+	cancelFiltered := hub.subscribe(SubscriberFunc(func(e Event) {
+		filtered = append(filtered, e)
+	}), []EventType{EVENT_TYPE_URL_FILTERED, EVENT_TYPE_ERROR})
+	events := []Event{
+		&URLAcceptedEvent{},
+		&URLFilteredEvent{},
+		&DownloadedEvent{},
+		&ItemEmittedEvent{},
+		&ErrorEvent{},
+	}
+	for _, e := range events {
+		hub.publish(e)
+	}
+	if len(all) != len(events) {
+		t.Fatalf("Inconsistent event number: expected: %d, actual: %d",
+			len(events), len(all))
+	}
+	for i, e := range all {
+		if e != events[i] {
+			t.Fatalf("Inconsistent event: expected: %#v, actual: %#v", events[i], e)
+		}
+	}
+	if len(filtered) != 2 ||
+		filtered[0].Type() != EVENT_TYPE_URL_FILTERED ||
+		filtered[1].Type() != EVENT_TYPE_ERROR {
+		t.Fatalf("Inconsistent filtered events: %#v", filtered)
+	}
+	cancelAll()
+	cancelAll()
+	cancelFiltered()
+	hub.publish(&ErrorEvent{})
+	if len(all) != len(events) || len(filtered) != 2 {
+		t.Fatalf("Received events after canceling subscriptions!")
+	}
+}
+
+func TestSubscribeChan(t *testing.T) {
+	sched := &myScheduler{}
+	ch, cancel := sched.SubscribeChan(2, EVENT_TYPE_ERROR)
+	for i := 0; i < 3; i++ {
+		err := werrors.NewCrawlerErrorBy(werrors.ERROR_TYPE_SCHEDULER, fmt.Errorf("error %d", i))
+		sched.emit(&ErrorEvent{Err: err})
+	}
+	sched.emit(&URLAcceptedEvent{})
+	cancel()
+	cancel()
+	sched.emit(&ErrorEvent{})
+	var received []Event
+	for e := range ch {
+		received = append(received, e)
+	}
+	// 通道已满时的事件会被丢弃。
+	if len(received) != 2 {
+		t.Fatalf("Inconsistent event number: expected: %d, actual: %d", 2, len(received))
+	}
+	for i, e := range received {
+		expected := fmt.Sprintf("error %d", i)
+		if err := e.(*ErrorEvent).Err.Unwrap(); err.Error() != expected {
+			t.Fatalf("Inconsistent event error: expected: %q, actual: %q", expected, err)
+		}
+	}
+}
+
+func TestSchedEvents(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><a href="/a.html">a</a>`+
+			`<a href="/a.html">a</a><a href="ftp://example.com/">ftp</a></body></html>`)
+	})
+	mux.HandleFunc("/a.html", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	sched := NewScheduler()
+	var lock sync.Mutex
+	eventMap := map[EventType][]Event{}
+	sched.Subscribe(SubscriberFunc(func(e Event) {
+		lock.Lock()
+		eventMap[e.Type()] = append(eventMap[e.Type()], e)
+		lock.Unlock()
+	}))
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 1),
+		genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	if _, err := sched.Run(ctx, firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when running scheduler: %s", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	expectedNumbers := map[EventType]int{
+		EVENT_TYPE_URL_ACCEPTED: 2,
+		EVENT_TYPE_URL_FILTERED: 2,
+		EVENT_TYPE_DOWNLOADED:   2,
+		EVENT_TYPE_ITEM_EMITTED: 3,
+	}
+	for eventType, expected := range expectedNumbers {
+		if n := len(eventMap[eventType]); n != expected {
+			t.Fatalf("Inconsistent %q event number: expected: %d, actual: %d",
+				eventType, expected, n)
+		}
+	}
+	reasonMap := map[string]bool{}
+	for _, e := range eventMap[EVENT_TYPE_URL_FILTERED] {
+		reasonMap[e.(*URLFilteredEvent).Reason] = true
+	}
+	if !reasonMap[rejectReasonDuplicate] || !reasonMap[rejectReasonScheme] {
+		t.Fatalf("Inconsistent filtered reasons: %v", reasonMap)
+	}
+	statusCodeMap := map[string]int{}
+	for _, e := range eventMap[EVENT_TYPE_DOWNLOADED] {
+		de := e.(*DownloadedEvent)
+		if de.Err != nil || de.Latency <= 0 || de.MID == "" {
+			t.Fatalf("Inconsistent download event: %#v", de)
+		}
+		statusCodeMap[de.URL] = de.StatusCode
+	}
+	if code := statusCodeMap[server.URL+"/a.html"]; code != http.StatusNotFound {
+		t.Fatalf("Inconsistent status code: expected: %d, actual: %d",
+			http.StatusNotFound, code)
+	}
+	// 状态码为404的响应会使分析器产生错误。
+	if len(eventMap[EVENT_TYPE_ERROR]) == 0 {
+		t.Fatalf("No error event!")
+	}
+}
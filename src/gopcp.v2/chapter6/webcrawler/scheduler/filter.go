@@ -0,0 +1,278 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 被过滤的请求的原因。
+const (
+	// rejectReasonScheme 代表URL的scheme不被支持。
+	rejectReasonScheme = "scheme"
+	// rejectReasonDuplicate 代表URL重复。
+	rejectReasonDuplicate = "duplicate"
+	// rejectReasonDomain 代表URL不在可接受的主域名或种子的爬取范围之内。
+	rejectReasonDomain = "domain"
+	// rejectReasonDepth 代表请求的深度超过了最大深度。
+	rejectReasonDepth = "depth"
+	// rejectReasonRobots 代表被robots.txt禁止。
+	rejectReasonRobots = "robots"
+	// rejectReasonInclude 代表URL未匹配任何包含模式。
+	rejectReasonInclude = "include"
+	// rejectReasonExclude 代表URL匹配了排除模式。
+	rejectReasonExclude = "exclude"
+	// rejectReasonQueryParams 代表URL的查询参数过多。
+	rejectReasonQueryParams = "query_params"
+	// rejectReasonExtension 代表URL的文件扩展名被禁止。
+	rejectReasonExtension = "extension"
+	// rejectReasonHostLimit 代表URL所属主机的URL数量已达到上限。
+	rejectReasonHostLimit = "host_limit"
+)
+
+// regexpPatternPrefix 代表正则表达式模式的前缀。
+// 不带此前缀的模式都会被视为通配符模式。
+const regexpPatternPrefix = "re:"
+
+// compilePattern 用于编译给定的URL模式。
+// 以“re:”开头的模式会被视为正则表达式，且不会被自动锚定。
+// 其他的模式会被视为通配符模式，需要匹配整个路径和查询部分。
+// 通配符模式中的“*”可匹配任意数量的任意字符（包括“/”），“?”可匹配单个任意字符。
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var expr string
+	if strings.HasPrefix(pattern, regexpPatternPrefix) {
+		expr = strings.TrimPrefix(pattern, regexpPatternPrefix)
+	} else {
+		var buf strings.Builder
+		buf.WriteString("^")
+		for _, r := range pattern {
+			switch r {
+			case '*':
+				buf.WriteString(".*")
+			case '?':
+				buf.WriteString(".")
+			default:
+				buf.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		buf.WriteString("$")
+		expr = buf.String()
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		errMsg := fmt.Sprintf("illegal URL pattern %q: %s", pattern, err)
+		return nil, genParameterError(errMsg)
+	}
+	return re, nil
+}
+
+// urlFilter 代表URL过滤器的接口类型。
+type urlFilter interface {
+	// reason 用于获取该过滤器拒绝请求时的原因。
+	reason() string
+	// accept 用于判断给定的URL是否可以被接受。
+	accept(u *url.URL) bool
+}
+
+// requestURI 用于获取给定URL的路径和查询部分。模式会与它进行匹配。
+func requestURI(u *url.URL) string {
+	uri := u.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	if u.RawQuery != "" {
+		uri += "?" + u.RawQuery
+	}
+	return uri
+}
+
+// includeFilter 代表包含模式的过滤器。
+// 只有匹配了至少一个模式的URL才会被接受。
+type includeFilter []*regexp.Regexp
+
+func (f includeFilter) reason() string {
+	return rejectReasonInclude
+}
+
+func (f includeFilter) accept(u *url.URL) bool {
+	uri := requestURI(u)
+	for _, re := range f {
+		if re.MatchString(uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeFilter 代表排除模式的过滤器。
+// 匹配了任一模式的URL都不会被接受。
+type excludeFilter []*regexp.Regexp
+
+func (f excludeFilter) reason() string {
+	return rejectReasonExclude
+}
+
+func (f excludeFilter) accept(u *url.URL) bool {
+	uri := requestURI(u)
+	for _, re := range f {
+		if re.MatchString(uri) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryParamFilter 代表限制查询参数数量的过滤器。
+type queryParamFilter uint32
+
+func (f queryParamFilter) reason() string {
+	return rejectReasonQueryParams
+}
+
+func (f queryParamFilter) accept(u *url.URL) bool {
+	if u.RawQuery == "" {
+		return true
+	}
+	var number uint32
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair != "" {
+			number++
+		}
+	}
+	return number <= uint32(f)
+}
+
+// extensionFilter 代表禁止某些文件扩展名的过滤器。
+type extensionFilter map[string]struct{}
+
+func (f extensionFilter) reason() string {
+	return rejectReasonExtension
+}
+
+func (f extensionFilter) accept(u *url.URL) bool {
+	ext := strings.ToLower(path.Ext(u.Path))
+	if ext == "" {
+		return true
+	}
+	_, ok := f[ext]
+	return !ok
+}
+
+// normalizeExtension 用于把给定的文件扩展名转为小写并保证以“.”开头。
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// filterChain 代表由多个URL过滤器组成的过滤链。
+type filterChain []urlFilter
+
+// newFilterChain 用于根据请求相关的参数创建过滤链。
+// 若参数中的模式不合法，则返回非nil的错误值。
+func newFilterChain(requestArgs RequestArgs) (filterChain, error) {
+	var chain filterChain
+	if len(requestArgs.ExcludePatterns) > 0 {
+		var f excludeFilter
+		for _, pattern := range requestArgs.ExcludePatterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				return nil, err
+			}
+			f = append(f, re)
+		}
+		chain = append(chain, f)
+	}
+	if len(requestArgs.IncludePatterns) > 0 {
+		var f includeFilter
+		for _, pattern := range requestArgs.IncludePatterns {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				return nil, err
+			}
+			f = append(f, re)
+		}
+		chain = append(chain, f)
+	}
+	if requestArgs.MaxQueryParams > 0 {
+		chain = append(chain, queryParamFilter(requestArgs.MaxQueryParams))
+	}
+	if len(requestArgs.BlockedExtensions) > 0 {
+		f := extensionFilter{}
+		for _, ext := range requestArgs.BlockedExtensions {
+			if ext = normalizeExtension(ext); ext != "" {
+				f[ext] = struct{}{}
+			}
+		}
+		chain = append(chain, f)
+	}
+	return chain, nil
+}
+
+// check 用于依次使用过滤链中的过滤器检查给定的URL。
+// 若URL被某个过滤器拒绝，则返回该过滤器的原因和false。
+func (chain filterChain) check(u *url.URL) (reason string, ok bool) {
+	for _, f := range chain {
+		if !f.accept(u) {
+			return f.reason(), false
+		}
+	}
+	return "", true
+}
+
+// hostLimiter 代表限制每个主机的URL数量的限制器。
+type hostLimiter struct {
+	// max 代表每个主机的URL的最大数量。
+	max uint64
+	// lock 代表保护计数的互斥锁。
+	lock sync.Mutex
+	// counts 代表主机与其已接受的URL的数量的映射。
+	counts map[string]uint64
+}
+
+// newHostLimiter 用于创建一个主机URL数量的限制器。
+// 若参数max为0，则返回nil。
+func newHostLimiter(max uint64) *hostLimiter {
+	if max == 0 {
+		return nil
+	}
+	return &hostLimiter{max: max, counts: map[string]uint64{}}
+}
+
+// take 用于为给定的主机占用一个URL的名额。
+// 若该主机的名额已用尽，则返回false。
+func (hl *hostLimiter) take(host string) bool {
+	host = strings.ToLower(host)
+	hl.lock.Lock()
+	defer hl.lock.Unlock()
+	if hl.counts[host] >= hl.max {
+		return false
+	}
+	hl.counts[host]++
+	return true
+}
+
+// dump 用于获取各个主机已接受的URL的数量的副本。
+func (hl *hostLimiter) dump() map[string]uint64 {
+	hl.lock.Lock()
+	defer hl.lock.Unlock()
+	counts := make(map[string]uint64, len(hl.counts))
+	for host, count := range hl.counts {
+		counts[host] = count
+	}
+	return counts
+}
+
+// load 用于载入由dump方法获取的各个主机已接受的URL的数量。
+func (hl *hostLimiter) load(counts map[string]uint64) {
+	hl.lock.Lock()
+	defer hl.lock.Unlock()
+	for host, count := range counts {
+		hl.counts[strings.ToLower(host)] = count
+	}
+}
@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestCompilePattern(t *testing.T) {
+	patternMap := map[string]map[string]bool{
+		"/docs/*": {
+			"/docs/":            true,
+			"/docs/a/b.html":    true,
+			"/docs":             false,
+			"/blog/docs/a.html": false,
+		},
+		"*/logout*": {
+			"/logout":             true,
+			"/user/logout?next=/": true,
+			"/user/login":         false,
+		},
+		"/page?.html": {
+			"/page1.html":  true,
+			"/page12.html": false,
+		},
+		"re:[?&]page=\\d+": {
+			"/list?page=2":         true,
+			"/list?sort=1&page=30": true,
+			"/list?page=last":      false,
+		},
+	}
+	for pattern, uriMap := range patternMap {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			t.Fatalf("An error occurs when compiling pattern: %s (pattern: %q)",
+				err, pattern)
+		}
+		for uri, expected := range uriMap {
+			if actual := re.MatchString(uri); actual != expected {
+				t.Fatalf("Inconsistent matching result: expected: %v, actual: %v (pattern: %q, URI: %q)",
+					expected, actual, pattern, uri)
+			}
+		}
+	}
+	if _, err := compilePattern("re:("); err == nil {
+		t.Fatalf("No error when compiling illegal pattern %q!", "re:(")
+	}
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.ExcludePatterns = []string{"re:["}
+	if err := requestArgs.Check(); err == nil {
+		t.Fatalf("No error when checking illegal request arguments: %#v", requestArgs)
+	}
+}
+
+func TestFilterChain(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.IncludePatterns = []string{"/docs/*", "/search?*"}
+	requestArgs.ExcludePatterns = []string{"*/calendar/*"}
+	requestArgs.MaxQueryParams = 2
+	requestArgs.BlockedExtensions = []string{".PDF", "zip"}
+	chain, err := newFilterChain(requestArgs)
+	if err != nil {
+		t.Fatalf("An error occurs when creating filter chain: %s", err)
+	}
+	urlMap := map[string]string{
+		"http://example.com/docs/a.html":             "",
+		"http://example.com/search?q=golang&page=2":  "",
+		"http://example.com/blog/a.html":             rejectReasonInclude,
+		"http://example.com/docs/calendar/2017.html": rejectReasonExclude,
+		"http://example.com/search?q=golang&p=2&s=1": rejectReasonQueryParams,
+		"http://example.com/docs/book.pdf":           rejectReasonExtension,
+		"http://example.com/docs/Code.ZIP":           rejectReasonExtension,
+	}
+	for u, expected := range urlMap {
+		reqURL, _ := url.Parse(u)
+		reason, ok := chain.check(reqURL)
+		if ok != (expected == "") || reason != expected {
+			t.Fatalf("Inconsistent filtering result: expected: %q, actual: %q (URL: %s)",
+				expected, reason, u)
+		}
+	}
+	if chain, _ := newFilterChain(genRequestArgs([]string{}, 0)); len(chain) != 0 {
+		t.Fatalf("Inconsistent filter number: expected: %d, actual: %d", 0, len(chain))
+	}
+}
+
+func TestSchedFilter(t *testing.T) {
+	requestArgs := genRequestArgs([]string{"example.com"}, 1)
+	requestArgs.ExcludePatterns = []string{"*logout*"}
+	requestArgs.BlockedExtensions = []string{"jpg"}
+	requestArgs.MaxURLsPerHost = 2
+	sched := &myScheduler{}
+	err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	urlMap := map[string]bool{
+		"ftp://example.com/a.html":      false,
+		"http://example.com/a.html":     true,
+		"http://example.com/a.html#top": false,
+		"http://example.org/a.html":     false,
+		"http://example.com/logout":     false,
+		"http://example.com/a.jpg":      false,
+		"http://example.com/b.html":     true,
+		"http://example.com/c.html":     false,
+		"http://www.example.com/a.html": true,
+	}
+	// 按顺序发送，以保证每个主机的URL数量的限制是确定的。
+	urls := []string{
+		"ftp://example.com/a.html",
+		"http://example.com/a.html",
+		"http://example.com/a.html#top",
+		"http://example.org/a.html",
+		"http://example.com/logout",
+		"http://example.com/a.jpg",
+		"http://example.com/b.html",
+		"http://example.com/c.html",
+		"http://www.example.com/a.html",
+	}
+	for _, u := range urls {
+		httpReq, _ := http.NewRequest("GET", u, nil)
+		if actual := sched.sendReq(module.NewRequest(httpReq, 0)); actual != urlMap[u] {
+			t.Fatalf("Inconsistent sending result: expected: %v, actual: %v (URL: %s)",
+				urlMap[u], actual, u)
+		}
+	}
+	httpReq, _ := http.NewRequest("GET", "http://www.example.com/b.html", nil)
+	if sched.sendReq(module.NewRequest(httpReq, 2)) {
+		t.Fatalf("Inconsistent sending result: expected: %v, actual: %v (URL: %s)",
+			false, true, httpReq.URL)
+	}
+	expectedRejected := map[string]uint64{
+		rejectReasonScheme:    1,
+		rejectReasonDuplicate: 1,
+		rejectReasonDomain:    1,
+		rejectReasonExclude:   1,
+		rejectReasonExtension: 1,
+		rejectReasonHostLimit: 1,
+		rejectReasonDepth:     1,
+	}
+	rejected := sched.Summary().Struct().Rejected
+	if len(rejected) != len(expectedRejected) {
+		t.Fatalf("Inconsistent rejected counts: expected: %v, actual: %v",
+			expectedRejected, rejected)
+	}
+	for reason, count := range expectedRejected {
+		if rejected[reason] != count {
+			t.Fatalf("Inconsistent rejected count: expected: %d, actual: %d (reason: %s)",
+				count, rejected[reason], reason)
+		}
+	}
+	// 每个主机已接受的URL的数量应该在恢复后保持不变。
+	var buf bytes.Buffer
+	if err := sched.Checkpoint(&buf); err != nil {
+		t.Fatalf("An error occurs when writing checkpoint: %s", err)
+	}
+	another := &myScheduler{}
+	err = another.Init(genRequestArgs([]string{}, 0), genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := another.Restore(&buf); err != nil {
+		t.Fatalf("An error occurs when restoring scheduler: %s", err)
+	}
+	urlMap = map[string]bool{
+		"http://example.com/d.html":     false,
+		"http://www.example.com/b.html": true,
+		"http://www.example.com/c.html": false,
+	}
+	urls = []string{
+		"http://example.com/d.html",
+		"http://www.example.com/b.html",
+		"http://www.example.com/c.html",
+	}
+	for _, u := range urls {
+		httpReq, _ := http.NewRequest("GET", u, nil)
+		if actual := another.sendReq(module.NewRequest(httpReq, 0)); actual != urlMap[u] {
+			t.Fatalf("Inconsistent sending result after restoring: expected: %v, actual: %v (URL: %s)",
+				urlMap[u], actual, u)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// ErrClosedFrontier 是表示URL边界已关闭的错误的变量。
+var ErrClosedFrontier = errors.New("closed frontier")
+
+// FrontierType 代表URL边界的类型。
+type FrontierType string
+
+const (
+	// FRONTIER_TYPE_BFS 代表广度优先的URL边界。
+	// 深度较小的请求会先被取出，深度相同的请求按放入的顺序取出。
+	FRONTIER_TYPE_BFS FrontierType = "bfs"
+	// FRONTIER_TYPE_DFS 代表深度优先的URL边界。
+	// 深度较大的请求会先被取出，深度相同的请求按放入的逆序取出。
+	FRONTIER_TYPE_DFS FrontierType = "dfs"
+	// FRONTIER_TYPE_PRIORITY 代表按优先级排序的URL边界。
+	// 优先级较高的请求会先被取出，优先级相同的请求按放入的顺序取出。
+	FRONTIER_TYPE_PRIORITY FrontierType = "priority"
+)
+
+// legalFrontierTypeMap 代表合法的URL边界类型的字典。
+var legalFrontierTypeMap = map[FrontierType]lessFunc{
+	FRONTIER_TYPE_BFS:      lessBFS,
+	FRONTIER_TYPE_DFS:      lessDFS,
+	FRONTIER_TYPE_PRIORITY: lessPriority,
+}
+
+// LegalFrontierType 用于判断给定的URL边界类型是否合法。
+// 空字符串会被视为广度优先类型。
+func LegalFrontierType(frontierType FrontierType) bool {
+	if frontierType == "" {
+		return true
+	}
+	_, ok := legalFrontierTypeMap[frontierType]
+	return ok
+}
+
+// Frontier 代表URL边界的接口类型。
+// URL边界用于存放待下载的请求，并决定它们被取出的顺序。
+type Frontier interface {
+	// Type 用于获取URL边界的类型。
+	Type() FrontierType
+	// Push 用于放入请求。请求的深度和优先级会影响其被取出的顺序。
+	// 注意！本方法应该是阻塞的。
+	// 若URL边界已满，则会等待直到有空位为止。
+	// 若URL边界已关闭则会直接返回非nil的错误值。
+	Push(req *module.Request) error
+	// Pop 用于取出请求。
+	// 注意！本方法应该是阻塞的。
+	// 若URL边界已空，则会等待直到有请求为止。
+	// 若URL边界已关闭则会直接返回非nil的错误值。
+	Pop() (*module.Request, error)
+	// Len 用于获取URL边界中请求的数量。
+	Len() uint64
+	// Cap 用于获取URL边界的容量。
+	Cap() uint64
+	// Close 用于关闭URL边界。
+	// 若URL边界之前已关闭则返回false，否则返回true。
+	Close() bool
+	// Closed 用于判断URL边界是否已关闭。
+	Closed() bool
+}
+
+// NewFrontier 用于创建一个URL边界。
+// 参数frontierType代表URL边界的类型。若为空则使用广度优先类型。
+// 参数capacity代表URL边界的容量。
+func NewFrontier(frontierType FrontierType, capacity uint64) (Frontier, error) {
+	if frontierType == "" {
+		frontierType = FRONTIER_TYPE_BFS
+	}
+	less, ok := legalFrontierTypeMap[frontierType]
+	if !ok {
+		errMsg := fmt.Sprintf("illegal frontier type: %q", frontierType)
+		return nil, genParameterError(errMsg)
+	}
+	if capacity == 0 {
+		return nil, genParameterError("zero frontier capacity")
+	}
+	frontier := &myFrontier{
+		frontierType: frontierType,
+		capacity:     capacity,
+		entries:      &frontierHeap{less: less},
+	}
+	frontier.notEmpty = sync.NewCond(&frontier.lock)
+	frontier.notFull = sync.NewCond(&frontier.lock)
+	return frontier, nil
+}
+
+// myFrontier 代表URL边界的实现类型。
+type myFrontier struct {
+	// frontierType 代表URL边界的类型。
+	frontierType FrontierType
+	// capacity 代表URL边界的容量。
+	capacity uint64
+	// entries 代表存放请求的堆。
+	entries *frontierHeap
+	// seq 代表下一个放入的请求的序号。
+	seq uint64
+	// closed 代表URL边界是否已关闭。
+	closed bool
+	// lock 代表保护内部共享资源的互斥锁。
+	lock sync.Mutex
+	// notEmpty 代表URL边界非空的条件变量。
+	notEmpty *sync.Cond
+	// notFull 代表URL边界未满的条件变量。
+	notFull *sync.Cond
+}
+
+func (frontier *myFrontier) Type() FrontierType {
+	return frontier.frontierType
+}
+
+func (frontier *myFrontier) Push(req *module.Request) error {
+	if req == nil {
+		return genParameterError("nil request")
+	}
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	for !frontier.closed && uint64(frontier.entries.Len()) >= frontier.capacity {
+		frontier.notFull.Wait()
+	}
+	if frontier.closed {
+		return ErrClosedFrontier
+	}
+	heap.Push(frontier.entries, &frontierEntry{req: req, seq: frontier.seq})
+	frontier.seq++
+	frontier.notEmpty.Signal()
+	return nil
+}
+
+func (frontier *myFrontier) Pop() (*module.Request, error) {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	for !frontier.closed && frontier.entries.Len() == 0 {
+		frontier.notEmpty.Wait()
+	}
+	if frontier.closed {
+		return nil, ErrClosedFrontier
+	}
+	entry := heap.Pop(frontier.entries).(*frontierEntry)
+	frontier.notFull.Signal()
+	return entry.req, nil
+}
+
+func (frontier *myFrontier) Len() uint64 {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	return uint64(frontier.entries.Len())
+}
+
+func (frontier *myFrontier) Cap() uint64 {
+	return frontier.capacity
+}
+
+func (frontier *myFrontier) Close() bool {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	if frontier.closed {
+		return false
+	}
+	frontier.closed = true
+	frontier.notEmpty.Broadcast()
+	frontier.notFull.Broadcast()
+	return true
+}
+
+func (frontier *myFrontier) Closed() bool {
+	frontier.lock.Lock()
+	defer frontier.lock.Unlock()
+	return frontier.closed
+}
+
+// frontierEntry 代表URL边界中的条目。
+type frontierEntry struct {
+	// req 代表请求。
+	req *module.Request
+	// seq 代表请求被放入的序号。
+	seq uint64
+}
+
+// lessFunc 代表判断条目取出顺序的函数类型。
+// 若条目a应先于条目b被取出，则返回true。
+type lessFunc func(a, b *frontierEntry) bool
+
+// lessBFS 代表广度优先的顺序。
+func lessBFS(a, b *frontierEntry) bool {
+	if a.req.Depth() != b.req.Depth() {
+		return a.req.Depth() < b.req.Depth()
+	}
+	return a.seq < b.seq
+}
+
+// lessDFS 代表深度优先的顺序。
+func lessDFS(a, b *frontierEntry) bool {
+	if a.req.Depth() != b.req.Depth() {
+		return a.req.Depth() > b.req.Depth()
+	}
+	return a.seq > b.seq
+}
+
+// lessPriority 代表按优先级排序的顺序。
+func lessPriority(a, b *frontierEntry) bool {
+	if a.req.Priority() != b.req.Priority() {
+		return a.req.Priority() > b.req.Priority()
+	}
+	return a.seq < b.seq
+}
+
+// frontierHeap 代表存放条目的堆。它实现了heap.Interface接口。
+type frontierHeap struct {
+	// items 代表条目的列表。
+	items []*frontierEntry
+	// less 代表判断条目取出顺序的函数。
+	less lessFunc
+}
+
+func (h *frontierHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *frontierHeap) Less(i, j int) bool {
+	return h.less(h.items[i], h.items[j])
+}
+
+func (h *frontierHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *frontierHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*frontierEntry))
+}
+
+func (h *frontierHeap) Pop() interface{} {
+	n := len(h.items)
+	entry := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	return entry
+}
+
+// FrontierSummaryStruct 代表URL边界的摘要类型。
+type FrontierSummaryStruct struct {
+	Type string `json:"type"`
+	Cap  uint64 `json:"cap"`
+	Len  uint64 `json:"len"`
+}
+
+// getFrontierSummary 用于生成和返回URL边界的摘要信息。
+func getFrontierSummary(frontier Frontier) FrontierSummaryStruct {
+	return FrontierSummaryStruct{
+		Type: string(frontier.Type()),
+		Cap:  frontier.Cap(),
+		Len:  frontier.Len(),
+	}
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestFrontierNew(t *testing.T) {
+	frontier, err := NewFrontier("", 10)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a frontier: %s", err)
+	}
+	if frontier.Type() != FRONTIER_TYPE_BFS {
+		t.Fatalf("Inconsistent frontier type: expected: %s, actual: %s",
+			FRONTIER_TYPE_BFS, frontier.Type())
+	}
+	if frontier.Cap() != 10 {
+		t.Fatalf("Inconsistent frontier cap: expected: %d, actual: %d",
+			10, frontier.Cap())
+	}
+	if _, err := NewFrontier("unknown", 10); err == nil {
+		t.Fatal("No error when create a frontier with illegal type!")
+	}
+	if _, err := NewFrontier(FRONTIER_TYPE_DFS, 0); err == nil {
+		t.Fatal("No error when create a frontier with zero capacity!")
+	}
+	if err := frontier.Push(nil); err == nil {
+		t.Fatal("No error when push nil request!")
+	}
+}
+
+func TestFrontierOrder(t *testing.T) {
+	// 每个元素依次代表深度和优先级。
+	reqParams := [][2]int{{0, 0}, {2, 1}, {1, 3}, {2, 3}, {1, 0}}
+	expectedOrderMap := map[FrontierType][]int{
+		FRONTIER_TYPE_BFS:      {0, 2, 4, 1, 3},
+		FRONTIER_TYPE_DFS:      {3, 1, 4, 2, 0},
+		FRONTIER_TYPE_PRIORITY: {2, 3, 1, 0, 4},
+	}
+	for frontierType, expectedOrder := range expectedOrderMap {
+		frontier, _ := NewFrontier(frontierType, uint64(len(reqParams)))
+		for i, params := range reqParams {
+			req := genFrontierRequest(i, uint32(params[0]), params[1], t)
+			if err := frontier.Push(req); err != nil {
+				t.Fatalf("An error occurs when pushing request: %s (type: %s)",
+					err, frontierType)
+			}
+		}
+		if frontier.Len() != uint64(len(reqParams)) {
+			t.Fatalf("Inconsistent frontier length: expected: %d, actual: %d (type: %s)",
+				len(reqParams), frontier.Len(), frontierType)
+		}
+		for _, index := range expectedOrder {
+			req, err := frontier.Pop()
+			if err != nil {
+				t.Fatalf("An error occurs when popping request: %s (type: %s)",
+					err, frontierType)
+			}
+			expectedURL := fmt.Sprintf("http://cn.bing.com/search?p=%d", index)
+			if url := req.HTTPReq().URL.String(); url != expectedURL {
+				t.Fatalf("Inconsistent popped request: expected: %s, actual: %s (type: %s)",
+					expectedURL, url, frontierType)
+			}
+		}
+		if frontier.Len() != 0 {
+			t.Fatalf("Inconsistent frontier length: expected: %d, actual: %d (type: %s)",
+				0, frontier.Len(), frontierType)
+		}
+	}
+}
+
+func TestFrontierBlockAndClose(t *testing.T) {
+	frontier, _ := NewFrontier(FRONTIER_TYPE_BFS, 1)
+	frontier.Push(genFrontierRequest(0, 0, 0, t))
+	// 测试URL边界已满时的阻塞。
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- frontier.Push(genFrontierRequest(1, 0, 0, t))
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("It still can push request to full frontier!")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if _, err := frontier.Pop(); err != nil {
+		t.Fatalf("An error occurs when popping request: %s", err)
+	}
+	if err := <-pushed; err != nil {
+		t.Fatalf("An error occurs when pushing request: %s", err)
+	}
+	frontier.Pop()
+	// 测试URL边界为空时的阻塞以及关闭。
+	popped := make(chan error, 1)
+	go func() {
+		_, err := frontier.Pop()
+		popped <- err
+	}()
+	select {
+	case <-popped:
+		t.Fatal("It still can pop request from empty frontier!")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if !frontier.Close() {
+		t.Fatal("Couldn't close frontier!")
+	}
+	if frontier.Close() {
+		t.Fatal("It still can close closed frontier!")
+	}
+	if !frontier.Closed() {
+		t.Fatal("Inconsistent closed state: expected: true, actual: false")
+	}
+	if err := <-popped; err != ErrClosedFrontier {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v",
+			ErrClosedFrontier, err)
+	}
+	if err := frontier.Push(genFrontierRequest(2, 0, 0, t)); err != ErrClosedFrontier {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v",
+			ErrClosedFrontier, err)
+	}
+}
+
+// genFrontierRequest 用于生成测试URL边界用的请求。
+func genFrontierRequest(index int, depth uint32, priority int, t *testing.T) *module.Request {
+	url := fmt.Sprintf("http://cn.bing.com/search?p=%d", index)
+	httpReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+			err, url)
+	}
+	return module.NewPriorityRequest(httpReq, depth, priority)
+}
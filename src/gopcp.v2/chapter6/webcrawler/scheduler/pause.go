@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate 代表暂停闸门。
+// 闸门关闭时，调度器的各个处理流程都会在取出数据之前等待，直到闸门被打开。
+// 零值的闸门是打开的。
+type pauseGate struct {
+	// lock 代表保护通道的互斥锁。
+	lock sync.Mutex
+	// ch 代表闸门打开时会被关闭的通道。若为nil则代表闸门是打开的。
+	ch chan struct{}
+}
+
+// close 用于关闭闸门。
+func (gate *pauseGate) close() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.ch == nil {
+		gate.ch = make(chan struct{})
+	}
+}
+
+// open 用于打开闸门，并唤醒所有正在等待的流程。
+func (gate *pauseGate) open() {
+	gate.lock.Lock()
+	defer gate.lock.Unlock()
+	if gate.ch != nil {
+		close(gate.ch)
+		gate.ch = nil
+	}
+}
+
+// wait 用于等待闸门打开。
+// 若在闸门打开之前ctx已被取消，则返回false。
+func (gate *pauseGate) wait(ctx context.Context) bool {
+	gate.lock.Lock()
+	ch := gate.ch
+	gate.lock.Unlock()
+	if ch == nil {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (sched *myScheduler) Pause() (err error) {
+	logger.Info("Pause scheduler...")
+	// 检查状态。
+	logger.Info("Check status for pause...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_PAUSING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_PAUSED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	sched.gate.close()
+	logger.Info("Scheduler has been paused.")
+	return nil
+}
+
+func (sched *myScheduler) Resume() (err error) {
+	logger.Info("Resume scheduler...")
+	// 检查状态。
+	logger.Info("Check status for resume...")
+	var oldStatus Status
+	oldStatus, err =
+		sched.checkAndSetStatus(SCHED_STATUS_RESUMING)
+	defer func() {
+		sched.statusLock.Lock()
+		if err != nil {
+			sched.status = oldStatus
+		} else {
+			sched.status = SCHED_STATUS_STARTED
+		}
+		sched.statusLock.Unlock()
+	}()
+	if err != nil {
+		return
+	}
+	sched.gate.open()
+	logger.Info("Scheduler has been resumed.")
+	return nil
+}
+
+// waitIfPaused 用于在调度器已暂停时等待其恢复。
+// 若在恢复之前调度器已被停止，则返回false。
+func (sched *myScheduler) waitIfPaused() bool {
+	return sched.gate.wait(sched.ctx)
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedPause(t *testing.T) {
+	var hits int64
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&hits, 1)
+			fmt.Fprint(w, "<html><body></body></html>")
+		}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	sched := &myScheduler{}
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 0),
+		genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	if err := sched.Pause(); err == nil {
+		t.Fatalf("No error when pausing a scheduler which has not been started!")
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/a.html", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&hits) == 1 && sched.pending() == 0
+	})
+	if err := sched.Resume(); err == nil {
+		t.Fatalf("No error when resuming a scheduler which has not been paused!")
+	}
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_PAUSED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_PAUSED), GetStatusDescription(status))
+	}
+	if err := sched.Pause(); err == nil {
+		t.Fatalf("No error when pausing a scheduler which has been paused!")
+	}
+	var seeds []Seed
+	for _, page := range []string{"/b.html", "/c.html"} {
+		httpReq, _ := http.NewRequest("GET", server.URL+page, nil)
+		seeds = append(seeds, NewSeed(httpReq, SEED_SCOPE_DOMAIN))
+	}
+	if err := sched.AddSeeds(seeds...); err != nil {
+		t.Fatalf("An error occurs when adding seeds: %s", err)
+	}
+	// 暂停期间，请求不会被下载。
+	// 已阻塞在取出操作上的下载流程最多会再取出一个请求，但会在下载之前等待。
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt64(&hits); n != 1 {
+		t.Fatalf("Inconsistent hit number: expected: %d, actual: %d", 1, n)
+	}
+	if n := sched.frontier.Len(); n < 1 {
+		t.Fatalf("Inconsistent frontier length: expected: >= %d, actual: %d", 1, n)
+	}
+	if err := sched.Resume(); err != nil {
+		t.Fatalf("An error occurs when resuming scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STARTED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STARTED), GetStatusDescription(status))
+	}
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&hits) == 3 && sched.pending() == 0
+	})
+	// 已暂停的调度器可以被停止。
+	if err := sched.Pause(); err != nil {
+		t.Fatalf("An error occurs when pausing scheduler: %s", err)
+	}
+	if err := sched.Stop(); err != nil {
+		t.Fatalf("An error occurs when stopping scheduler: %s", err)
+	}
+}
+
+// waitFor 用于等待给定的条件被满足。若超时则使测试失败。
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout when waiting for condition!")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// politeness 代表礼貌爬取的控制器。
+// 它会按主机对请求进行排队，并在把请求交给下载器之前
+// 保证针对每个主域名的请求频率和针对每个主机的并发请求数不超过限制。
+type politeness struct {
+	// ctx 代表上下文，用于感知调度器的停止。
+	ctx context.Context
+	// interval 代表针对同一主域名的相邻两次请求之间的最小间隔时间。
+	interval time.Duration
+	// maxInFlight 代表针对每个主机同时进行中的请求的最大数量。0代表不限制。
+	maxInFlight uint32
+	// handle 代表真正处理请求的函数。
+	handle func(req *module.Request)
+	// lock 代表保护内部共享资源的互斥锁。
+	lock sync.Mutex
+	// hosts 代表主机与其请求队列的映射。
+	hosts map[string]*hostQueue
+	// domains 代表主域名与其限速状态的映射。
+	domains map[string]*domainLimiter
+}
+
+// hostQueue 代表针对单个主机的请求队列。
+type hostQueue struct {
+	// host 代表主机。
+	host string
+	// domain 代表主机所属的主域名。
+	domain string
+	// queue 代表等待中的请求。
+	queue []*module.Request
+	// slots 代表进行中请求的信号量。若为nil则代表不限制。
+	slots chan struct{}
+	// inFlight 代表进行中的请求的数量。
+	inFlight uint32
+	// running 代表是否已有分发该队列的goroutine。
+	running bool
+	// dispatched 代表已分发的请求的数量。
+	dispatched uint64
+	// lastWait 代表最近一次分发请求前的等待时间。
+	lastWait time.Duration
+	// totalWait 代表分发请求前的累计等待时间。
+	totalWait time.Duration
+}
+
+// domainLimiter 代表针对单个主域名的限速状态。
+type domainLimiter struct {
+	// next 代表下一次允许发出请求的时间。
+	next time.Time
+	// crawlDelay 代表针对该主域名单独设定的最小间隔时间。
+	crawlDelay time.Duration
+}
+
+// newPoliteness 用于根据请求相关的参数创建礼貌爬取的控制器。
+// 若参数中未设定任何限制且无需遵守robots.txt，则返回nil。
+func newPoliteness(
+	ctx context.Context,
+	requestArgs RequestArgs,
+	handle func(req *module.Request)) *politeness {
+	if requestArgs.MaxRequestsPerSecond <= 0 &&
+		requestArgs.MaxInFlightPerHost == 0 &&
+		requestArgs.CrawlDelay <= 0 &&
+		!requestArgs.ObeyRobots {
+		return nil
+	}
+	var interval time.Duration
+	if requestArgs.MaxRequestsPerSecond > 0 {
+		interval = time.Duration(float64(time.Second) / requestArgs.MaxRequestsPerSecond)
+	}
+	if requestArgs.CrawlDelay > interval {
+		interval = requestArgs.CrawlDelay
+	}
+	return &politeness{
+		ctx:         ctx,
+		interval:    interval,
+		maxInFlight: requestArgs.MaxInFlightPerHost,
+		handle:      handle,
+		hosts:       map[string]*hostQueue{},
+		domains:     map[string]*domainLimiter{},
+	}
+}
+
+// schedule 用于把请求放入对应主机的队列。
+// 请求会在满足限制条件时被异步地交给处理函数。
+func (p *politeness) schedule(req *module.Request) {
+	host, domain := getHostAndDomain(req.HTTPReq())
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	hq, ok := p.hosts[host]
+	if !ok {
+		hq = &hostQueue{host: host, domain: domain}
+		if p.maxInFlight > 0 {
+			hq.slots = make(chan struct{}, p.maxInFlight)
+		}
+		p.hosts[host] = hq
+	}
+	hq.queue = append(hq.queue, req)
+	if !hq.running {
+		hq.running = true
+		go p.dispatch(hq)
+	}
+}
+
+// setCrawlDelay 用于为给定的主域名单独设定最小间隔时间。
+func (p *politeness) setCrawlDelay(domain string, delay time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	dl, ok := p.domains[domain]
+	if !ok {
+		dl = &domainLimiter{}
+		p.domains[domain] = dl
+	}
+	dl.crawlDelay = delay
+}
+
+// dispatch 用于依次分发给定队列中的请求，直到队列为空。
+func (p *politeness) dispatch(hq *hostQueue) {
+	for {
+		p.lock.Lock()
+		if len(hq.queue) == 0 {
+			hq.running = false
+			p.lock.Unlock()
+			return
+		}
+		req := hq.queue[0]
+		hq.queue[0] = nil
+		hq.queue = hq.queue[1:]
+		p.lock.Unlock()
+		begin := time.Now()
+		// 等待进行中的请求数降到限制以下。
+		if hq.slots != nil {
+			select {
+			case hq.slots <- struct{}{}:
+			case <-p.ctx.Done():
+				p.stopDispatch(hq)
+				return
+			}
+		}
+		// 等待主域名的请求间隔。
+		if !p.wait(hq.domain) {
+			if hq.slots != nil {
+				<-hq.slots
+			}
+			p.stopDispatch(hq)
+			return
+		}
+		waited := time.Since(begin)
+		p.lock.Lock()
+		hq.inFlight++
+		hq.dispatched++
+		hq.lastWait = waited
+		hq.totalWait += waited
+		p.lock.Unlock()
+		go func(req *module.Request) {
+			defer p.release(hq)
+			p.handle(req)
+		}(req)
+	}
+}
+
+// stopDispatch 用于在调度器停止时终止对给定队列的分发。
+func (p *politeness) stopDispatch(hq *hostQueue) {
+	p.lock.Lock()
+	hq.running = false
+	p.lock.Unlock()
+}
+
+// release 用于在请求处理完毕后释放主机的并发额度。
+func (p *politeness) release(hq *hostQueue) {
+	p.lock.Lock()
+	hq.inFlight--
+	p.lock.Unlock()
+	if hq.slots != nil {
+		<-hq.slots
+	}
+}
+
+// reserve 用于为给定主域名预留下一个可发出请求的时间，
+// 并返回从现在起需要等待的时长。
+func (p *politeness) reserve(domain string) time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	dl, ok := p.domains[domain]
+	if !ok {
+		dl = &domainLimiter{}
+		p.domains[domain] = dl
+	}
+	interval := p.interval
+	if dl.crawlDelay > interval {
+		interval = dl.crawlDelay
+	}
+	now := time.Now()
+	if interval <= 0 {
+		return 0
+	}
+	next := dl.next
+	if next.Before(now) {
+		next = now
+	}
+	dl.next = next.Add(interval)
+	return next.Sub(now)
+}
+
+// wait 用于为给定主域名预留下一个可发出请求的时间，并等待到该时间。
+// 若在等待期间调度器被停止，则返回false。
+func (p *politeness) wait(domain string) bool {
+	wait := p.reserve(domain)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// total 用于获取所有主机队列中等待中的请求的总数。
+func (p *politeness) total() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var total uint64
+	for _, hq := range p.hosts {
+		total += uint64(len(hq.queue))
+	}
+	return total
+}
+
+// HostSummaryStruct 代表单个主机的礼貌爬取摘要的类型。
+type HostSummaryStruct struct {
+	Host       string `json:"host"`
+	Domain     string `json:"domain"`
+	Queued     uint64 `json:"queued"`
+	InFlight   uint32 `json:"in_flight"`
+	Dispatched uint64 `json:"dispatched"`
+	LastWait   string `json:"last_wait"`
+	AvgWait    string `json:"avg_wait"`
+}
+
+// summary 用于获取所有主机的礼貌爬取摘要，并按主机排序。
+func (p *politeness) summary() []HostSummaryStruct {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	summaries := make([]HostSummaryStruct, 0, len(p.hosts))
+	for _, hq := range p.hosts {
+		var avgWait time.Duration
+		if hq.dispatched > 0 {
+			avgWait = hq.totalWait / time.Duration(hq.dispatched)
+		}
+		summaries = append(summaries, HostSummaryStruct{
+			Host:       hq.host,
+			Domain:     hq.domain,
+			Queued:     uint64(len(hq.queue)),
+			InFlight:   hq.inFlight,
+			Dispatched: hq.dispatched,
+			LastWait:   hq.lastWait.String(),
+			AvgWait:    avgWait.String(),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Host < summaries[j].Host
+	})
+	return summaries
+}
@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestPolitenessNew(t *testing.T) {
+	ctx := context.Background()
+	handle := func(req *module.Request) {}
+	requestArgs := genRequestArgs([]string{}, 0)
+	if p := newPoliteness(ctx, requestArgs, handle); p != nil {
+		t.Fatalf("It still can create politeness without any limitation! (requestArgs: %#v)",
+			requestArgs)
+	}
+	requestArgs.MaxRequestsPerSecond = 4
+	p := newPoliteness(ctx, requestArgs, handle)
+	if p == nil {
+		t.Fatal("Couldn't create politeness!")
+	}
+	expectedInterval := 250 * time.Millisecond
+	if p.interval != expectedInterval {
+		t.Fatalf("Inconsistent interval: expected: %s, actual: %s",
+			expectedInterval, p.interval)
+	}
+	requestArgs.CrawlDelay = time.Second
+	p = newPoliteness(ctx, requestArgs, handle)
+	expectedInterval = time.Second
+	if p.interval != expectedInterval {
+		t.Fatalf("Inconsistent interval: expected: %s, actual: %s",
+			expectedInterval, p.interval)
+	}
+}
+
+func TestPolitenessRate(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.CrawlDelay = 50 * time.Millisecond
+	number := 5
+	var wg sync.WaitGroup
+	wg.Add(number * 2)
+	var lock sync.Mutex
+	timeMap := map[string][]time.Time{}
+	handle := func(req *module.Request) {
+		lock.Lock()
+		host := req.HTTPReq().Host
+		timeMap[host] = append(timeMap[host], time.Now())
+		lock.Unlock()
+		wg.Done()
+	}
+	p := newPoliteness(context.Background(), requestArgs, handle)
+	// 两个主机属于同一个主域名，因此共用同一个请求间隔。
+	for i := 0; i < number; i++ {
+		p.schedule(genPolitenessRequest("cn.bing.com", i, t))
+		p.schedule(genPolitenessRequest("www.bing.com", i, t))
+	}
+	begin := time.Now()
+	wg.Wait()
+	elapsed := time.Since(begin)
+	minElapsed := time.Duration(number*2-2) * requestArgs.CrawlDelay
+	if elapsed < minElapsed {
+		t.Fatalf("Too short elapsed time: expected: >= %s, actual: %s",
+			minElapsed, elapsed)
+	}
+	for host, times := range timeMap {
+		if len(times) != number {
+			t.Fatalf("Inconsistent handled request number: expected: %d, actual: %d (host: %s)",
+				number, len(times), host)
+		}
+	}
+	summaries := p.summary()
+	if len(summaries) != 2 {
+		t.Fatalf("Inconsistent host summary number: expected: %d, actual: %d",
+			2, len(summaries))
+	}
+	for _, hs := range summaries {
+		if hs.Domain != "bing.com" {
+			t.Fatalf("Inconsistent domain in host summary: expected: %s, actual: %s",
+				"bing.com", hs.Domain)
+		}
+		if hs.Dispatched != uint64(number) {
+			t.Fatalf("Inconsistent dispatched number: expected: %d, actual: %d (host: %s)",
+				number, hs.Dispatched, hs.Host)
+		}
+		if hs.Queued != 0 {
+			t.Fatalf("Inconsistent queued number: expected: %d, actual: %d (host: %s)",
+				0, hs.Queued, hs.Host)
+		}
+	}
+	if p.total() != 0 {
+		t.Fatalf("Inconsistent total: expected: %d, actual: %d", 0, p.total())
+	}
+}
+
+func TestPolitenessInFlight(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.MaxInFlightPerHost = 2
+	number := 10
+	var wg sync.WaitGroup
+	wg.Add(number)
+	var current, max int32
+	handle := func(req *module.Request) {
+		defer wg.Done()
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+	}
+	p := newPoliteness(context.Background(), requestArgs, handle)
+	for i := 0; i < number; i++ {
+		p.schedule(genPolitenessRequest("cn.bing.com", i, t))
+	}
+	wg.Wait()
+	if max > int32(requestArgs.MaxInFlightPerHost) {
+		t.Fatalf("Too many in-flight requests: expected: <= %d, actual: %d",
+			requestArgs.MaxInFlightPerHost, max)
+	}
+}
+
+func TestPolitenessStop(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.CrawlDelay = time.Hour
+	var count uint32
+	handle := func(req *module.Request) {
+		atomic.AddUint32(&count, 1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newPoliteness(ctx, requestArgs, handle)
+	p.schedule(genPolitenessRequest("cn.bing.com", 0, t))
+	p.schedule(genPolitenessRequest("cn.bing.com", 1, t))
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadUint32(&count); n != 1 {
+		t.Fatalf("Inconsistent handled request number: expected: %d, actual: %d",
+			1, n)
+	}
+	// 测试单独设定的请求间隔。
+	requestArgs.CrawlDelay = time.Millisecond
+	p = newPoliteness(context.Background(), requestArgs, handle)
+	p.setCrawlDelay("bing.com", time.Hour)
+	p.reserve("bing.com")
+	if wait := p.reserve("bing.com"); wait < time.Minute {
+		t.Fatalf("Too short wait time: expected: >= %s, actual: %s",
+			time.Minute, wait)
+	}
+	if wait := p.reserve("sogou.com"); wait != 0 {
+		t.Fatalf("Inconsistent wait time: expected: %s, actual: %s",
+			time.Duration(0), wait)
+	}
+}
+
+// genPolitenessRequest 用于生成测试礼貌爬取用的请求。
+func genPolitenessRequest(host string, index int, t *testing.T) *module.Request {
+	url := fmt.Sprintf("http://%s/search?q=golang&p=%d", host, index)
+	httpReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating a HTTP request: %s (url: %s)",
+			err, url)
+	}
+	return module.NewRequest(httpReq, 0)
+}
@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRetryBackoff 代表默认的初始重试间隔时间。
+	defaultRetryBackoff = 500 * time.Millisecond
+	// defaultMaxRetryBackoff 代表默认的最大重试间隔时间。
+	defaultMaxRetryBackoff = time.Minute
+	// defaultMaxRetryAfter 代表默认的可以接受的Retry-After头指定的最长等待时间。
+	defaultMaxRetryAfter = 10 * time.Minute
+)
+
+// defaultRetryStatusCodes 代表默认的可重试的HTTP状态码的列表。
+var defaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryPolicy 代表下载失败时的重试策略。
+type retryPolicy struct {
+	// maxAttempts 代表每个请求的最大尝试次数（含首次尝试）。
+	maxAttempts uint32
+	// backoff 代表初始的重试间隔时间。之后每次重试的间隔时间都会翻倍。
+	backoff time.Duration
+	// maxBackoff 代表最大的重试间隔时间。
+	maxBackoff time.Duration
+	// maxRetryAfter 代表可以接受的Retry-After头指定的最长等待时间。
+	maxRetryAfter time.Duration
+	// statusCodes 代表可重试的HTTP状态码的集合。
+	statusCodes map[int]struct{}
+	// random 代表生成抖动用的随机数生成器。
+	random *rand.Rand
+	// randomLock 代表保护随机数生成器的互斥锁。
+	randomLock sync.Mutex
+}
+
+// newRetryPolicy 用于根据请求相关的参数创建重试策略。
+// 若最大尝试次数不大于1，则返回nil。
+func newRetryPolicy(requestArgs RequestArgs) *retryPolicy {
+	if requestArgs.MaxAttempts <= 1 {
+		return nil
+	}
+	policy := &retryPolicy{
+		maxAttempts:   requestArgs.MaxAttempts,
+		backoff:       requestArgs.RetryBackoff,
+		maxBackoff:    requestArgs.MaxRetryBackoff,
+		maxRetryAfter: requestArgs.MaxRetryAfter,
+		statusCodes:   map[int]struct{}{},
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if policy.backoff <= 0 {
+		policy.backoff = defaultRetryBackoff
+	}
+	if policy.maxBackoff <= 0 {
+		policy.maxBackoff = defaultMaxRetryBackoff
+	}
+	if policy.maxRetryAfter <= 0 {
+		policy.maxRetryAfter = defaultMaxRetryAfter
+	}
+	statusCodes := requestArgs.RetryStatusCodes
+	if statusCodes == nil {
+		statusCodes = defaultRetryStatusCodes
+	}
+	for _, code := range statusCodes {
+		policy.statusCodes[code] = struct{}{}
+	}
+	return policy
+}
+
+// canRetry 用于判断已经尝试了给定次数的请求是否还可以重试。
+func (policy *retryPolicy) canRetry(attempt uint32) bool {
+	return attempt < policy.maxAttempts
+}
+
+// retryableStatus 用于判断给定的HTTP状态码是否可重试。
+func (policy *retryPolicy) retryableStatus(statusCode int) bool {
+	_, ok := policy.statusCodes[statusCode]
+	return ok
+}
+
+// delay 用于计算已经尝试了给定次数的请求在重试前需要等待的时间。
+// 等待时间会随着尝试次数呈指数增长，并带有随机抖动。
+// 若参数httpResp中包含Retry-After头，则等待时间不会短于该头指定的时间，
+// 即使该时间超过了最大重试间隔时间。
+// 若该时间超过了可以接受的最长等待时间，则第二个结果值为false，代表应放弃重试。
+func (policy *retryPolicy) delay(attempt uint32, httpResp *http.Response) (time.Duration, bool) {
+	backoff := policy.backoff
+	for i := uint32(1); i < attempt && backoff < policy.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.maxBackoff {
+		backoff = policy.maxBackoff
+	}
+	// 在[backoff/2, backoff]的范围内随机抖动。
+	policy.randomLock.Lock()
+	jitter := time.Duration(policy.random.Int63n(int64(backoff/2) + 1))
+	policy.randomLock.Unlock()
+	delay := backoff - jitter
+	retryAfter := parseRetryAfter(httpResp)
+	if retryAfter > policy.maxRetryAfter {
+		return 0, false
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay, true
+}
+
+// parseRetryAfter 用于解析给定HTTP响应中的Retry-After头。
+// 该头的值可以是秒数，也可以是HTTP日期。若无法解析，则返回0。
+func parseRetryAfter(httpResp *http.Response) time.Duration {
+	if httpResp == nil {
+		return 0
+	}
+	value := httpResp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestRetryPolicyNew(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	if policy := newRetryPolicy(requestArgs); policy != nil {
+		t.Fatalf("It still can create retry policy without max attempts! (requestArgs: %#v)",
+			requestArgs)
+	}
+	requestArgs.MaxAttempts = 1
+	if policy := newRetryPolicy(requestArgs); policy != nil {
+		t.Fatalf("It still can create retry policy with only one attempt! (requestArgs: %#v)",
+			requestArgs)
+	}
+	requestArgs.MaxAttempts = 3
+	policy := newRetryPolicy(requestArgs)
+	if policy == nil {
+		t.Fatal("Couldn't create retry policy!")
+	}
+	if policy.backoff != defaultRetryBackoff {
+		t.Fatalf("Inconsistent backoff: expected: %s, actual: %s",
+			defaultRetryBackoff, policy.backoff)
+	}
+	if policy.maxBackoff != defaultMaxRetryBackoff {
+		t.Fatalf("Inconsistent max backoff: expected: %s, actual: %s",
+			defaultMaxRetryBackoff, policy.maxBackoff)
+	}
+	if policy.maxRetryAfter != defaultMaxRetryAfter {
+		t.Fatalf("Inconsistent max retry after: expected: %s, actual: %s",
+			defaultMaxRetryAfter, policy.maxRetryAfter)
+	}
+	for _, code := range []int{429, 503} {
+		if !policy.retryableStatus(code) {
+			t.Fatalf("Inconsistent retryable status: expected: %v, actual: %v (status code: %d)",
+				true, false, code)
+		}
+	}
+	if policy.retryableStatus(404) {
+		t.Fatalf("Inconsistent retryable status: expected: %v, actual: %v (status code: %d)",
+			false, true, 404)
+	}
+	if !policy.canRetry(2) || policy.canRetry(3) {
+		t.Fatalf("Inconsistent retry permission with max attempts %d!",
+			policy.maxAttempts)
+	}
+	requestArgs.RetryStatusCodes = []int{404}
+	policy = newRetryPolicy(requestArgs)
+	if !policy.retryableStatus(404) || policy.retryableStatus(503) {
+		t.Fatalf("Inconsistent retryable status codes: %v", policy.statusCodes)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	requestArgs := genRequestArgs([]string{}, 0)
+	requestArgs.MaxAttempts = 10
+	requestArgs.RetryBackoff = 100 * time.Millisecond
+	requestArgs.MaxRetryBackoff = time.Second
+	requestArgs.MaxRetryAfter = 5 * time.Minute
+	policy := newRetryPolicy(requestArgs)
+	for attempt := uint32(1); attempt < 10; attempt++ {
+		backoff := requestArgs.RetryBackoff << (attempt - 1)
+		if backoff > requestArgs.MaxRetryBackoff {
+			backoff = requestArgs.MaxRetryBackoff
+		}
+		for i := 0; i < 10; i++ {
+			delay, ok := policy.delay(attempt, nil)
+			if !ok || delay < backoff/2 || delay > backoff {
+				t.Fatalf("Inconsistent delay: expected: [%s, %s], actual: %s (attempt: %d)",
+					backoff/2, backoff, delay, attempt)
+			}
+		}
+	}
+	// 测试Retry-After头。
+	httpResp := &http.Response{Header: http.Header{}}
+	httpResp.Header.Set("Retry-After", "1")
+	if delay, ok := policy.delay(1, httpResp); !ok || delay != time.Second {
+		t.Fatalf("Inconsistent delay: expected: %s, actual: %s",
+			time.Second, delay)
+	}
+	// Retry-After头指定的时间不受最大重试间隔时间的限制。
+	httpResp.Header.Set("Retry-After", "120")
+	if delay, ok := policy.delay(1, httpResp); !ok || delay != 2*time.Minute {
+		t.Fatalf("Inconsistent delay: expected: %s, actual: %s",
+			2*time.Minute, delay)
+	}
+	// Retry-After头指定的时间超过上限时放弃重试。
+	httpResp.Header.Set("Retry-After", "301")
+	if _, ok := policy.delay(1, httpResp); ok {
+		t.Fatalf("It still can retry when Retry-After exceeds %s!", requestArgs.MaxRetryAfter)
+	}
+	httpResp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if _, ok := policy.delay(1, httpResp); ok {
+		t.Fatalf("It still can retry when Retry-After exceeds %s!", requestArgs.MaxRetryAfter)
+	}
+	for _, value := range []string{"", "-1", "abc"} {
+		httpResp.Header.Set("Retry-After", value)
+		if d := parseRetryAfter(httpResp); d != 0 {
+			t.Fatalf("Inconsistent Retry-After: expected: %s, actual: %s (value: %q)",
+				time.Duration(0), d, value)
+		}
+	}
+}
+
+func TestSchedRetry(t *testing.T) {
+	var count, failedCount uint32
+	mux := http.NewServeMux()
+	// 前两次请求会失败，之后的请求会成功。
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&count, 1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "<html><body></body></html>")
+	})
+	// 所有请求都会失败。
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&failedCount, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	requestArgs := genRequestArgs([]string{serverURL.Host}, 0)
+	requestArgs.MaxAttempts = 3
+	requestArgs.RetryBackoff = time.Millisecond
+	sched := NewScheduler()
+	err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/flaky", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	httpReq, _ := http.NewRequest("GET", server.URL+"/broken", nil)
+	mySched := sched.(*myScheduler)
+	mySched.sendReq(module.NewRequest(httpReq, 0))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		summary := sched.Summary().Struct()
+		if summary.Retries == 4 && summary.Failures == 1 && sched.Idle() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Inconsistent retry summary: expected: %d retries and %d failure, actual: %d retries and %d failure(s)",
+				4, 1, summary.Retries, summary.Failures)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadUint32(&count); n != 3 {
+		t.Fatalf("Inconsistent request count: expected: %d, actual: %d", 3, n)
+	}
+	if n := atomic.LoadUint32(&failedCount); n != requestArgs.MaxAttempts {
+		t.Fatalf("Inconsistent request count: expected: %d, actual: %d",
+			requestArgs.MaxAttempts, n)
+	}
+	if n := mySched.pendingReqMap.Len(); n != 0 {
+		t.Fatalf("Inconsistent pending request number: expected: %d, actual: %d", 0, n)
+	}
+}
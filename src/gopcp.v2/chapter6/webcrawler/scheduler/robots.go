@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/errors"
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/robots"
+)
+
+const (
+	// robotsTTL 代表robots.txt规则的缓存有效期。
+	robotsTTL = 24 * time.Hour
+	// robotsErrorTTL 代表robots.txt获取失败的结果的缓存有效期。
+	// 它应远短于robotsTTL，以便在临时故障消除后尽快重新获取。
+	robotsErrorTTL = time.Minute
+)
+
+// robotsCache 代表按主机缓存robots.txt规则的缓存器。
+type robotsCache struct {
+	// fetch 代表获取robots.txt规则的函数。
+	// 参数robotsURL代表robots.txt的URL。
+	fetch func(robotsURL *url.URL) (*robots.Rules, error)
+	// lock 代表保护缓存条目的互斥锁。
+	lock sync.Mutex
+	// entries 代表主机（含scheme）与其缓存条目的映射。
+	entries map[string]*robotsEntry
+}
+
+// robotsEntry 代表单个主机的robots.txt规则的缓存条目。
+type robotsEntry struct {
+	// ready 代表规则是否已获取完毕的通知通道。
+	ready chan struct{}
+	// rules 代表已获取的规则。
+	rules *robots.Rules
+	// err 代表获取规则时发生的错误。
+	err error
+	// expires 代表缓存的过期时间。
+	expires time.Time
+}
+
+// newRobotsCache 用于创建一个robots.txt规则的缓存器。
+func newRobotsCache(fetch func(robotsURL *url.URL) (*robots.Rules, error)) *robotsCache {
+	return &robotsCache{
+		fetch:   fetch,
+		entries: map[string]*robotsEntry{},
+	}
+}
+
+// get 用于获取适用于给定URL的robots.txt规则。
+// 若缓存中没有有效的规则，则会先获取规则。
+// 针对同一主机的并发调用只会获取一次规则。
+// 获取失败的结果只会被缓存robotsErrorTTL的时长。
+func (rc *robotsCache) get(reqURL *url.URL) (*robots.Rules, error) {
+	key := reqURL.Scheme + "://" + reqURL.Host
+	rc.lock.Lock()
+	entry, ok := rc.entries[key]
+	if ok && !entry.expired() {
+		rc.lock.Unlock()
+		<-entry.ready
+		return entry.rules, entry.err
+	}
+	entry = &robotsEntry{ready: make(chan struct{})}
+	rc.entries[key] = entry
+	rc.lock.Unlock()
+	robotsURL := &url.URL{
+		Scheme: reqURL.Scheme,
+		Host:   reqURL.Host,
+		Path:   "/robots.txt",
+	}
+	entry.rules, entry.err = rc.fetch(robotsURL)
+	if entry.err != nil {
+		entry.expires = time.Now().Add(robotsErrorTTL)
+	} else {
+		entry.expires = time.Now().Add(robotsTTL)
+	}
+	close(entry.ready)
+	return entry.rules, entry.err
+}
+
+// expired 用于判断缓存条目是否已过期。
+// 尚未获取完毕的条目不会被视为过期。
+func (entry *robotsEntry) expired() bool {
+	select {
+	case <-entry.ready:
+		return time.Now().After(entry.expires)
+	default:
+		return false
+	}
+}
+
+// checkRobots 用于在下载之前检查给定的请求是否被robots.txt允许。
+// 它在请求经过礼貌爬取控制器的排队之后才会被调用，
+// 因此robots.txt的获取同样受到针对每个主机的限制。
+// 在规则就绪之前，针对同一主机的请求都会在此等待。
+// 被禁止的请求会被过滤掉；若robots.txt获取失败，则会按照重试策略重试该请求。
+// 只有在请求可以被下载时才返回true。
+func (sched *myScheduler) checkRobots(req *module.Request) bool {
+	if sched.robots == nil {
+		return true
+	}
+	httpReq := req.HTTPReq()
+	rules, err := sched.robots.get(httpReq.URL)
+	if err == nil && rules.Allowed(httpReq.URL.RequestURI()) {
+		return true
+	}
+	sched.pendingReqMap.Delete(httpReq.URL.String())
+	if err != nil {
+		sched.retryOrFail(req, nil, fmt.Sprintf("robots.txt is unavailable: %s", err))
+		return false
+	}
+	sched.filterReq(req, rejectReasonRobots, "It is disallowed by robots.txt.")
+	return false
+}
+
+// fetchRobots 用于通过已注册的下载器获取并解析robots.txt。
+// 若robots.txt不存在（4xx），则允许访问所有路径；
+// 若获取失败或服务端出错（5xx），则返回错误。
+func (sched *myScheduler) fetchRobots(robotsURL *url.URL) (*robots.Rules, error) {
+	logger.Infof("Fetch robots.txt (URL: %s)...", robotsURL)
+	domain, err := getPrimaryDomain(robotsURL.Host)
+	if err != nil {
+		domain = robotsURL.Host
+	}
+	rules, err := sched.downloadRobots(robotsURL)
+	if err == nil {
+		if delay := rules.CrawlDelay(); delay > 0 && sched.politeness != nil {
+			logger.Infof("-- Crawl delay: %s (domain: %s)", delay, domain)
+			sched.politeness.setCrawlDelay(domain, delay)
+		}
+	}
+	// 获取robots.txt已经用掉了触发它的请求的间隔，
+	// 因此该请求在下载之前需要再等待一个间隔。
+	if sched.politeness != nil {
+		sched.politeness.wait(domain)
+	}
+	if err != nil {
+		logger.Warnf("Couldn't fetch robots.txt: %s (URL: %s)\n", err, robotsURL)
+		sched.sendContextError(err, errors.ErrorContext{URL: robotsURL.String()})
+		return nil, err
+	}
+	return rules, nil
+}
+
+// downloadRobots 用于下载并解析robots.txt。
+func (sched *myScheduler) downloadRobots(robotsURL *url.URL) (*robots.Rules, error) {
+	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
+	if err != nil || m == nil {
+		return nil, genError(fmt.Sprintf("couldn't get a downloader: %s", err))
+	}
+	downloader, ok := m.(module.Downloader)
+	if !ok {
+		return nil, genError(fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
+			m, m.ID()))
+	}
+	httpReq, err := http.NewRequest("GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	userAgent := sched.requestArgs.RobotsUserAgent
+	if userAgent != "" {
+		httpReq.Header.Set("User-Agent", userAgent)
+	}
+	httpReq = httpReq.WithContext(sched.ctx)
+	resp, err := downloader.Download(module.NewRequest(httpReq, 0))
+	if err != nil {
+		return nil, err
+	}
+	httpResp := resp.HTTPResp()
+	if httpResp == nil {
+		return nil, genError("nil HTTP response of robots.txt")
+	}
+	defer httpResp.Body.Close()
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+		return robots.Parse(httpResp.Body, userAgent)
+	case httpResp.StatusCode >= 400 && httpResp.StatusCode < 500:
+		return robots.AllowAll(), nil
+	default:
+		return nil, genError(fmt.Sprintf("unexpected status code %d of robots.txt",
+			httpResp.StatusCode))
+	}
+}
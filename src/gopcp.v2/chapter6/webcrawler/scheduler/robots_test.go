@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+	"gopcp.v2/chapter6/webcrawler/toolkit/robots"
+)
+
+// robotsContent 代表测试用的robots.txt的内容。
+var robotsContent = `User-agent: gopcp-crawler
+Disallow: /private
+Crawl-delay: 0.01
+
+User-agent: *
+Disallow: /
+`
+
+func TestRobotsCache(t *testing.T) {
+	var count uint32
+	fetch := func(robotsURL *url.URL) (*robots.Rules, error) {
+		atomic.AddUint32(&count, 1)
+		if robotsURL.String() != "http://cn.bing.com/robots.txt" {
+			t.Errorf("Inconsistent robots.txt URL: expected: %s, actual: %s",
+				"http://cn.bing.com/robots.txt", robotsURL)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return robots.DisallowAll(), nil
+	}
+	rc := newRobotsCache(fetch)
+	reqURL, _ := url.Parse("http://cn.bing.com/search?q=golang")
+	number := 10
+	var wg sync.WaitGroup
+	wg.Add(number)
+	for i := 0; i < number; i++ {
+		go func() {
+			defer wg.Done()
+			if rules, err := rc.get(reqURL); err != nil || rules == nil || rules.Allowed("/search") {
+				t.Errorf("Inconsistent robots rules: %#v", rules)
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 1, count)
+	}
+	// 测试缓存过期的情况。
+	rc.entries["http://cn.bing.com"].expires = time.Now().Add(-time.Second)
+	rc.get(reqURL)
+	if count != 2 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 2, count)
+	}
+}
+
+func TestRobotsCacheError(t *testing.T) {
+	var count uint32
+	fetchErr := genError("robots.txt is unavailable")
+	fetch := func(robotsURL *url.URL) (*robots.Rules, error) {
+		if atomic.AddUint32(&count, 1) == 1 {
+			return nil, fetchErr
+		}
+		return robots.AllowAll(), nil
+	}
+	rc := newRobotsCache(fetch)
+	reqURL, _ := url.Parse("http://cn.bing.com/search?q=golang")
+	for i := 0; i < 2; i++ {
+		if _, err := rc.get(reqURL); err != fetchErr {
+			t.Fatalf("Inconsistent error: expected: %v, actual: %v", fetchErr, err)
+		}
+	}
+	entry := rc.entries["http://cn.bing.com"]
+	if ttl := time.Until(entry.expires); ttl > robotsErrorTTL {
+		t.Fatalf("Inconsistent cache TTL of error: expected: <= %s, actual: %s",
+			robotsErrorTTL, ttl)
+	}
+	// 获取失败的结果过期后会被重新获取。
+	entry.expires = time.Now().Add(-time.Second)
+	if rules, err := rc.get(reqURL); err != nil || !rules.Allowed("/search") {
+		t.Fatalf("Inconsistent robots rules after refetching: %#v (error: %v)", rules, err)
+	}
+	if count != 2 {
+		t.Fatalf("Inconsistent fetch count: expected: %d, actual: %d", 2, count)
+	}
+	if ttl := time.Until(rc.entries["http://cn.bing.com"].expires); ttl <= robotsErrorTTL {
+		t.Fatalf("Inconsistent cache TTL: expected: about %s, actual: %s", robotsTTL, ttl)
+	}
+}
+
+func TestSchedRobots(t *testing.T) {
+	var robotsCount uint32
+	release := make(chan struct{})
+	var lock sync.Mutex
+	visited := map[string]bool{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&robotsCount, 1)
+		if ua := r.Header.Get("User-Agent"); ua != "gopcp-crawler" {
+			t.Errorf("Inconsistent user agent: expected: %q, actual: %q",
+				"gopcp-crawler", ua)
+		}
+		<-release
+		fmt.Fprint(w, robotsContent)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		visited[r.URL.Path] = true
+		lock.Unlock()
+		fmt.Fprint(w, "<html><body></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	requestArgs := genRequestArgs([]string{serverURL.Host}, 1)
+	requestArgs.ObeyRobots = true
+	requestArgs.RobotsUserAgent = "gopcp-crawler"
+	dataArgs := genDataArgs(10, 2, 1)
+	moduleArgs := genSimpleModuleArgs(3, 2, 1, t)
+	sched := NewScheduler()
+	if err := sched.Init(requestArgs, dataArgs, moduleArgs); err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	if err := sched.Start(firstHTTPReq); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	mySched := sched.(*myScheduler)
+	// 在robots.txt的规则就绪之前，发送请求不会被阻塞。
+	urls := []string{
+		server.URL + "/public/a.html",
+		server.URL + "/private/a.html",
+		server.URL + "/private",
+	}
+	for _, u := range urls {
+		httpReq, _ := http.NewRequest("GET", u, nil)
+		req := module.NewRequest(httpReq, 1)
+		if !mySched.sendReq(req) {
+			t.Fatalf("Inconsistent sending result: expected: %v, actual: %v (URL: %s)",
+				true, false, u)
+		}
+	}
+	waitFor(t, func() bool { return atomic.LoadUint32(&robotsCount) == 1 })
+	lock.Lock()
+	n := len(visited)
+	lock.Unlock()
+	if n != 0 {
+		t.Fatalf("Some pages are downloaded before the robots.txt rules are ready: %v", visited)
+	}
+	close(release)
+	waitFor(t, func() bool {
+		return sched.Summary().Struct().Rejected[rejectReasonRobots] == 2
+	})
+	waitFor(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return visited["/index.html"] && visited["/public/a.html"]
+	})
+	lock.Lock()
+	for path := range visited {
+		if strings.HasPrefix(path, "/private") {
+			t.Errorf("A page disallowed by robots.txt is downloaded: %s", path)
+		}
+	}
+	lock.Unlock()
+	if n := atomic.LoadUint32(&robotsCount); n != 1 {
+		t.Fatalf("Inconsistent robots.txt fetch count: expected: %d, actual: %d",
+			1, n)
+	}
+	mySched.politeness.lock.Lock()
+	dl := mySched.politeness.domains[serverURL.Hostname()]
+	mySched.politeness.lock.Unlock()
+	if dl == nil || dl.crawlDelay != 10*time.Millisecond {
+		t.Fatalf("Inconsistent crawl delay: expected: %s, actual: %#v",
+			10*time.Millisecond, dl)
+	}
+}
+
+func TestSchedRobotsStatus(t *testing.T) {
+	statusMap := map[int]bool{
+		http.StatusNotFound:            true,
+		http.StatusForbidden:           true,
+		http.StatusInternalServerError: false,
+		http.StatusServiceUnavailable:  false,
+	}
+	for status, expected := range statusMap {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		server := httptest.NewServer(mux)
+		serverURL, _ := url.Parse(server.URL)
+		requestArgs := genRequestArgs([]string{serverURL.Host}, 0)
+		requestArgs.ObeyRobots = true
+		sched := &myScheduler{}
+		err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+		if err != nil {
+			t.Fatalf("An error occurs when initializing scheduler: %s", err)
+		}
+		sched.robots = newRobotsCache(sched.fetchRobots)
+		httpReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+		if actual := sched.checkRobots(module.NewRequest(httpReq, 0)); actual != expected {
+			t.Fatalf("Inconsistent checking result: expected: %v, actual: %v (status code: %d)",
+				expected, actual, status)
+		}
+		// 在没有重试策略时，robots.txt获取失败的请求会被计为下载失败。
+		var failureCount uint64
+		if !expected {
+			failureCount = 1
+		}
+		if actual := atomic.LoadUint64(&sched.failureCount); actual != failureCount {
+			t.Fatalf("Inconsistent failure count: expected: %d, actual: %d (status code: %d)",
+				failureCount, actual, status)
+		}
+		server.Close()
+	}
+}
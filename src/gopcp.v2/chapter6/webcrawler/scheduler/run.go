@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Run 用于启动调度器并阻塞地执行爬取流程。
+// 参数ctx用于控制爬取流程的生命周期。
+// 参数firstHTTPReqs代表若干个首次请求。若调度器已从检查点恢复，则可以为空。
+// 当所有请求、响应和条目都已处理完毕，或ctx被取消，
+// 或调度器被其他的调用方停止时，该方法会停止调度器并返回。
+// 结果值中的摘要信息是调度器停止时的摘要信息。
+// 若是因为ctx被取消而返回，则结果值中的错误值为ctx.Err()。
+func (sched *myScheduler) Run(
+	ctx context.Context, firstHTTPReqs ...*http.Request) (SummaryStruct, error) {
+	if ctx == nil {
+		return SummaryStruct{}, genParameterError("nil context")
+	}
+	if err := sched.start(firstHTTPReqs); err != nil {
+		return SummaryStruct{}, err
+	}
+	schedCtx := sched.ctx
+	doneCh := sched.doneCh
+	var err error
+loop:
+	for sched.pending() > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break loop
+		case <-schedCtx.Done():
+			break loop
+		case <-doneCh:
+		}
+	}
+	if stopErr := sched.Stop(); stopErr != nil && err == nil &&
+		schedCtx.Err() == nil {
+		err = stopErr
+	}
+	return sched.summary.Struct(), err
+}
+
+// resetPending 用于重置进行中的工作的计数，并创建新的完成通知通道。
+func (sched *myScheduler) resetPending() {
+	atomic.StoreInt64(&sched.pendingNumber, 0)
+	sched.doneCh = make(chan struct{}, 1)
+}
+
+// pending 用于获取进行中的工作的数量。
+func (sched *myScheduler) pending() int64 {
+	return atomic.LoadInt64(&sched.pendingNumber)
+}
+
+// incrPending 用于把进行中的工作的计数加1。
+// 注意！它必须在产生该工作的父工作的计数被减少之前调用。
+func (sched *myScheduler) incrPending() {
+	atomic.AddInt64(&sched.pendingNumber, 1)
+}
+
+// decrPending 用于把进行中的工作的计数减1。
+// 若计数降为0，则发出完成通知。
+func (sched *myScheduler) decrPending() {
+	if atomic.AddInt64(&sched.pendingNumber, -1) > 0 {
+		return
+	}
+	select {
+	case sched.doneCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// pageLinkMap 代表测试用的页面与其链接的映射。
+var pageLinkMap = map[string][]string{
+	"/index.html": {"/a.html", "/b.html"},
+	"/a.html":     {"/c.html", "/index.html"},
+	"/b.html":     {"/c.html"},
+	"/c.html":     {"/d.html"},
+	"/d.html":     nil,
+}
+
+func TestSchedRun(t *testing.T) {
+	mux := http.NewServeMux()
+	for page, links := range pageLinkMap {
+		links := links
+		mux.HandleFunc(page, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "<html><body>")
+			for _, link := range links {
+				fmt.Fprintf(w, "<a href=%q>link</a>", link)
+			}
+			fmt.Fprint(w, "</body></html>")
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	requestArgs := genRequestArgs([]string{serverURL.Host}, 2)
+	sched := NewScheduler()
+	err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(3, 2, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	summary, err := sched.Run(ctx, firstHTTPReq)
+	if err != nil {
+		t.Fatalf("An error occurs when running scheduler: %s", err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED), GetStatusDescription(status))
+	}
+	// 深度为3的“/d.html”会被过滤掉。
+	if summary.NumURL != 4 {
+		t.Fatalf("Inconsistent URL number: expected: %d, actual: %d",
+			4, summary.NumURL)
+	}
+	if n := sched.(*myScheduler).pending(); n != 0 {
+		t.Fatalf("Inconsistent pending number: expected: %d, actual: %d", 0, n)
+	}
+}
+
+func TestSchedRunCancel(t *testing.T) {
+	blockCh := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-blockCh:
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer close(blockCh)
+	serverURL, _ := url.Parse(server.URL)
+	requestArgs := genRequestArgs([]string{serverURL.Host}, 0)
+	sched := NewScheduler()
+	err := sched.Init(requestArgs, genDataArgs(10, 2, 1), genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = sched.Run(ctx, firstHTTPReq)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Inconsistent error: expected: %v, actual: %v",
+			context.DeadlineExceeded, err)
+	}
+	if status := sched.Status(); status != SCHED_STATUS_STOPPED {
+		t.Fatalf("Inconsistent status: expected: %s, actual: %s",
+			GetStatusDescription(SCHED_STATUS_STOPPED), GetStatusDescription(status))
+	}
+	// 没有任何首次请求时应该返回错误。
+	if _, err := sched.Run(context.Background()); err == nil {
+		t.Fatalf("No error when running scheduler without first request!")
+	}
+}
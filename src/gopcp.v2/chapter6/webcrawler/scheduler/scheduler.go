@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"gopcp.v2/chapter4/loadgen/lib"
 	"gopcp.v2/chapter5/cmap"
+	werrors "gopcp.v2/chapter6/webcrawler/errors"
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
 	"gopcp.v2/helper/log"
@@ -27,13 +32,55 @@ type Scheduler interface {
 		dataArgs DataArgs,
 		moduleArgs ModuleArgs) (err error)
 	// Start 用于启动调度器并执行爬取流程。
-	// 参数firstHTTPReq即代表首次请求。调度器会以此为起始点开始执行爬取流程。
-	Start(firstHTTPReq *http.Request) (err error)
+	// 参数firstHTTPReqs即代表若干个首次请求。调度器会以此为起始点开始执行爬取流程。
+	// 每个首次请求的主域名都会被添加到可接受的主域名中。
+	// 若调度器已从检查点恢复或已通过AddSeeds方法添加了种子，则可以不传入该参数。
+	Start(firstHTTPReqs ...*http.Request) (err error)
+	// AddSeeds 用于添加若干个种子。每个种子都可以有自己的爬取范围。
+	// 若调度器正在运行，则种子请求会被立即放入；
+	// 若调度器已初始化或已停止，则种子请求会在下次启动时被放入。
+	AddSeeds(seeds ...Seed) error
+	// Run 用于启动调度器并阻塞地执行爬取流程，直到爬取完成或ctx被取消。
+	// 参数firstHTTPReqs代表若干个首次请求。若调度器已从检查点恢复，则可以为空。
+	// 返回时调度器已被停止，结果值中包含停止时的摘要信息。
+	// 若ctx被取消，则结果值中的错误值为ctx.Err()。
+	Run(ctx context.Context, firstHTTPReqs ...*http.Request) (SummaryStruct, error)
+	// Pause 用于暂停调度器的运行。
+	// 暂停期间，各个处理流程都不会再从URL边界和缓冲池中取出数据，
+	// 而其中已有的数据都会被保留。正在处理中的数据会在处理完成后再暂停。
+	Pause() (err error)
+	// Resume 用于恢复已暂停的调度器的运行。
+	Resume() (err error)
 	// Stop 用于停止调度器的运行。
 	// 所有处理模块执行的流程都会被中止。
 	Stop() (err error)
+	// StopGracefully 用于优雅地停止调度器的运行。
+	// 调度器会立即停止下载新的请求，但会等待已下载的响应被分析、
+	// 已生成的条目被处理之后再停止。等待的时长受参数ctx的限制。
+	// 未被下载的请求都会被保留在检查点中。
+	// 若在等待完成之前ctx已被取消，则调度器会被立即停止，且结果值为ctx.Err()。
+	StopGracefully(ctx context.Context) (err error)
 	// Status 用于获取调度器的状态。
 	Status() Status
+	// Subscribe 用于添加事件订阅者，并返回用于取消订阅的函数。
+	// 订阅者会在事件发生时被同步地调用。
+	// 参数types代表订阅的事件类型。若为空，则订阅所有类型的事件。
+	// 订阅可以在调度器初始化之前进行，且不会因调度器的重新初始化而失效。
+	Subscribe(subscriber Subscriber, types ...EventType) (cancel func())
+	// SubscribeChan 用于以带缓冲的通道的形式订阅事件，
+	// 并返回该通道和用于取消订阅的函数。
+	// 参数bufferCap代表通道的容量。通道已满时新的事件会被丢弃。
+	// 参数types代表订阅的事件类型。若为空，则订阅所有类型的事件。
+	// 取消订阅时该通道会被关闭。
+	SubscribeChan(bufferCap uint32, types ...EventType) (<-chan Event, func())
+	// SubscribeErrors 用于以带缓冲的通道的形式订阅错误，
+	// 并返回该通道和用于取消订阅的函数。
+	// 通道中的错误都带有出错的组件ID、请求的URL、深度和产生时间等上下文信息。
+	// 参数bufferCap代表通道的容量。通道已满时新的错误会被丢弃。
+	// 参数errTypes代表订阅的错误类型。若为空，则订阅所有类型的错误。
+	// 取消订阅时该通道会被关闭。
+	SubscribeErrors(bufferCap uint32,
+		errTypes ...werrors.ErrorType) (<-chan werrors.CrawlerError, func())
 	// ErrorChan 用于获得错误通道。
 	// 调度器以及各个处理模块运行过程中出现的所有错误都会被发送到该通道。
 	// 若结果值为nil，则说明错误通道不可用或调度器已被停止。
@@ -42,6 +89,14 @@ type Scheduler interface {
 	Idle() bool
 	// Summary 用于获取摘要实例。
 	Summary() SchedSummary
+	// Checkpoint 用于把当前的爬取进度写入给定的写入器。
+	// 写入的内容包括已处理的URL、尚未完成下载的请求以及相关的参数。
+	// 该方法可以在调度器运行期间或停止之后调用。
+	Checkpoint(w io.Writer) error
+	// Restore 用于从给定的读取器中恢复之前写入的爬取进度。
+	// 该方法只能在调度器已初始化或已停止时调用，
+	// 之后调用Start方法即可继续执行爬取流程。
+	Restore(r io.Reader) error
 }
 
 // NewScheduler 会创建一个调度器实例。
@@ -51,22 +106,68 @@ func NewScheduler() Scheduler {
 
 // myScheduler 代表调度器的实现类型。
 type myScheduler struct {
+	// retryCount 代表已安排的重试的次数。
+	retryCount uint64
+	// failureCount 代表最终下载失败的请求的数量。
+	failureCount uint64
+	// retryingNumber 代表正在等待重试的请求的数量。
+	retryingNumber int64
+	// pendingNumber 代表进行中的工作的数量，
+	// 包括待下载或重试的请求、待分析的响应以及待处理的条目。
+	pendingNumber int64
+	// doneCh 代表进行中的工作全部完成时的通知通道。
+	doneCh chan struct{}
+	// retryPolicy 代表下载失败时的重试策略。若为nil则代表不重试。
+	retryPolicy *retryPolicy
 	// maxDepth 代表爬取的最大深度。首次请求的深度为0。
 	maxDepth uint32
 	// acceptedDomainMap 代表可以接受的URL的主域名的字典。
 	acceptedDomainMap cmap.ConcurrentMap
 	// registrar 代表组件注册器。
 	registrar module.Registrar
-	// reqBufferPool 代表请求的缓冲池。
-	reqBufferPool buffer.Pool
+	// frontier 代表存放待下载请求的URL边界。
+	frontier Frontier
 	// respBufferPool 代表响应的缓冲池。
 	respBufferPool buffer.Pool
 	// itemBufferPool 代表条目的缓冲池。
 	itemBufferPool buffer.Pool
 	// errorBufferPool 代表错误的缓冲池。
 	errorBufferPool buffer.Pool
-	// urlMap 代表已处理的URL的字典。
-	urlMap cmap.ConcurrentMap
+	// downloadTickets 代表下载阶段的工作协程票池。
+	downloadTickets lib.GoTickets
+	// analyzeTickets 代表分析阶段的工作协程票池。
+	analyzeTickets lib.GoTickets
+	// pickTickets 代表条目处理阶段的工作协程票池。
+	pickTickets lib.GoTickets
+	// deduper 代表记录已处理的URL的去重器。
+	deduper Deduper
+	// canonicalizer 代表生成去重用的键的URL规范化器。
+	canonicalizer *canonicalizer
+	// pendingReqMap 代表尚未完成下载的请求的字典。
+	pendingReqMap cmap.ConcurrentMap
+	// restoredReqs 代表从检查点恢复的、待启动时放入的请求的列表。
+	restoredReqs []*module.Request
+	// seedReqs 代表在启动之前添加的、待启动时放入的种子请求的列表。
+	seedReqs []*module.Request
+	// requestArgs 代表请求相关的参数。
+	requestArgs RequestArgs
+	// dataArgs 代表数据相关的参数。
+	dataArgs DataArgs
+	// moduleArgs 代表组件相关的参数。
+	moduleArgs ModuleArgs
+	// politeness 代表礼貌爬取的控制器。若为nil则代表不做礼貌爬取的限制。
+	// 它会在每次启动时被替换，在调度流程之外读取时需持有statusLock。
+	politeness *politeness
+	// robots 代表robots.txt规则的缓存器。若为nil则代表不检查robots.txt。
+	robots *robotsCache
+	// filters 代表URL的过滤链。
+	filters filterChain
+	// hostLimiter 代表每个主机的URL数量的限制器。若为nil则代表不限制。
+	hostLimiter *hostLimiter
+	// rejected 代表按原因统计的被过滤请求的计数器。
+	rejected *rejectedCounter
+	// recentErrors 代表最近发生的错误的记录器。
+	recentErrors *errorRecorder
 	// ctx 代表上下文，用于感知调度器的停止。
 	ctx context.Context
 	// cancelFunc 代表取消函数，用于停止调度器。
@@ -75,6 +176,12 @@ type myScheduler struct {
 	status Status
 	// statusLock 代表专用于状态的读写锁。
 	statusLock sync.RWMutex
+	// events 代表事件的分发器。
+	events eventHub
+	// gate 代表暂停闸门。
+	gate pauseGate
+	// drainer 代表用于优雅停止的进行中工作的跟踪器。
+	drainer drainer
 	// summary 代表摘要信息。
 	summary SchedSummary
 }
@@ -122,19 +229,22 @@ func (sched *myScheduler) Init(
 	} else {
 		sched.registrar.Clear()
 	}
-	sched.maxDepth = requestArgs.MaxDepth
-	logger.Infof("-- Max depth: %d", sched.maxDepth)
-	sched.acceptedDomainMap, _ =
-		cmap.NewConcurrentMap(1, nil)
-	for _, domain := range requestArgs.AcceptedDomains {
-		sched.acceptedDomainMap.Put(domain, struct{}{})
+	sched.initRequestArgs(requestArgs)
+	if sched.deduper, err = NewDeduper(dataArgs); err != nil {
+		return err
 	}
-	logger.Infof("-- Accepted primary domains: %v",
-		requestArgs.AcceptedDomains)
-	sched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
-	logger.Infof("-- URL map: length: %d, concurrency: %d",
-		sched.urlMap.Len(), sched.urlMap.Concurrency())
+	logger.Infof("-- Deduper: type: %s", sched.deduper.Type())
+	sched.pendingReqMap, _ = cmap.NewConcurrentMap(16, nil)
+	sched.restoredReqs = nil
+	sched.seedReqs = nil
+	sched.rejected = newRejectedCounter()
+	sched.recentErrors = newErrorRecorder()
+	atomic.StoreUint64(&sched.retryCount, 0)
+	atomic.StoreUint64(&sched.failureCount, 0)
+	sched.dataArgs = dataArgs
+	sched.moduleArgs = moduleArgs
 	sched.initBufferPool(dataArgs)
+	sched.initWorkers(dataArgs)
 	sched.resetContext()
 	sched.summary =
 		newSchedSummary(requestArgs, dataArgs, moduleArgs, sched)
@@ -147,7 +257,14 @@ func (sched *myScheduler) Init(
 	return nil
 }
 
-func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
+func (sched *myScheduler) Start(firstHTTPReqs ...*http.Request) (err error) {
+	return sched.start(firstHTTPReqs)
+}
+
+// start 用于启动调度器并以给定的若干个首次请求开始执行爬取流程。
+// 其中为nil的首次请求会被忽略。
+// 若调度器已从检查点恢复或已添加了种子，则可以没有任何有效的首次请求。
+func (sched *myScheduler) start(firstHTTPReqs []*http.Request) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			errMsg := fmt.Sprintf("Fatal scheduler error: %s", p)
@@ -175,32 +292,74 @@ func (sched *myScheduler) Start(firstHTTPReq *http.Request) (err error) {
 	}
 	// 检查参数。
 	logger.Info("Check first HTTP request...")
-	if firstHTTPReq == nil {
-		err = genParameterError("nil first HTTP request")
-		return
+	var firstReqs []*module.Request
+	for _, firstHTTPReq := range firstHTTPReqs {
+		if firstHTTPReq == nil {
+			continue
+		}
+		seed := NewSeed(firstHTTPReq, SEED_SCOPE_DOMAIN)
+		if err = seed.check(); err != nil {
+			return
+		}
+		var firstReq *module.Request
+		firstReq, err = sched.addSeedScope(seed)
+		if err != nil {
+			return
+		}
+		firstReqs = append(firstReqs, firstReq)
 	}
-	logger.Info("The first HTTP request is valid.")
-	// 获得首次请求的主域名，并将其添加到可接受的主域名的字典。
-	logger.Info("Get the primary domain...")
-	logger.Infof("-- Host: %s", firstHTTPReq.Host)
-	var primaryDomain string
-	primaryDomain, err = getPrimaryDomain(firstHTTPReq.Host)
-	if err != nil {
-		return
+	firstReqs = append(firstReqs, sched.seedReqs...)
+	if len(firstReqs) == 0 {
+		if len(sched.restoredReqs) == 0 {
+			err = genParameterError("nil first HTTP request")
+			return
+		}
+		logger.Info("No first HTTP request. Use the restored requests instead.")
+	} else {
+		logger.Infof("The first HTTP request is valid. (number: %d)", len(firstReqs))
 	}
-	logger.Infof("-- Primary domain: %s", primaryDomain)
-	sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
 	// 开始调度数据和组件。
 	if err = sched.checkBufferPoolForStart(); err != nil {
 		return
 	}
+	if sched.canceled() {
+		sched.resetContext()
+	}
+	sched.resetPending()
+	sched.drainer.reset()
+	politeness := newPoliteness(
+		sched.ctx, sched.requestArgs, sched.downloadWithTicket)
+	// 摘要信息可能会在调度器重启期间被并发地获取，所以需要在锁的保护下替换。
+	sched.statusLock.Lock()
+	sched.politeness = politeness
+	sched.statusLock.Unlock()
+	if politeness != nil {
+		logger.Infof("-- Politeness: interval: %s, max in-flight per host: %d",
+			politeness.interval, politeness.maxInFlight)
+	}
+	sched.robots = nil
+	if sched.requestArgs.ObeyRobots {
+		logger.Infof("-- Obey robots.txt: user agent: %q",
+			sched.requestArgs.RobotsUserAgent)
+		sched.robots = newRobotsCache(sched.fetchRobots)
+	}
 	sched.download()
 	sched.analyze()
 	sched.pick()
 	logger.Info("Scheduler has been started.")
-	// 放入第一个请求。
-	firstReq := module.NewRequest(firstHTTPReq, 0)
-	sched.sendReq(firstReq)
+	// 放入从检查点恢复的请求。
+	if len(sched.restoredReqs) > 0 {
+		logger.Infof("Put %d restored request(s)...", len(sched.restoredReqs))
+		for _, req := range sched.restoredReqs {
+			sched.putReq(req)
+		}
+		sched.restoredReqs = nil
+	}
+	// 放入首次请求和种子请求。
+	for _, firstReq := range firstReqs {
+		sched.sendReq(firstReq)
+	}
+	sched.seedReqs = nil
 	return nil
 }
 
@@ -223,13 +382,19 @@ func (sched *myScheduler) Stop() (err error) {
 	if err != nil {
 		return
 	}
+	sched.shutdown()
+	logger.Info("Scheduler has been stopped.")
+	return nil
+}
+
+// shutdown 用于取消调度器的上下文，并关闭URL边界和各个缓冲池。
+func (sched *myScheduler) shutdown() {
 	sched.cancelFunc()
-	sched.reqBufferPool.Close()
+	sched.gate.open()
+	sched.frontier.Close()
 	sched.respBufferPool.Close()
 	sched.itemBufferPool.Close()
 	sched.errorBufferPool.Close()
-	logger.Info("Scheduler has been stopped.")
-	return nil
 }
 
 func (sched *myScheduler) Status() Status {
@@ -258,7 +423,7 @@ func (sched *myScheduler) ErrorChan() <-chan error {
 			err, ok := datum.(error)
 			if !ok {
 				errMsg := fmt.Sprintf("incorrect error type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				sched.sendError(errors.New(errMsg), "")
 				continue
 			}
 			if sched.canceled() {
@@ -278,11 +443,17 @@ func (sched *myScheduler) Idle() bool {
 			return false
 		}
 	}
-	if sched.reqBufferPool.Total() > 0 ||
+	if sched.frontier.Len() > 0 ||
 		sched.respBufferPool.Total() > 0 ||
 		sched.itemBufferPool.Total() > 0 {
 		return false
 	}
+	if sched.politeness != nil && sched.politeness.total() > 0 {
+		return false
+	}
+	if atomic.LoadInt64(&sched.retryingNumber) > 0 {
+		return false
+	}
 	return true
 }
 
@@ -353,41 +524,53 @@ func (sched *myScheduler) registerModules(moduleArgs ModuleArgs) error {
 	return nil
 }
 
-// download 会从请求缓冲池取出请求并下载，
+// download 会从URL边界取出请求并下载，
 // 然后把得到的响应放入响应缓冲池。
+// 若启用了礼貌爬取，请求会先经过礼貌爬取控制器的排队再被下载。
 func (sched *myScheduler) download() {
 	go func() {
 		for {
-			if sched.canceled() {
+			if sched.canceled() || !sched.waitIfPaused() ||
+				sched.drainer.isDraining() {
 				break
 			}
-			datum, err := sched.reqBufferPool.Get()
+			req, err := sched.frontier.Pop()
 			if err != nil {
-				logger.Warnln("The request buffer pool was closed. Break request reception.")
+				logger.Warnln("The frontier was closed. Break request reception.")
 				break
 			}
-			req, ok := datum.(*module.Request)
-			if !ok {
-				errMsg := fmt.Sprintf("incorrect request type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+			if sched.politeness != nil {
+				sched.politeness.schedule(req)
+				continue
 			}
-			sched.downloadOne(req)
+			goWithTicket(sched.downloadTickets, func() {
+				sched.downloadOne(req)
+			})
 		}
 	}()
 }
 
 // downloadOne 会根据给定的请求执行下载并把响应放入响应缓冲池。
 func (sched *myScheduler) downloadOne(req *module.Request) {
+	defer sched.decrPending()
 	if req == nil {
 		return
 	}
-	if sched.canceled() {
+	if sched.canceled() || !sched.waitIfPaused() {
+		return
+	}
+	// 优雅停止时，尚未开始下载的请求不会再被下载。
+	if !sched.drainer.begin() {
+		return
+	}
+	defer sched.drainer.done()
+	if !sched.checkRobots(req) {
 		return
 	}
 	m, err := sched.registrar.Get(module.TYPE_DOWNLOADER)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a downloader: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
+		sched.sendError(errors.New(errMsg), "")
 		sched.sendReq(req)
 		return
 	}
@@ -395,17 +578,93 @@ func (sched *myScheduler) downloadOne(req *module.Request) {
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect downloader type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
+		sched.sendError(errors.New(errMsg), m.ID())
 		sched.sendReq(req)
 		return
 	}
-	resp, err := downloader.Download(req)
-	if resp != nil {
-		sendResp(resp, sched.respBufferPool)
+	begin := time.Now()
+	// 让下载过程能够感知调度器的停止。
+	resp, err := downloader.Download(req.WithContext(sched.ctx))
+	event := &DownloadedEvent{
+		At:      time.Now(),
+		MID:     m.ID(),
+		Attempt: req.Attempt(),
+		Latency: time.Since(begin),
+		Err:     err,
+	}
+	if httpReq := req.HTTPReq(); httpReq != nil && httpReq.URL != nil {
+		sched.pendingReqMap.Delete(httpReq.URL.String())
+		event.URL = httpReq.URL.String()
 	}
+	if resp != nil && resp.HTTPResp() != nil {
+		event.StatusCode = resp.HTTPResp().StatusCode
+	}
+	sched.emit(event)
 	if err != nil {
-		sendError(err, m.ID(), sched.errorBufferPool)
+		if !sched.retryOrFail(req, nil, err.Error()) {
+			sched.sendContextError(err, getReqErrorContext(m.ID(), req))
+		}
+		return
+	}
+	if resp == nil {
+		return
+	}
+	if httpResp := resp.HTTPResp(); httpResp != nil &&
+		sched.retryPolicy != nil &&
+		sched.retryPolicy.retryableStatus(httpResp.StatusCode) {
+		reason := fmt.Sprintf("status code %d", httpResp.StatusCode)
+		if sched.retryOrFail(req, httpResp, reason) {
+			if httpResp.Body != nil {
+				httpResp.Body.Close()
+			}
+			return
+		}
 	}
+	sched.sendResp(resp)
+}
+
+// retryOrFail 会在重试策略允许时安排对给定请求的重试，并返回true。
+// 否则，该请求会被计为最终下载失败，并返回false。
+// 参数httpResp代表本次尝试得到的HTTP响应，可以为nil。
+// 参数reason代表需要重试的原因。
+func (sched *myScheduler) retryOrFail(
+	req *module.Request, httpResp *http.Response, reason string) bool {
+	reqURL := req.HTTPReq().URL
+	policy := sched.retryPolicy
+	var delay time.Duration
+	ok := policy != nil && policy.canRetry(req.Attempt()) && !sched.canceled()
+	if ok {
+		if delay, ok = policy.delay(req.Attempt(), httpResp); !ok {
+			reason = fmt.Sprintf("%s, and Retry-After exceeds %s",
+				reason, policy.maxRetryAfter)
+		}
+	}
+	if !ok {
+		atomic.AddUint64(&sched.failureCount, 1)
+		logger.Warnf("Give up the request after %d attempt(s): %s (URL: %s)\n",
+			req.Attempt(), reason, reqURL)
+		return false
+	}
+	retryReq := req.Retry()
+	atomic.AddUint64(&sched.retryCount, 1)
+	atomic.AddInt64(&sched.retryingNumber, 1)
+	sched.incrPending()
+	sched.pendingReqMap.Put(reqURL.String(), retryReq)
+	logger.Infof("Retry the request in %s: %s (attempt: %d, URL: %s)",
+		delay, reason, retryReq.Attempt(), reqURL)
+	ctx := sched.ctx
+	time.AfterFunc(delay, func() {
+		defer atomic.AddInt64(&sched.retryingNumber, -1)
+		if ctx.Err() != nil {
+			sched.decrPending()
+			return
+		}
+		if err := sched.frontier.Push(retryReq); err != nil {
+			logger.Warnln("The frontier was closed. Ignore request retrying.")
+			sched.decrPending()
+		}
+	})
+	return true
 }
 
 // analyze 会从响应缓冲池取出响应并解析，
@@ -413,7 +672,7 @@ func (sched *myScheduler) downloadOne(req *module.Request) {
 func (sched *myScheduler) analyze() {
 	go func() {
 		for {
-			if sched.canceled() {
+			if sched.canceled() || !sched.waitIfPaused() {
 				break
 			}
 			datum, err := sched.respBufferPool.Get()
@@ -424,36 +683,45 @@ func (sched *myScheduler) analyze() {
 			resp, ok := datum.(*module.Response)
 			if !ok {
 				errMsg := fmt.Sprintf("incorrect response type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				sched.sendError(errors.New(errMsg), "")
 			}
-			sched.analyzeOne(resp)
+			goWithTicket(sched.analyzeTickets, func() {
+				sched.analyzeOne(resp)
+			})
 		}
 	}()
 }
 
 // analyzeOne 会根据给定的响应执行解析并把结果放入相应的缓冲池。
 func (sched *myScheduler) analyzeOne(resp *module.Response) {
+	defer sched.decrPending()
+	defer sched.drainer.done()
 	if resp == nil {
 		return
 	}
-	if sched.canceled() {
+	if sched.canceled() || !sched.waitIfPaused() {
 		return
 	}
 	m, err := sched.registrar.Get(module.TYPE_ANALYZER)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get an analyzer: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		sched.sendError(errors.New(errMsg), "")
+		sched.sendResp(resp)
 		return
 	}
 	analyzer, ok := m.(module.Analyzer)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect analyzer type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendResp(resp, sched.respBufferPool)
+		sched.sendError(errors.New(errMsg), m.ID())
+		sched.sendResp(resp)
 		return
 	}
+	var respURL string
+	if httpResp := resp.HTTPResp(); httpResp != nil &&
+		httpResp.Request != nil && httpResp.Request.URL != nil {
+		respURL = httpResp.Request.URL.String()
+	}
 	dataList, errs := analyzer.Analyze(resp)
 	if dataList != nil {
 		for _, data := range dataList {
@@ -464,16 +732,24 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 			case *module.Request:
 				sched.sendReq(d)
 			case module.Item:
-				sendItem(d, sched.itemBufferPool)
+				sched.emit(&ItemEmittedEvent{
+					At:   time.Now(),
+					URL:  respURL,
+					MID:  m.ID(),
+					Item: d,
+				})
+				sched.sendItem(d)
 			default:
 				errMsg := fmt.Sprintf("Unsupported data type %T! (data: %#v)", d, d)
-				sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
+				sched.sendContextError(errors.New(errMsg),
+					getRespErrorContext(m.ID(), resp))
 			}
 		}
 	}
 	if errs != nil {
+		errCtx := getRespErrorContext(m.ID(), resp)
 		for _, err := range errs {
-			sendError(err, m.ID(), sched.errorBufferPool)
+			sched.sendContextError(err, errCtx)
 		}
 	}
 }
@@ -482,7 +758,7 @@ func (sched *myScheduler) analyzeOne(resp *module.Response) {
 func (sched *myScheduler) pick() {
 	go func() {
 		for {
-			if sched.canceled() {
+			if sched.canceled() || !sched.waitIfPaused() {
 				break
 			}
 			datum, err := sched.itemBufferPool.Get()
@@ -493,42 +769,47 @@ func (sched *myScheduler) pick() {
 			item, ok := datum.(module.Item)
 			if !ok {
 				errMsg := fmt.Sprintf("incorrect item type: %T", datum)
-				sendError(errors.New(errMsg), "", sched.errorBufferPool)
+				sched.sendError(errors.New(errMsg), "")
 			}
-			sched.pickOne(item)
+			goWithTicket(sched.pickTickets, func() {
+				sched.pickOne(item)
+			})
 		}
 	}()
 }
 
 // pickOne 会处理给定的条目。
 func (sched *myScheduler) pickOne(item module.Item) {
-	if sched.canceled() {
+	defer sched.decrPending()
+	defer sched.drainer.done()
+	if sched.canceled() || !sched.waitIfPaused() {
 		return
 	}
 	m, err := sched.registrar.Get(module.TYPE_PIPELINE)
 	if err != nil || m == nil {
 		errMsg := fmt.Sprintf("couldn't get a pipeline: %s", err)
-		sendError(errors.New(errMsg), "", sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		sched.sendError(errors.New(errMsg), "")
+		sched.sendItem(item)
 		return
 	}
 	pipeline, ok := m.(module.Pipeline)
 	if !ok {
 		errMsg := fmt.Sprintf("incorrect pipeline type: %T (MID: %s)",
 			m, m.ID())
-		sendError(errors.New(errMsg), m.ID(), sched.errorBufferPool)
-		sendItem(item, sched.itemBufferPool)
+		sched.sendError(errors.New(errMsg), m.ID())
+		sched.sendItem(item)
 		return
 	}
 	errs := pipeline.Send(item)
 	if errs != nil {
+		errCtx := getItemErrorContext(m.ID(), item)
 		for _, err := range errs {
-			sendError(err, m.ID(), sched.errorBufferPool)
+			sched.sendContextError(err, errCtx)
 		}
 	}
 }
 
-// sendReq 会向请求缓冲池发送请求。
+// sendReq 会向URL边界发送请求。
 // 不符合要求的请求会被过滤掉。
 func (sched *myScheduler) sendReq(req *module.Request) bool {
 	if req == nil {
@@ -549,34 +830,136 @@ func (sched *myScheduler) sendReq(req *module.Request) bool {
 	}
 	scheme := strings.ToLower(reqURL.Scheme)
 	if scheme != "http" && scheme != "https" {
-		logger.Warnf("Ignore the request! Its URL scheme is %q, but should be %q or %q. (URL: %s)\n",
-			scheme, "http", "https", reqURL)
+		sched.filterReq(req, rejectReasonScheme,
+			fmt.Sprintf("Its URL scheme is %q, but should be %q or %q.",
+				scheme, "http", "https"))
 		return false
 	}
-	if v := sched.urlMap.Get(reqURL.String()); v != nil {
-		logger.Warnf("Ignore the request! Its URL is repeated. (URL: %s)\n", reqURL)
-		return false
-	}
-	pd, _ := getPrimaryDomain(httpReq.Host)
-	if sched.acceptedDomainMap.Get(pd) == nil {
-		if pd == "bing.net" {
+	if !sched.inScope(req) {
+		if pd, _ := getPrimaryDomain(httpReq.Host); pd == "bing.net" {
 			panic(httpReq.URL)
 		}
-		logger.Warnf("Ignore the request! Its host %q is not in accepted primary domain map. (URL: %s)\n",
-			httpReq.Host, reqURL)
+		sched.filterReq(req, rejectReasonDomain,
+			fmt.Sprintf("Its host %q is not in accepted primary domain map or its seed's scope.",
+				httpReq.Host))
 		return false
 	}
 	if req.Depth() > sched.maxDepth {
-		logger.Warnf("Ignore the request! Its depth %d is greater than %d. (URL: %s)\n",
-			req.Depth(), sched.maxDepth, reqURL)
+		sched.filterReq(req, rejectReasonDepth,
+			fmt.Sprintf("Its depth %d is greater than %d.", req.Depth(), sched.maxDepth))
 		return false
 	}
+	if reason, ok := sched.filters.check(reqURL); !ok {
+		sched.filterReq(req, reason,
+			fmt.Sprintf("It is rejected by the %q filter.", reason))
+		return false
+	}
+	// 以添加键的结果作为唯一的判断依据，以保证并发发送同一URL时只有一个请求被接受。
+	// 键在有副作用的检查之前就被占用。之后的检查若拒绝了该URL，键也不会被撤销，
+	// 因为这些检查的结果对同一URL来说不会改变。
+	key := sched.canonicalizer.canonicalize(reqURL)
+	if !sched.deduper.Add(key) {
+		sched.filterReq(req, rejectReasonDuplicate, "Its URL is repeated.")
+		return false
+	}
+	if sched.hostLimiter != nil && !sched.hostLimiter.take(reqURL.Host) {
+		sched.filterReq(req, rejectReasonHostLimit,
+			fmt.Sprintf("Its host %q has reached the URL limit %d.",
+				reqURL.Host, sched.hostLimiter.max))
+		return false
+	}
+	sched.pendingReqMap.Put(reqURL.String(), req)
+	// 优雅停止时新的请求不会再被下载，但会被保留在检查点中。
+	if !sched.drainer.isDraining() {
+		sched.putReq(req)
+	}
+	sched.emit(&URLAcceptedEvent{
+		At:    time.Now(),
+		URL:   reqURL.String(),
+		Depth: req.Depth(),
+	})
+	return true
+}
+
+// filterReq 会按照给定的原因对被过滤的请求计数，并发出相应的事件。
+func (sched *myScheduler) filterReq(req *module.Request, reason, detail string) {
+	sched.rejected.incr(reason)
+	sched.emit(&URLFilteredEvent{
+		At:     time.Now(),
+		URL:    req.HTTPReq().URL.String(),
+		Depth:  req.Depth(),
+		Reason: reason,
+		Detail: detail,
+	})
+}
+
+// rejectedCounter 代表按原因统计的被过滤请求的计数器。
+type rejectedCounter struct {
+	// lock 代表保护计数的互斥锁。
+	lock sync.Mutex
+	// counts 代表原因与计数的映射。
+	counts map[string]uint64
+}
+
+// newRejectedCounter 用于创建一个被过滤请求的计数器。
+func newRejectedCounter() *rejectedCounter {
+	return &rejectedCounter{counts: map[string]uint64{}}
+}
+
+// incr 用于把给定原因的计数加1。
+func (rc *rejectedCounter) incr(reason string) {
+	rc.lock.Lock()
+	rc.counts[reason]++
+	rc.lock.Unlock()
+}
+
+// snapshot 用于获取所有原因的计数的快照。
+// 若尚无任何请求被过滤，则返回nil。
+func (rc *rejectedCounter) snapshot() map[string]uint64 {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if len(rc.counts) == 0 {
+		return nil
+	}
+	counts := make(map[string]uint64, len(rc.counts))
+	for reason, count := range rc.counts {
+		counts[reason] = count
+	}
+	return counts
+}
+
+// putReq 会把请求异步地放入URL边界，且不做任何过滤。
+func (sched *myScheduler) putReq(req *module.Request) {
+	sched.incrPending()
 	go func(req *module.Request) {
-		if err := sched.reqBufferPool.Put(req); err != nil {
-			logger.Warnln("The request buffer pool was closed. Ignore request sending.")
+		if err := sched.frontier.Push(req); err != nil {
+			logger.Warnln("The frontier was closed. Ignore request sending.")
+			sched.decrPending()
 		}
 	}(req)
-	sched.urlMap.Put(reqURL.String(), struct{}{})
+}
+
+// sendResp 会向响应缓冲池发送响应，并把它计入进行中的工作。
+func (sched *myScheduler) sendResp(resp *module.Response) bool {
+	sched.incrPending()
+	sched.drainer.add()
+	if !sendResp(resp, sched.respBufferPool) {
+		sched.drainer.done()
+		sched.decrPending()
+		return false
+	}
+	return true
+}
+
+// sendItem 会向条目缓冲池发送条目，并把它计入进行中的工作。
+func (sched *myScheduler) sendItem(item module.Item) bool {
+	sched.incrPending()
+	sched.drainer.add()
+	if !sendItem(item, sched.itemBufferPool) {
+		sched.drainer.done()
+		sched.decrPending()
+		return false
+	}
 	return true
 }
 
@@ -606,17 +989,48 @@ func sendItem(item module.Item, itemBufferPool buffer.Pool) bool {
 	return true
 }
 
+// initRequestArgs 用于按照给定的请求相关参数初始化相应的字段。
+func (sched *myScheduler) initRequestArgs(requestArgs RequestArgs) {
+	sched.requestArgs = requestArgs
+	sched.maxDepth = requestArgs.MaxDepth
+	logger.Infof("-- Max depth: %d", sched.maxDepth)
+	sched.retryPolicy = newRetryPolicy(requestArgs)
+	if sched.retryPolicy != nil {
+		logger.Infof("-- Retry policy: max attempts: %d, backoff: %s, max backoff: %s, max retry after: %s",
+			sched.retryPolicy.maxAttempts, sched.retryPolicy.backoff,
+			sched.retryPolicy.maxBackoff, sched.retryPolicy.maxRetryAfter)
+	}
+	sched.acceptedDomainMap, _ =
+		cmap.NewConcurrentMap(1, nil)
+	for _, domain := range requestArgs.AcceptedDomains {
+		if rule, err := normalizeDomainRule(domain); err == nil {
+			sched.acceptedDomainMap.Put(rule, struct{}{})
+		}
+	}
+	logger.Infof("-- Accepted primary domains: %v",
+		requestArgs.AcceptedDomains)
+	sched.filters, _ = newFilterChain(requestArgs)
+	logger.Infof("-- URL filters: include: %v, exclude: %v, max query params: %d, blocked extensions: %v",
+		requestArgs.IncludePatterns, requestArgs.ExcludePatterns,
+		requestArgs.MaxQueryParams, requestArgs.BlockedExtensions)
+	sched.hostLimiter = newHostLimiter(requestArgs.MaxURLsPerHost)
+	logger.Infof("-- Max URLs per host: %d", requestArgs.MaxURLsPerHost)
+	sched.canonicalizer = newCanonicalizer(requestArgs.IgnoredQueryParams)
+	logger.Infof("-- Ignored query parameters: %v",
+		requestArgs.IgnoredQueryParams)
+}
+
 // initBufferPool 用于按照给定的参数初始化缓冲池。
 // 如果某个缓冲池可用且未关闭，就先关闭该缓冲池。
 func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
-	// 初始化请求缓冲池。
-	if sched.reqBufferPool != nil && !sched.reqBufferPool.Closed() {
-		sched.reqBufferPool.Close()
-	}
-	sched.reqBufferPool, _ = buffer.NewPool(
-		dataArgs.ReqBufferCap, dataArgs.ReqMaxBufferNumber)
-	logger.Infof("-- Request buffer pool: bufferCap: %d, maxBufferNumber: %d",
-		sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+	// 初始化URL边界。
+	if sched.frontier != nil && !sched.frontier.Closed() {
+		sched.frontier.Close()
+	}
+	sched.frontier, _ = NewFrontier(dataArgs.FrontierType,
+		uint64(dataArgs.ReqBufferCap)*uint64(dataArgs.ReqMaxBufferNumber))
+	logger.Infof("-- Frontier: type: %s, cap: %d",
+		sched.frontier.Type(), sched.frontier.Cap())
 	// 初始化响应缓冲池。
 	if sched.respBufferPool != nil && !sched.respBufferPool.Closed() {
 		sched.respBufferPool.Close()
@@ -647,13 +1061,13 @@ func (sched *myScheduler) initBufferPool(dataArgs DataArgs) {
 // 如果某个缓冲池不可用，就直接返回错误值报告此情况。
 // 如果某个缓冲池已关闭，就按照原先的参数重新初始化它。
 func (sched *myScheduler) checkBufferPoolForStart() error {
-	// 检查请求缓冲池。
-	if sched.reqBufferPool == nil {
-		return genError("nil request buffer pool")
+	// 检查URL边界。
+	if sched.frontier == nil {
+		return genError("nil frontier")
 	}
-	if sched.reqBufferPool != nil && sched.reqBufferPool.Closed() {
-		sched.reqBufferPool, _ = buffer.NewPool(
-			sched.reqBufferPool.BufferCap(), sched.reqBufferPool.MaxBufferNumber())
+	if sched.frontier != nil && sched.frontier.Closed() {
+		sched.frontier, _ = NewFrontier(
+			sched.frontier.Type(), sched.frontier.Cap())
 	}
 	// 检查响应缓冲池。
 	if sched.respBufferPool == nil {
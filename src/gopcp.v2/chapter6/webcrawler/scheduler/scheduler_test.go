@@ -6,7 +6,6 @@ import (
 	"testing"
 	"time"
 
-	"gopcp.v2/chapter5/cmap"
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
 )
@@ -340,10 +339,10 @@ func TestSchedSendReq(t *testing.T) {
 			err)
 	}
 	mySched := sched.(*myScheduler)
-	urlMapLen := mySched.urlMap.Len()
-	if urlMapLen != 1 {
-		t.Fatalf("Inconsistent URL map length: expected: %d, actual: %d",
-			1, urlMapLen)
+	urlNumber := mySched.deduper.Len()
+	if urlNumber != 1 {
+		t.Fatalf("Inconsistent URL number: expected: %d, actual: %d",
+			1, urlNumber)
 	}
 	// 测试参数无效的情况。
 	if mySched.sendReq(nil) {
@@ -364,7 +363,13 @@ func TestSchedSendReq(t *testing.T) {
 	if mySched.sendReq(req) {
 		t.Fatalf("It still can send repeated request!")
 	}
-	mySched.urlMap, _ = cmap.NewConcurrentMap(16, nil)
+	// 测试URL仅在非规范部分上有区别的情况。
+	url = "HTTP://CN.bing.com:80/images/search?q=golang#top"
+	sameHTTPReq, _ := http.NewRequest("GET", url, nil)
+	if mySched.sendReq(module.NewRequest(sameHTTPReq, 0)) {
+		t.Fatalf("It still can send request with canonically repeated URL! (URL: %s)", url)
+	}
+	mySched.deduper = NewMapDeduper()
 	// 测试scheme不匹配的情况。
 	httpReq.URL.Scheme = "tcp"
 	if mySched.sendReq(req) {
@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// SeedScope 代表种子的爬取范围。
+type SeedScope string
+
+const (
+	// SEED_SCOPE_DOMAIN 代表种子的主域名。
+	// 主域名相同的URL都会被接受。这也是首次请求的爬取范围。
+	SEED_SCOPE_DOMAIN SeedScope = "domain"
+	// SEED_SCOPE_HOST 代表种子的主机。
+	// 只有主机（含端口）相同的URL才会被接受。
+	SEED_SCOPE_HOST SeedScope = "host"
+	// SEED_SCOPE_PREFIX 代表种子的路径前缀。
+	// 只有主机相同且路径以种子URL所在目录开头的URL才会被接受。
+	// 例如，种子“http://example.com/docs/index.html”的路径前缀为“/docs/”。
+	SEED_SCOPE_PREFIX SeedScope = "prefix"
+)
+
+// LegalSeedScope 用于判断给定的爬取范围是否合法。
+// 空字符串会被视为主域名范围。
+func LegalSeedScope(scope SeedScope) bool {
+	switch scope {
+	case "", SEED_SCOPE_DOMAIN, SEED_SCOPE_HOST, SEED_SCOPE_PREFIX:
+		return true
+	}
+	return false
+}
+
+// Seed 代表种子，即作为爬取起点的请求。
+type Seed struct {
+	// HTTPReq 代表种子的HTTP请求。
+	HTTPReq *http.Request
+	// Scope 代表种子的爬取范围。若为空则使用主域名范围。
+	// 主域名范围会与RequestArgs中的可接受主域名合并。
+	// 主机范围和路径前缀范围则只适用于由该种子（直接或间接）发现的请求，
+	// 这些请求不再受可接受主域名的约束。
+	Scope SeedScope
+	// Priority 代表种子请求的优先级。
+	Priority int
+}
+
+// NewSeed 用于创建一个具有给定爬取范围的种子。
+func NewSeed(httpReq *http.Request, scope SeedScope) Seed {
+	return Seed{HTTPReq: httpReq, Scope: scope}
+}
+
+// check 用于检查种子的有效性。
+func (seed Seed) check() error {
+	if seed.HTTPReq == nil {
+		return genParameterError("nil seed HTTP request")
+	}
+	if seed.HTTPReq.URL == nil {
+		return genParameterError("nil seed URL")
+	}
+	if !LegalSeedScope(seed.Scope) {
+		errMsg := fmt.Sprintf("illegal seed scope: %q (URL: %s)",
+			seed.Scope, seed.HTTPReq.URL)
+		return genParameterError(errMsg)
+	}
+	return nil
+}
+
+// ScopeRule 代表按主机或路径前缀限定的爬取范围的规则。
+type ScopeRule struct {
+	// Host 代表主机（含端口），已转为小写。
+	Host string `json:"host"`
+	// PathPrefix 代表路径前缀。若为空则代表该主机下的所有路径。
+	PathPrefix string `json:"path_prefix,omitempty"`
+}
+
+// newScopeRule 用于根据给定的URL和爬取范围生成规则。
+func newScopeRule(u *url.URL, scope SeedScope) ScopeRule {
+	rule := ScopeRule{Host: strings.ToLower(u.Host)}
+	if scope == SEED_SCOPE_PREFIX {
+		path := u.EscapedPath()
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			rule.PathPrefix = path[:i+1]
+		} else {
+			rule.PathPrefix = "/"
+		}
+	}
+	return rule
+}
+
+// parseScopeRule 用于解析由String方法生成的规则的字符串形式。
+func parseScopeRule(s string) ScopeRule {
+	if i := strings.Index(s, "/"); i >= 0 {
+		return ScopeRule{Host: s[:i], PathPrefix: s[i:]}
+	}
+	return ScopeRule{Host: s}
+}
+
+// String 用于获取规则的字符串形式，即主机与路径前缀的拼接。
+func (rule ScopeRule) String() string {
+	return rule.Host + rule.PathPrefix
+}
+
+// match 用于判断给定的URL是否符合规则。
+func (rule ScopeRule) match(u *url.URL) bool {
+	if strings.ToLower(u.Host) != rule.Host {
+		return false
+	}
+	if rule.PathPrefix == "" {
+		return true
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, rule.PathPrefix)
+}
+
+func (sched *myScheduler) AddSeeds(seeds ...Seed) error {
+	if len(seeds) == 0 {
+		return genParameterError("empty seed list")
+	}
+	for _, seed := range seeds {
+		if err := seed.check(); err != nil {
+			return err
+		}
+	}
+	sched.statusLock.Lock()
+	status := sched.status
+	if status != SCHED_STATUS_INITIALIZED &&
+		status != SCHED_STATUS_STARTED &&
+		status != SCHED_STATUS_PAUSED &&
+		status != SCHED_STATUS_STOPPED {
+		sched.statusLock.Unlock()
+		return genError(fmt.Sprintf("couldn't add seeds in status %q!",
+			GetStatusDescription(status)))
+	}
+	reqs := make([]*module.Request, 0, len(seeds))
+	for _, seed := range seeds {
+		req, err := sched.addSeedScope(seed)
+		if err != nil {
+			sched.statusLock.Unlock()
+			return err
+		}
+		reqs = append(reqs, req)
+	}
+	if status != SCHED_STATUS_STARTED && status != SCHED_STATUS_PAUSED {
+		// 调度器未启动时，种子请求会在启动时被放入。
+		sched.seedReqs = append(sched.seedReqs, reqs...)
+		sched.statusLock.Unlock()
+		return nil
+	}
+	sched.statusLock.Unlock()
+	for _, req := range reqs {
+		sched.sendReq(req)
+	}
+	return nil
+}
+
+// addSeedScope 用于设定给定种子的爬取范围，并返回由该种子生成的请求。
+// 主机范围和路径前缀范围的规则会以META_KEY_SCOPE为键存放在请求的元数据中，
+// 并由此传递给从该请求发现的子请求。
+func (sched *myScheduler) addSeedScope(seed Seed) (*module.Request, error) {
+	httpReq := seed.HTTPReq
+	logger.Infof("-- Seed: %s (scope: %s)", httpReq.URL, seed.Scope)
+	req := module.NewPriorityRequest(httpReq, 0, seed.Priority)
+	switch seed.Scope {
+	case SEED_SCOPE_HOST, SEED_SCOPE_PREFIX:
+		rule := newScopeRule(httpReq.URL, seed.Scope)
+		logger.Infof("-- Scope rule: %s", rule)
+		req = req.WithMeta(module.Meta{
+			Values: map[string]string{module.META_KEY_SCOPE: rule.String()},
+		})
+	default:
+		// 获得种子的主域名，并将其添加到可接受的主域名的字典。
+		primaryDomain, err := getPrimaryDomain(httpReq.Host)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("-- Primary domain: %s", primaryDomain)
+		sched.acceptedDomainMap.Put(primaryDomain, struct{}{})
+	}
+	return req, nil
+}
+
+// inScope 用于判断给定的请求是否在爬取范围之内。
+// 若请求携带了其种子的爬取范围，则只依据该范围判断，
+// 否则依据可接受的主域名判断。
+func (sched *myScheduler) inScope(req *module.Request) bool {
+	httpReq := req.HTTPReq()
+	if scope := req.Meta().Value(module.META_KEY_SCOPE); scope != "" {
+		return parseScopeRule(scope).match(httpReq.URL)
+	}
+	return matchDomainRules(sched.acceptedDomainMap, httpReq.Host)
+}
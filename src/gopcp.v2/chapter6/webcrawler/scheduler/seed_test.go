@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+func TestScopeRule(t *testing.T) {
+	seedURL, _ := url.Parse("http://Example.com:8080/docs/guide/index.html")
+	hostRule := newScopeRule(seedURL, SEED_SCOPE_HOST)
+	prefixRule := newScopeRule(seedURL, SEED_SCOPE_PREFIX)
+	expectedRules := []ScopeRule{
+		{Host: "example.com:8080"},
+		{Host: "example.com:8080", PathPrefix: "/docs/guide/"},
+	}
+	for i, rule := range []ScopeRule{hostRule, prefixRule} {
+		if rule != expectedRules[i] {
+			t.Fatalf("Inconsistent scope rule: expected: %#v, actual: %#v",
+				expectedRules[i], rule)
+		}
+		if parsed := parseScopeRule(rule.String()); parsed != rule {
+			t.Fatalf("Inconsistent parsed scope rule: expected: %#v, actual: %#v",
+				rule, parsed)
+		}
+	}
+	urlMap := map[string][2]bool{
+		"http://example.com:8080/docs/guide/a.html":     {true, true},
+		"http://EXAMPLE.com:8080/docs/guide/":           {true, true},
+		"https://example.com:8080/docs/guide/b.html":    {true, true},
+		"http://example.com:8080/docs/other.html":       {true, false},
+		"http://example.com:8080":                       {true, false},
+		"http://example.com/docs/guide/a.html":          {false, false},
+		"http://www.example.com:8080/docs/guide/a.html": {false, false},
+	}
+	for u, expected := range urlMap {
+		reqURL, _ := url.Parse(u)
+		for i, rule := range []ScopeRule{hostRule, prefixRule} {
+			if actual := rule.match(reqURL); actual != expected[i] {
+				t.Fatalf("Inconsistent matching result: expected: %v, actual: %v (rule: %#v, URL: %s)",
+					expected[i], actual, rule, u)
+			}
+		}
+	}
+}
+
+func TestSchedAddSeeds(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body></body></html>")
+	})
+	server1 := httptest.NewServer(handler)
+	defer server1.Close()
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+	sched := NewScheduler()
+	err := sched.Init(genRequestArgs([]string{}, 1), genDataArgs(10, 2, 1),
+		genSimpleModuleArgs(1, 1, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	// 测试非法的种子。
+	illegalSeeds := [][]Seed{
+		nil,
+		{{}},
+		{{HTTPReq: &http.Request{}}},
+	}
+	seedReq, _ := http.NewRequest("GET", server1.URL+"/docs/index.html", nil)
+	illegalSeeds = append(illegalSeeds, []Seed{NewSeed(seedReq, "path")})
+	for _, seeds := range illegalSeeds {
+		if err := sched.AddSeeds(seeds...); err == nil {
+			t.Fatalf("No error when adding illegal seeds: %#v", seeds)
+		}
+	}
+	// 在启动之前添加种子。
+	if err := sched.AddSeeds(NewSeed(seedReq, SEED_SCOPE_PREFIX)); err != nil {
+		t.Fatalf("An error occurs when adding seeds: %s", err)
+	}
+	mySched := sched.(*myScheduler)
+	// 种子的爬取范围应该被存放在种子请求的元数据中。
+	server1URL, _ := url.Parse(server1.URL)
+	server2URL, _ := url.Parse(server2.URL)
+	scope1 := server1URL.Host + "/docs/"
+	scope2 := server2URL.Host
+	if scope := mySched.seedReqs[0].Meta().Value(module.META_KEY_SCOPE); scope != scope1 {
+		t.Fatalf("Inconsistent seed scope: expected: %q, actual: %q", scope1, scope)
+	}
+	if err := sched.Start(); err != nil {
+		t.Fatalf("An error occurs when starting scheduler: %s", err)
+	}
+	defer sched.Stop()
+	if n := len(mySched.seedReqs); n != 0 {
+		t.Fatalf("Inconsistent seed request number: expected: %d, actual: %d", 0, n)
+	}
+	// 在运行期间添加种子。
+	anotherSeedReq, _ := http.NewRequest("GET", server2.URL+"/index.html", nil)
+	if err := sched.AddSeeds(NewSeed(anotherSeedReq, SEED_SCOPE_HOST)); err != nil {
+		t.Fatalf("An error occurs when adding seeds: %s", err)
+	}
+	// 每个请求都只依据其种子的爬取范围判断。
+	cases := []struct {
+		url      string
+		scope    string
+		expected bool
+	}{
+		{server1.URL + "/docs/index.html", scope1, false}, // 已被添加过。
+		{server1.URL + "/docs/a.html", scope1, true},
+		{server1.URL + "/blog/a.html", scope1, false},
+		{server2.URL + "/blog/b.html", scope1, false},
+		{server2.URL + "/index.html", scope2, false}, // 已被添加过。
+		{server2.URL + "/blog/a.html", scope2, true},
+		{server1.URL + "/docs/b.html", scope2, false},
+		// 未携带爬取范围的请求依据可接受的主域名判断。
+		{server1.URL + "/docs/c.html", "", false},
+	}
+	for _, c := range cases {
+		httpReq, _ := http.NewRequest("GET", c.url, nil)
+		req := module.NewRequest(httpReq, 1).WithMeta(module.Meta{
+			Values: map[string]string{module.META_KEY_SCOPE: c.scope},
+		})
+		if actual := mySched.sendReq(req); actual != c.expected {
+			t.Fatalf("Inconsistent sending result: expected: %v, actual: %v (URL: %s, scope: %q)",
+				c.expected, actual, c.url, c.scope)
+		}
+	}
+}
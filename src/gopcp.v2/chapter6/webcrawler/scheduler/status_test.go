@@ -90,6 +90,48 @@ func TestCheckStatus(t *testing.T) {
 		t.Fatalf("An error occurs when checking status: %s (currentStatus: %q, wantedStatus: %q)!",
 			err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
 	}
+	// 6. 只有处于已启动状态时才能变为正在暂停状态，
+	// 且只有处于已暂停状态时才能变为正在恢复状态。
+	statusMap := map[Status]Status{
+		SCHED_STATUS_STARTED: SCHED_STATUS_PAUSING,
+		SCHED_STATUS_PAUSED:  SCHED_STATUS_RESUMING,
+	}
+	currentStatusList = []Status{
+		SCHED_STATUS_UNINITIALIZED,
+		SCHED_STATUS_INITIALIZED,
+		SCHED_STATUS_STARTED,
+		SCHED_STATUS_STOPPED,
+		SCHED_STATUS_PAUSING,
+		SCHED_STATUS_PAUSED,
+		SCHED_STATUS_RESUMING,
+	}
+	for _, wantedStatus := range []Status{SCHED_STATUS_PAUSING, SCHED_STATUS_RESUMING} {
+		for _, currentStatus := range currentStatusList {
+			err := checkStatus(currentStatus, wantedStatus, nil)
+			if statusMap[currentStatus] == wantedStatus {
+				if err != nil {
+					t.Fatalf("An error occurs when checking status: %s (currentStatus: %q, wantedStatus: %q)!",
+						err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+				}
+			} else if err == nil {
+				t.Fatalf("It still can check status with current status %q wanted status %q!",
+					GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+			}
+		}
+	}
+	// 7. 处于已暂停状态时，不能变为正在初始化和正在启动状态，但可以变为正在停止状态。
+	currentStatus = SCHED_STATUS_PAUSED
+	for _, wantedStatus := range []Status{SCHED_STATUS_INITIALIZING, SCHED_STATUS_STARTING} {
+		if err := checkStatus(currentStatus, wantedStatus, nil); err == nil {
+			t.Fatalf("It still can check status with current status %q wanted status %q!",
+				GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+		}
+	}
+	wantedStatus = SCHED_STATUS_STOPPING
+	if err := checkStatus(currentStatus, wantedStatus, nil); err != nil {
+		t.Fatalf("An error occurs when checking status: %s (currentStatus: %q, wantedStatus: %q)!",
+			err, GetStatusDescription(currentStatus), GetStatusDescription(wantedStatus))
+	}
 }
 
 func TestCheckStatusInParallel(t *testing.T) {
@@ -156,7 +198,10 @@ func TestGetStatusDescription(t *testing.T) {
 		SCHED_STATUS_STARTED:       "started",
 		SCHED_STATUS_STOPPING:      "stopping",
 		SCHED_STATUS_STOPPED:       "stopped",
-		Status(7):                  "unknown",
+		SCHED_STATUS_PAUSING:       "pausing",
+		SCHED_STATUS_PAUSED:        "paused",
+		SCHED_STATUS_RESUMING:      "resuming",
+		Status(10):                 "unknown",
 	}
 	for status, expectedDesc := range statusMap {
 		desc := GetStatusDescription(status)
@@ -2,7 +2,9 @@ package scheduler
 
 import (
 	"encoding/json"
+	"reflect"
 	"sort"
+	"sync/atomic"
 
 	"gopcp.v2/chapter6/webcrawler/module"
 	"gopcp.v2/chapter6/webcrawler/toolkit/buffer"
@@ -56,11 +58,19 @@ type SummaryStruct struct {
 	Downloaders     []module.SummaryStruct  `json:"downloaders"`
 	Analyzers       []module.SummaryStruct  `json:"analyzers"`
 	Pipelines       []module.SummaryStruct  `json:"pipelines"`
-	ReqBufferPool   BufferPoolSummaryStruct `json:"request_buffer_pool"`
+	Frontier        FrontierSummaryStruct   `json:"frontier"`
 	RespBufferPool  BufferPoolSummaryStruct `json:"response_buffer_pool"`
 	ItemBufferPool  BufferPoolSummaryStruct `json:"item_buffer_pool"`
 	ErrorBufferPool BufferPoolSummaryStruct `json:"error_buffer_pool"`
 	NumURL          uint64                  `json:"url_number"`
+	Hosts           []HostSummaryStruct     `json:"hosts,omitempty"`
+	Rejected        map[string]uint64       `json:"rejected,omitempty"`
+	Retries         uint64                  `json:"retries,omitempty"`
+	Failures        uint64                  `json:"failures,omitempty"`
+	RecentErrors    []string                `json:"recent_errors,omitempty"`
+	DownloadWorkers WorkerSummaryStruct     `json:"download_workers"`
+	AnalyzeWorkers  WorkerSummaryStruct     `json:"analyze_workers"`
+	PickWorkers     WorkerSummaryStruct     `json:"pick_workers"`
 }
 
 // Same 用于判断当前的调度器摘要与另一份是否相同。
@@ -81,7 +91,8 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 		return false
 	}
 	for i, ds := range another.Downloaders {
-		if ds != one.Downloaders[i] {
+		// 组件的额外摘要信息可能是不可比较的类型。
+		if !reflect.DeepEqual(ds, one.Downloaders[i]) {
 			return false
 		}
 	}
@@ -89,7 +100,7 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 		return false
 	}
 	for i, as := range another.Analyzers {
-		if as != one.Analyzers[i] {
+		if !reflect.DeepEqual(as, one.Analyzers[i]) {
 			return false
 		}
 	}
@@ -97,11 +108,11 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 		return false
 	}
 	for i, ps := range another.Pipelines {
-		if ps != one.Pipelines[i] {
+		if !reflect.DeepEqual(ps, one.Pipelines[i]) {
 			return false
 		}
 	}
-	if another.ReqBufferPool != one.ReqBufferPool {
+	if another.Frontier != one.Frontier {
 		return false
 	}
 	if another.RespBufferPool != one.RespBufferPool {
@@ -116,11 +127,53 @@ func (one *SummaryStruct) Same(another SummaryStruct) bool {
 	if another.NumURL != one.NumURL {
 		return false
 	}
+	if len(another.Hosts) != len(one.Hosts) {
+		return false
+	}
+	for i, hs := range another.Hosts {
+		if hs != one.Hosts[i] {
+			return false
+		}
+	}
+	if another.Retries != one.Retries || another.Failures != one.Failures {
+		return false
+	}
+	if len(another.Rejected) != len(one.Rejected) {
+		return false
+	}
+	for reason, count := range another.Rejected {
+		if c, ok := one.Rejected[reason]; !ok || c != count {
+			return false
+		}
+	}
+	if !sameStrings(another.RecentErrors, one.RecentErrors) {
+		return false
+	}
+	if another.DownloadWorkers != one.DownloadWorkers ||
+		another.AnalyzeWorkers != one.AnalyzeWorkers ||
+		another.PickWorkers != one.PickWorkers {
+		return false
+	}
 	return true
 }
 
 func (ss *mySchedSummary) Struct() SummaryStruct {
 	registrar := ss.sched.registrar
+	var hosts []HostSummaryStruct
+	ss.sched.statusLock.RLock()
+	p := ss.sched.politeness
+	ss.sched.statusLock.RUnlock()
+	if p != nil {
+		hosts = p.summary()
+	}
+	var rejected map[string]uint64
+	if rc := ss.sched.rejected; rc != nil {
+		rejected = rc.snapshot()
+	}
+	var recentErrors []string
+	if er := ss.sched.recentErrors; er != nil {
+		recentErrors = er.snapshot()
+	}
 	return SummaryStruct{
 		RequestArgs:     ss.requestArgs,
 		DataArgs:        ss.dataArgs,
@@ -129,11 +182,19 @@ func (ss *mySchedSummary) Struct() SummaryStruct {
 		Downloaders:     getModuleSummaries(registrar, module.TYPE_DOWNLOADER),
 		Analyzers:       getModuleSummaries(registrar, module.TYPE_ANALYZER),
 		Pipelines:       getModuleSummaries(registrar, module.TYPE_PIPELINE),
-		ReqBufferPool:   getBufferPoolSummary(ss.sched.reqBufferPool),
+		Frontier:        getFrontierSummary(ss.sched.frontier),
 		RespBufferPool:  getBufferPoolSummary(ss.sched.respBufferPool),
 		ItemBufferPool:  getBufferPoolSummary(ss.sched.itemBufferPool),
 		ErrorBufferPool: getBufferPoolSummary(ss.sched.errorBufferPool),
-		NumURL:          ss.sched.urlMap.Len(),
+		NumURL:          ss.sched.deduper.Len(),
+		Hosts:           hosts,
+		Rejected:        rejected,
+		Retries:         atomic.LoadUint64(&ss.sched.retryCount),
+		Failures:        atomic.LoadUint64(&ss.sched.failureCount),
+		RecentErrors:    recentErrors,
+		DownloadWorkers: getWorkerSummary(ss.sched.downloadTickets),
+		AnalyzeWorkers:  getWorkerSummary(ss.sched.analyzeTickets),
+		PickWorkers:     getWorkerSummary(ss.sched.pickTickets),
 	}
 }
 
@@ -132,12 +132,12 @@ func TestSummaryStruct(t *testing.T) {
 	}
 	another.Pipelines = make([]module.SummaryStruct, len(one.Pipelines))
 	copy(another.Pipelines, one.Pipelines)
-	// 不同的请求缓冲池摘要。
-	another.ReqBufferPool.Total = 10
+	// 不同的URL边界摘要。
+	another.Frontier.Len = 10
 	if one.Same(another) {
-		t.Fatalf("Same scheduler summaries with different request buffer summary!")
+		t.Fatalf("Same scheduler summaries with different frontier summary!")
 	}
-	another.ReqBufferPool = one.ReqBufferPool
+	another.Frontier = one.Frontier
 	// 不同的响应缓冲池摘要。
 	another.RespBufferPool.Total = 11
 	if one.Same(another) {
@@ -162,6 +162,45 @@ func TestSummaryStruct(t *testing.T) {
 		t.Fatalf("Same scheduler summaries with different URL number!")
 	}
 	another.NumURL = one.NumURL
+	// 不同的主机摘要。
+	another.Hosts = []HostSummaryStruct{{Host: "cn.bing.com"}}
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different host summaries!")
+	}
+	one.Hosts = []HostSummaryStruct{{Host: "www.bing.com"}}
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different host summary!")
+	}
+	one.Hosts = nil
+	another.Hosts = nil
+	// 不同的重试次数和失败数量。
+	another.Retries = 3
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different retry count!")
+	}
+	another.Retries = one.Retries
+	another.Failures = 1
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different failure count!")
+	}
+	another.Failures = one.Failures
+	// 不同的被过滤请求的计数。
+	another.Rejected = map[string]uint64{rejectReasonRobots: 1}
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different rejected counts!")
+	}
+	one.Rejected = map[string]uint64{rejectReasonRobots: 2}
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different rejected count!")
+	}
+	one.Rejected = nil
+	another.Rejected = nil
+	// 不同的工作协程摘要。
+	another.AnalyzeWorkers.Active = 1
+	if one.Same(another) {
+		t.Fatalf("Same scheduler summaries with different worker summary!")
+	}
+	another.AnalyzeWorkers = one.AnalyzeWorkers
 	if !one.Same(another) {
 		t.Fatalf("Different scheduler summaries: one: %#v, another: %#v",
 			one, another)
@@ -225,14 +264,22 @@ func TestSummaryString(t *testing.T) {
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "extra": {
+                "route_number": 1,
+                "unmatched": 0
+            }
         },
         {
             "id": "A4",
             "called": 0,
             "accepted": 0,
             "completed": 0,
-            "handling": 0
+            "handling": 0,
+            "extra": {
+                "route_number": 1,
+                "unmatched": 0
+            }
         }
     ],
     "pipelines": [
@@ -248,11 +295,10 @@ func TestSummaryString(t *testing.T) {
             }
         }
     ],
-    "request_buffer_pool": {
-        "buffer_cap": 10,
-        "max_buffer_number": 2,
-        "buffer_number": 1,
-        "total": 0
+    "frontier": {
+        "type": "bfs",
+        "cap": 20,
+        "len": 0
     },
     "response_buffer_pool": {
         "buffer_cap": 10,
@@ -272,7 +318,19 @@ func TestSummaryString(t *testing.T) {
         "buffer_number": 1,
         "total": 0
     },
-    "url_number": 0
+    "url_number": 0,
+    "download_workers": {
+        "total": 1,
+        "active": 0
+    },
+    "analyze_workers": {
+        "total": 1,
+        "active": 0
+    },
+    "pick_workers": {
+        "total": 1,
+        "active": 0
+    }
 }`
 	summaryStr := summary.String()
 	if summaryStr != expectedSummaryStr {
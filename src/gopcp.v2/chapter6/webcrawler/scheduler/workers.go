@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"gopcp.v2/chapter4/loadgen/lib"
+	"gopcp.v2/chapter6/webcrawler/module"
+)
+
+// defaultWorkerNumber 代表各个处理阶段的默认工作协程数量。
+const defaultWorkerNumber uint32 = 1
+
+// newWorkerTickets 用于创建某个处理阶段的工作协程票池。
+// 若参数number为0，则使用默认数量。
+func newWorkerTickets(number uint32) lib.GoTickets {
+	if number == 0 {
+		number = defaultWorkerNumber
+	}
+	tickets, _ := lib.NewGoTickets(number)
+	return tickets
+}
+
+// initWorkers 用于按照给定的参数初始化各个处理阶段的工作协程票池。
+func (sched *myScheduler) initWorkers(dataArgs DataArgs) {
+	sched.downloadTickets = newWorkerTickets(dataArgs.DownloadWorkers)
+	sched.analyzeTickets = newWorkerTickets(dataArgs.AnalyzeWorkers)
+	sched.pickTickets = newWorkerTickets(dataArgs.PickWorkers)
+	logger.Infof("-- Workers: download: %d, analyze: %d, pick: %d",
+		sched.downloadTickets.Total(),
+		sched.analyzeTickets.Total(),
+		sched.pickTickets.Total())
+}
+
+// goWithTicket 会在从给定票池中拿到一张票之后异步地执行给定的函数，
+// 并在执行完毕之后归还这张票。
+// 若票池中已无票可拿，则该方法会阻塞，直到有票被归还。
+func goWithTicket(tickets lib.GoTickets, f func()) {
+	tickets.Take()
+	go func() {
+		defer tickets.Return()
+		f()
+	}()
+}
+
+// downloadWithTicket 会在拿到下载阶段的票之后同步地下载给定的请求。
+// 它用于由礼貌爬取的控制器分派的请求。
+func (sched *myScheduler) downloadWithTicket(req *module.Request) {
+	sched.downloadTickets.Take()
+	defer sched.downloadTickets.Return()
+	sched.downloadOne(req)
+}
+
+// WorkerSummaryStruct 代表某个处理阶段的工作协程的摘要类型。
+type WorkerSummaryStruct struct {
+	// Total 代表工作协程的最大数量。
+	Total uint32 `json:"total"`
+	// Active 代表正在工作的协程的数量。
+	Active uint32 `json:"active"`
+}
+
+// getWorkerSummary 用于生成和返回某个处理阶段的工作协程的摘要信息。
+func getWorkerSummary(tickets lib.GoTickets) WorkerSummaryStruct {
+	if tickets == nil {
+		return WorkerSummaryStruct{}
+	}
+	return WorkerSummaryStruct{
+		Total:  tickets.Total(),
+		Active: tickets.Total() - tickets.Remainder(),
+	}
+}
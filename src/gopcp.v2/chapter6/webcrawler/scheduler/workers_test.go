@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedWorkers(t *testing.T) {
+	releaseCh := make(chan struct{})
+	var concurrency, maxConcurrency int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>")
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "<a href=\"/%d.html\">link</a>", i)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&concurrency, 1)
+		defer atomic.AddInt64(&concurrency, -1)
+		for {
+			max := atomic.LoadInt64(&maxConcurrency)
+			if n <= max || atomic.CompareAndSwapInt64(&maxConcurrency, max, n) {
+				break
+			}
+		}
+		<-releaseCh
+		fmt.Fprint(w, "<html><body></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	dataArgs := genDataArgs(10, 2, 1)
+	dataArgs.DownloadWorkers = 3
+	dataArgs.AnalyzeWorkers = 2
+	sched := &myScheduler{}
+	err := sched.Init(genRequestArgs([]string{serverURL.Host}, 1),
+		dataArgs, genSimpleModuleArgs(3, 2, 1, t))
+	if err != nil {
+		t.Fatalf("An error occurs when initializing scheduler: %s", err)
+	}
+	summary := sched.Summary().Struct()
+	expectedWorkers := map[string][2]WorkerSummaryStruct{
+		"download": {{Total: 3}, summary.DownloadWorkers},
+		"analyze":  {{Total: 2}, summary.AnalyzeWorkers},
+		"pick":     {{Total: defaultWorkerNumber}, summary.PickWorkers},
+	}
+	for stage, pair := range expectedWorkers {
+		if pair[0] != pair[1] {
+			t.Fatalf("Inconsistent worker summary: expected: %#v, actual: %#v (stage: %s)",
+				pair[0], pair[1], stage)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	firstHTTPReq, _ := http.NewRequest("GET", server.URL+"/index.html", nil)
+	resultCh := make(chan error, 1)
+	go func() {
+		_, err := sched.Run(ctx, firstHTTPReq)
+		resultCh <- err
+	}()
+	waitFor(t, func() bool {
+		return atomic.LoadInt64(&concurrency) == 3
+	})
+	// 下载阶段的工作协程都在工作时，不会再有更多的下载。
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt64(&maxConcurrency); n != 3 {
+		t.Fatalf("Inconsistent max concurrency: expected: %d, actual: %d", 3, n)
+	}
+	if active := sched.Summary().Struct().DownloadWorkers.Active; active != 3 {
+		t.Fatalf("Inconsistent active download worker number: expected: %d, actual: %d",
+			3, active)
+	}
+	close(releaseCh)
+	if err := <-resultCh; err != nil {
+		t.Fatalf("An error occurs when running scheduler: %s", err)
+	}
+	if n := atomic.LoadInt64(&maxConcurrency); n != 3 {
+		t.Fatalf("Inconsistent max concurrency: expected: %d, actual: %d", 3, n)
+	}
+}
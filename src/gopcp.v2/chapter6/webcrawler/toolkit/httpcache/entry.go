@@ -0,0 +1,255 @@
+package httpcache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HEADER_FROM_CACHE 代表由缓存提供的响应中的标记头。
+// 其值为“fresh”或“revalidated”，分别代表缓存是新鲜的和已经过服务端验证的。
+const HEADER_FROM_CACHE = "X-From-Cache"
+
+// 缓存响应的来源。
+const (
+	// FROM_CACHE_FRESH 代表响应由新鲜的缓存直接提供，没有请求服务端。
+	FROM_CACHE_FRESH = "fresh"
+	// FROM_CACHE_REVALIDATED 代表服务端返回了304状态码，响应由缓存提供。
+	FROM_CACHE_REVALIDATED = "revalidated"
+)
+
+// heuristicFraction 代表根据Last-Modified启发式地计算新鲜期时使用的比例。
+const heuristicFraction = 10
+
+// maxHeuristicLifetime 代表启发式计算的新鲜期的上限。
+const maxHeuristicLifetime = 24 * time.Hour
+
+// Entry 代表缓存条目。
+type Entry struct {
+	// URL 代表请求的URL。
+	URL string `json:"url"`
+	// StatusCode 代表响应的状态码。
+	StatusCode int `json:"status_code"`
+	// Header 代表响应头。
+	Header http.Header `json:"header"`
+	// Body 代表响应体。
+	Body []byte `json:"body"`
+	// StoredAt 代表条目被存储或被重新验证的时间。
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// Key 用于生成给定请求对应的缓存键。
+func Key(httpReq *http.Request) string {
+	return httpReq.Method + " " + httpReq.URL.String()
+}
+
+// NewEntry 用于根据响应和响应体创建缓存条目。
+func NewEntry(httpResp *http.Response, body []byte, storedAt time.Time) *Entry {
+	return &Entry{
+		URL:        httpResp.Request.URL.String(),
+		StatusCode: httpResp.StatusCode,
+		Header:     cloneHeader(httpResp.Header),
+		Body:       body,
+		StoredAt:   storedAt,
+	}
+}
+
+// Cacheable 用于判断给定的响应是否可以被缓存。
+// 只有GET请求的、状态码为200的、未禁止存储且不依赖于请求头（Vary）的响应才可以被缓存，
+// 并且它必须带有验证器（ETag或Last-Modified）或者显式的新鲜期。
+func Cacheable(httpResp *http.Response) bool {
+	if httpResp == nil || httpResp.Request == nil ||
+		httpResp.Request.Method != "GET" || httpResp.StatusCode != http.StatusOK {
+		return false
+	}
+	reqCC := parseCacheControl(httpResp.Request.Header)
+	respCC := parseCacheControl(httpResp.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return false
+	}
+	if _, ok := respCC["no-store"]; ok {
+		return false
+	}
+	if _, ok := respCC["private"]; ok {
+		return false
+	}
+	if httpResp.Header.Get("Vary") != "" {
+		return false
+	}
+	if httpResp.Header.Get("ETag") != "" || httpResp.Header.Get("Last-Modified") != "" {
+		return true
+	}
+	if _, ok := respCC["max-age"]; ok {
+		return true
+	}
+	return httpResp.Header.Get("Expires") != ""
+}
+
+// Fresh 用于判断该条目对于给定的请求在给定的时间是否是新鲜的，即是否可以不经验证直接使用。
+func (entry *Entry) Fresh(httpReq *http.Request, now time.Time) bool {
+	reqCC := parseCacheControl(httpReq.Header)
+	if _, ok := reqCC["no-cache"]; ok {
+		return false
+	}
+	respCC := parseCacheControl(entry.Header)
+	if _, ok := respCC["no-cache"]; ok {
+		return false
+	}
+	lifetime := entry.lifetime(respCC)
+	if maxAge, ok := parseSeconds(reqCC, "max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	return entry.age(now) < lifetime
+}
+
+// lifetime 用于计算该条目的新鲜期。
+func (entry *Entry) lifetime(respCC map[string]string) time.Duration {
+	if maxAge, ok := parseSeconds(respCC, "max-age"); ok {
+		return maxAge
+	}
+	date := entry.date()
+	if expires := entry.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			// 非法的Expires代表已过期。
+			return 0
+		}
+		return t.Sub(date)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		t, err := http.ParseTime(lastModified)
+		if err != nil || !t.Before(date) {
+			return 0
+		}
+		lifetime := date.Sub(t) / heuristicFraction
+		if lifetime > maxHeuristicLifetime {
+			lifetime = maxHeuristicLifetime
+		}
+		return lifetime
+	}
+	return 0
+}
+
+// date 用于获取响应的生成时间。若响应头中没有，则使用条目被存储的时间。
+func (entry *Entry) date() time.Time {
+	if date := entry.Header.Get("Date"); date != "" {
+		if t, err := http.ParseTime(date); err == nil {
+			return t
+		}
+	}
+	return entry.StoredAt
+}
+
+// age 用于计算该条目在给定时间的年龄。
+func (entry *Entry) age(now time.Time) time.Duration {
+	var age time.Duration
+	if v := entry.Header.Get("Age"); v != "" {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil && seconds > 0 {
+			age = time.Duration(seconds) * time.Second
+		}
+	}
+	if elapsed := now.Sub(entry.StoredAt); elapsed > 0 {
+		age += elapsed
+	}
+	return age
+}
+
+// SetConditional 用于在给定的请求中设置用于验证该条目的条件请求头。
+// 若该条目没有验证器，则返回false。
+func (entry *Entry) SetConditional(httpReq *http.Request) bool {
+	var set bool
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		httpReq.Header.Set("If-None-Match", etag)
+		set = true
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", lastModified)
+		set = true
+	}
+	return set
+}
+
+// Revalidated 用于根据状态码为304的响应生成更新后的条目。
+// 响应中的头会覆盖条目中的同名头，但内容相关的头除外。
+func (entry *Entry) Revalidated(notModified *http.Response, now time.Time) *Entry {
+	header := cloneHeader(entry.Header)
+	for k, v := range notModified.Header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Content-Length", "Content-Encoding", "Content-Type", "Transfer-Encoding":
+			continue
+		}
+		header[k] = append([]string(nil), v...)
+	}
+	// 更新后的年龄从现在开始计算。
+	header.Del("Age")
+	return &Entry{
+		URL:        entry.URL,
+		StatusCode: entry.StatusCode,
+		Header:     header,
+		Body:       entry.Body,
+		StoredAt:   now,
+	}
+}
+
+// Response 用于根据该条目为给定的请求生成响应。
+// 参数from代表响应的来源，会被放在HEADER_FROM_CACHE头中。
+func (entry *Entry) Response(httpReq *http.Request, from string) *http.Response {
+	header := cloneHeader(entry.Header)
+	header.Set(HEADER_FROM_CACHE, from)
+	return &http.Response{
+		Status:        strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       httpReq,
+	}
+}
+
+// parseCacheControl 用于解析Cache-Control头，并返回指令名称与值的映射。
+func parseCacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, line := range header["Cache-Control"] {
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, value := part, ""
+			if i := strings.Index(part, "="); i >= 0 {
+				name = strings.TrimSpace(part[:i])
+				value = strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+			}
+			directives[strings.ToLower(name)] = value
+		}
+	}
+	return directives
+}
+
+// parseSeconds 用于获取给定指令中以秒为单位的时长。
+func parseSeconds(directives map[string]string, name string) (time.Duration, bool) {
+	value, ok := directives[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, true
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// cloneHeader 用于复制给定的头。
+func cloneHeader(header http.Header) http.Header {
+	cloned := make(http.Header, len(header))
+	for k, v := range header {
+		cloned[k] = append([]string(nil), v...)
+	}
+	return cloned
+}
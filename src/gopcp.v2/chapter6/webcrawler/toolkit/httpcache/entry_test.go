@@ -0,0 +1,144 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheable(t *testing.T) {
+	cases := []struct {
+		method   string
+		code     int
+		reqCC    string
+		header   http.Header
+		expected bool
+	}{
+		{"GET", 200, "", http.Header{"Etag": {`"v1"`}}, true},
+		{"GET", 200, "", http.Header{"Last-Modified": {"Mon, 02 Jan 2017 15:04:05 GMT"}}, true},
+		{"GET", 200, "", http.Header{"Cache-Control": {"public, max-age=60"}}, true},
+		{"GET", 200, "", http.Header{"Expires": {"Mon, 02 Jan 2017 15:04:05 GMT"}}, true},
+		{"GET", 200, "", http.Header{}, false},
+		{"POST", 200, "", http.Header{"Etag": {`"v1"`}}, false},
+		{"GET", 404, "", http.Header{"Etag": {`"v1"`}}, false},
+		{"GET", 200, "no-store", http.Header{"Etag": {`"v1"`}}, false},
+		{"GET", 200, "", http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-store"}}, false},
+		{"GET", 200, "", http.Header{"Etag": {`"v1"`}, "Cache-Control": {"Private"}}, false},
+		{"GET", 200, "", http.Header{"Etag": {`"v1"`}, "Vary": {"Accept-Encoding"}}, false},
+	}
+	for i, c := range cases {
+		httpReq, _ := http.NewRequest(c.method, "http://example.com/", nil)
+		if c.reqCC != "" {
+			httpReq.Header.Set("Cache-Control", c.reqCC)
+		}
+		httpResp := &http.Response{StatusCode: c.code, Header: c.header, Request: httpReq}
+		if actual := Cacheable(httpResp); actual != c.expected {
+			t.Fatalf("Inconsistent cacheable: expected: %v, actual: %v (index: %d)",
+				c.expected, actual, i)
+		}
+	}
+	if Cacheable(nil) {
+		t.Fatalf("Inconsistent cacheable: expected: %v, actual: %v", false, true)
+	}
+}
+
+func TestEntryFresh(t *testing.T) {
+	now := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+	date := now.Format(http.TimeFormat)
+	cases := []struct {
+		header   http.Header
+		reqCC    string
+		storedAt time.Time
+		expected bool
+	}{
+		{http.Header{"Cache-Control": {"max-age=60"}}, "", now.Add(-59 * time.Second), true},
+		{http.Header{"Cache-Control": {"max-age=60"}}, "", now.Add(-60 * time.Second), false},
+		{http.Header{"Cache-Control": {"max-age=60"}, "Age": {"30"}},
+			"", now.Add(-40 * time.Second), false},
+		{http.Header{"Cache-Control": {"max-age=60"}}, "no-cache", now, false},
+		{http.Header{"Cache-Control": {"max-age=60"}}, "max-age=10", now.Add(-20 * time.Second), false},
+		{http.Header{"Cache-Control": {"no-cache, max-age=60"}}, "", now, false},
+		{http.Header{"Date": {date},
+			"Expires": {now.Add(time.Hour).Format(http.TimeFormat)}}, "", now, true},
+		{http.Header{"Date": {date}, "Expires": {"0"}}, "", now, false},
+		// 启发式新鲜期：(Date - Last-Modified) / 10，即1小时。
+		{http.Header{"Date": {date},
+			"Last-Modified": {now.Add(-10 * time.Hour).Format(http.TimeFormat)}},
+			"", now.Add(-59 * time.Minute), true},
+		{http.Header{"Date": {date},
+			"Last-Modified": {now.Add(-10 * time.Hour).Format(http.TimeFormat)}},
+			"", now.Add(-61 * time.Minute), false},
+		{http.Header{"Etag": {`"v1"`}}, "", now, false},
+	}
+	for i, c := range cases {
+		entry := &Entry{StatusCode: 200, Header: c.header, StoredAt: c.storedAt}
+		httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+		if c.reqCC != "" {
+			httpReq.Header.Set("Cache-Control", c.reqCC)
+		}
+		if actual := entry.Fresh(httpReq, now); actual != c.expected {
+			t.Fatalf("Inconsistent freshness: expected: %v, actual: %v (index: %d)",
+				c.expected, actual, i)
+		}
+	}
+}
+
+func TestEntryRevalidate(t *testing.T) {
+	storedAt := time.Now().Add(-time.Hour)
+	entry := &Entry{
+		URL:        "http://example.com/",
+		StatusCode: 200,
+		Header: http.Header{
+			"Etag":          {`"v1"`},
+			"Last-Modified": {"Mon, 02 Jan 2017 15:04:05 GMT"},
+			"Content-Type":  {"text/html"},
+			"Age":           {"100"},
+		},
+		Body:     []byte("content"),
+		StoredAt: storedAt,
+	}
+	httpReq, _ := http.NewRequest("GET", entry.URL, nil)
+	if !entry.SetConditional(httpReq) {
+		t.Fatalf("Couldn't set conditional headers!")
+	}
+	if httpReq.Header.Get("If-None-Match") != `"v1"` ||
+		httpReq.Header.Get("If-Modified-Since") != "Mon, 02 Jan 2017 15:04:05 GMT" {
+		t.Fatalf("Inconsistent conditional headers: %v", httpReq.Header)
+	}
+	notModified := &http.Response{
+		StatusCode: http.StatusNotModified,
+		Header: http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Content-Type":  {"text/plain"},
+		},
+	}
+	now := time.Now()
+	updated := entry.Revalidated(notModified, now)
+	if updated.StoredAt != now || string(updated.Body) != "content" {
+		t.Fatalf("Inconsistent updated entry: %#v", updated)
+	}
+	if updated.Header.Get("Cache-Control") != "max-age=60" ||
+		updated.Header.Get("Content-Type") != "text/html" ||
+		updated.Header.Get("Age") != "" {
+		t.Fatalf("Inconsistent updated header: %v", updated.Header)
+	}
+	if entry.Header.Get("Cache-Control") != "" {
+		t.Fatalf("The original entry has been modified: %v", entry.Header)
+	}
+	resp := updated.Response(httpReq, FROM_CACHE_REVALIDATED)
+	if resp.StatusCode != 200 || resp.Header.Get(HEADER_FROM_CACHE) != FROM_CACHE_REVALIDATED ||
+		resp.Request != httpReq {
+		t.Fatalf("Inconsistent response: %#v", resp)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "content" {
+		t.Fatalf("Inconsistent body: expected: %q, actual: %q", "content", body)
+	}
+	// 没有验证器的条目。
+	entry = &Entry{Header: http.Header{}}
+	httpReq, _ = http.NewRequest("GET", "http://example.com/", nil)
+	if entry.SetConditional(httpReq) {
+		t.Fatalf("Set conditional headers without validators!")
+	}
+}
@@ -0,0 +1,136 @@
+package httpcache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store 代表缓存存储的接口类型。
+// 该接口的实现类型必须是并发安全的！
+type Store interface {
+	// Get 用于获取给定键对应的缓存条目。若不存在，则返回nil和nil。
+	Get(key string) (*Entry, error)
+	// Set 用于存储缓存条目。
+	Set(key string, entry *Entry) error
+	// Delete 用于删除给定键对应的缓存条目。
+	Delete(key string) error
+}
+
+// NewMemoryStore 用于创建一个在内存中存储缓存条目的缓存存储。
+func NewMemoryStore() Store {
+	return &memoryStore{entries: map[string]*Entry{}}
+}
+
+// memoryStore 代表在内存中存储缓存条目的缓存存储的实现类型。
+type memoryStore struct {
+	// lock 代表保护条目字典的读写锁。
+	lock sync.RWMutex
+	// entries 代表键与缓存条目的映射。
+	entries map[string]*Entry
+}
+
+func (ms *memoryStore) Get(key string) (*Entry, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	return ms.entries[key], nil
+}
+
+func (ms *memoryStore) Set(key string, entry *Entry) error {
+	if entry == nil {
+		return fmt.Errorf("nil cache entry")
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.entries[key] = entry
+	return nil
+}
+
+func (ms *memoryStore) Delete(key string) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	delete(ms.entries, key)
+	return nil
+}
+
+// NewDiskStore 用于创建一个在给定目录中存储缓存条目的缓存存储。
+// 每个缓存条目会被存储为一个以其键的SHA-1摘要命名的JSON文件。
+func NewDiskStore(dirPath string) (Store, error) {
+	if dirPath == "" {
+		return nil, fmt.Errorf("empty directory path")
+	}
+	absDirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDirPath, 0755); err != nil {
+		return nil, err
+	}
+	return &diskStore{dirPath: absDirPath}, nil
+}
+
+// diskStore 代表在磁盘上存储缓存条目的缓存存储的实现类型。
+type diskStore struct {
+	// dirPath 代表存储缓存条目的目录的绝对路径。
+	dirPath string
+}
+
+// path 用于获取给定键对应的文件路径。
+func (ds *diskStore) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(ds.dirPath, hex.EncodeToString(sum[:])+".json")
+}
+
+func (ds *diskStore) Get(key string) (*Entry, error) {
+	data, err := ioutil.ReadFile(ds.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("couldn't decode cache entry: %s (key: %s)", err, key)
+	}
+	return &entry, nil
+}
+
+// Set 会先把条目写入临时文件再重命名，以保证其他读取者不会读到不完整的条目。
+func (ds *diskStore) Set(key string, entry *Entry) error {
+	if entry == nil {
+		return fmt.Errorf("nil cache entry")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(ds.dirPath, "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), ds.path(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func (ds *diskStore) Delete(key string) error {
+	err := os.Remove(ds.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package httpcache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatalf("An error occurs when creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dirPath)
+	diskStore, err := NewDiskStore(dirPath)
+	if err != nil {
+		t.Fatalf("An error occurs when creating disk store: %s", err)
+	}
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"disk":   diskStore,
+	}
+	for name, store := range stores {
+		key := "GET http://example.com/"
+		entry, err := store.Get(key)
+		if err != nil || entry != nil {
+			t.Fatalf("Inconsistent entry: expected: %v, actual: %v, error: %v (store: %s)",
+				nil, entry, err, name)
+		}
+		expected := &Entry{
+			URL:        "http://example.com/",
+			StatusCode: 200,
+			Header:     http.Header{"Etag": {`"v1"`}},
+			Body:       []byte("content"),
+			StoredAt:   time.Now().Round(0),
+		}
+		if err := store.Set(key, expected); err != nil {
+			t.Fatalf("An error occurs when storing entry: %s (store: %s)", err, name)
+		}
+		entry, err = store.Get(key)
+		if err != nil || entry == nil {
+			t.Fatalf("Couldn't get entry: %v (store: %s)", err, name)
+		}
+		if entry.URL != expected.URL || entry.StatusCode != expected.StatusCode ||
+			entry.Header.Get("ETag") != `"v1"` || string(entry.Body) != "content" ||
+			!entry.StoredAt.Equal(expected.StoredAt) {
+			t.Fatalf("Inconsistent entry: expected: %#v, actual: %#v (store: %s)",
+				expected, entry, name)
+		}
+		if err := store.Set(key, nil); err == nil {
+			t.Fatalf("No error when storing nil entry! (store: %s)", name)
+		}
+		if err := store.Delete(key); err != nil {
+			t.Fatalf("An error occurs when deleting entry: %s (store: %s)", err, name)
+		}
+		if err := store.Delete(key); err != nil {
+			t.Fatalf("An error occurs when deleting entry twice: %s (store: %s)", err, name)
+		}
+		if entry, _ := store.Get(key); entry != nil {
+			t.Fatalf("The entry still exists after deleting! (store: %s)", name)
+		}
+	}
+	if _, err := NewDiskStore(""); err == nil {
+		t.Fatalf("No error when creating disk store with empty directory path!")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"sync"
 )
 
 // MultipleReader 代表多重读取器的接口。
@@ -12,6 +14,11 @@ type MultipleReader interface {
 	// Reader 用于获取一个可关闭读取器的实例。
 	// 后者会持有本多重读取器中的数据。
 	Reader() io.ReadCloser
+	// Size 用于获取本多重读取器中的数据的字节数。
+	Size() int64
+	// Close 用于释放本多重读取器占用的资源，如临时文件。
+	// 在此之前获取的读取器仍然可用，但在此之后不能再获取新的读取器。
+	Close() error
 }
 
 // myMultipleReader 代表多重读取器的实现类型。
@@ -20,6 +27,7 @@ type myMultipleReader struct {
 }
 
 // NewMultipleReader 用于新建并返回一个多重读取器的实例。
+// 它会把所有数据都读入内存。
 func NewMultipleReader(reader io.Reader) (MultipleReader, error) {
 	var data []byte
 	var err error
@@ -39,3 +47,95 @@ func NewMultipleReader(reader io.Reader) (MultipleReader, error) {
 func (rr *myMultipleReader) Reader() io.ReadCloser {
 	return ioutil.NopCloser(bytes.NewReader(rr.data))
 }
+
+func (rr *myMultipleReader) Size() int64 {
+	return int64(len(rr.data))
+}
+
+func (rr *myMultipleReader) Close() error {
+	return nil
+}
+
+// NewMultipleReaderWithLimit 用于新建并返回一个限制内存占用的多重读取器的实例。
+// 若数据的字节数不超过参数memLimit，则数据会被读入内存，
+// 否则数据会被写入临时文件，且每个读取器都会单独打开该文件。
+// 参数memLimit小于0时代表不限制内存占用，与NewMultipleReader相同。
+func NewMultipleReaderWithLimit(reader io.Reader, memLimit int64) (MultipleReader, error) {
+	if reader == nil || memLimit < 0 {
+		return NewMultipleReader(reader)
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, reader, memLimit+1)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("multiple reader: couldn't create a new one: %s", err)
+	}
+	if n <= memLimit {
+		return &myMultipleReader{data: buf.Bytes()}, nil
+	}
+	file, err := ioutil.TempFile("", "webcrawler-reader-")
+	if err != nil {
+		return nil, fmt.Errorf("multiple reader: couldn't create temp file: %s", err)
+	}
+	size, err := io.Copy(file, io.MultiReader(&buf, reader))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("multiple reader: couldn't write temp file: %s", err)
+	}
+	return &fileMultipleReader{path: file.Name(), size: size}, nil
+}
+
+// fileMultipleReader 代表以临时文件存储数据的多重读取器的实现类型。
+type fileMultipleReader struct {
+	// lock 代表保护关闭状态的互斥锁。
+	lock sync.Mutex
+	// path 代表临时文件的路径。
+	path string
+	// size 代表数据的字节数。
+	size int64
+	// closed 代表是否已关闭。
+	closed bool
+}
+
+func (fr *fileMultipleReader) Reader() io.ReadCloser {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	if fr.closed {
+		return ioutil.NopCloser(&errReader{
+			err: fmt.Errorf("multiple reader: already closed"),
+		})
+	}
+	file, err := os.Open(fr.path)
+	if err != nil {
+		return ioutil.NopCloser(&errReader{
+			err: fmt.Errorf("multiple reader: couldn't open temp file: %s", err),
+		})
+	}
+	return file
+}
+
+func (fr *fileMultipleReader) Size() int64 {
+	return fr.size
+}
+
+// Close 会删除临时文件。已经打开的读取器在被关闭之前仍然可以读取数据。
+func (fr *fileMultipleReader) Close() error {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	if fr.closed {
+		return nil
+	}
+	fr.closed = true
+	return os.Remove(fr.path)
+}
+
+// errReader 代表总是返回给定错误的读取器。
+type errReader struct {
+	err error
+}
+
+func (er *errReader) Read(p []byte) (int, error) {
+	return 0, er.err
+}
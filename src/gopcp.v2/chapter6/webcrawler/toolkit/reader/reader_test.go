@@ -3,6 +3,8 @@ package reader
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 )
@@ -29,3 +31,60 @@ func TestReaderNew(t *testing.T) {
 			expectedData, content2)
 	}
 }
+
+func TestReaderWithLimit(t *testing.T) {
+	expectedData := "0987dcba"
+	// 数据未超过内存占用上限。
+	rr, err := NewMultipleReaderWithLimit(strings.NewReader(expectedData), 8)
+	if err != nil {
+		t.Fatalf("An error occurs when new multiple reader: %s", err)
+	}
+	if _, ok := rr.(*myMultipleReader); !ok {
+		t.Fatalf("Inconsistent multiple reader type: expected: %T, actual: %T",
+			&myMultipleReader{}, rr)
+	}
+	// 数据超过了内存占用上限。
+	rr, err = NewMultipleReaderWithLimit(strings.NewReader(expectedData), 3)
+	if err != nil {
+		t.Fatalf("An error occurs when new multiple reader: %s", err)
+	}
+	fr, ok := rr.(*fileMultipleReader)
+	if !ok {
+		t.Fatalf("Inconsistent multiple reader type: expected: %T, actual: %T",
+			&fileMultipleReader{}, rr)
+	}
+	if rr.Size() != int64(len(expectedData)) {
+		t.Fatalf("Inconsistent data size: expected: %d, actual: %d",
+			len(expectedData), rr.Size())
+	}
+	readers := []io.ReadCloser{rr.Reader(), rr.Reader()}
+	if err := rr.Close(); err != nil {
+		t.Fatalf("An error occurs when closing multiple reader: %s", err)
+	}
+	if _, err := os.Stat(fr.path); !os.IsNotExist(err) {
+		t.Fatalf("The temp file %s still exists after closing!", fr.path)
+	}
+	// 关闭之前获取的读取器仍然可用。
+	for i, r := range readers {
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("An error occurs when reading data: %s (index: %d)", err, i)
+		}
+		if string(data) != expectedData {
+			t.Fatalf("Inconsistent data: expected: %s, actual: %s (index: %d)",
+				expectedData, data, i)
+		}
+	}
+	if _, err := ioutil.ReadAll(rr.Reader()); err == nil {
+		t.Fatalf("No error when reading data after closing!")
+	}
+	// 不限制内存占用。
+	rr, err = NewMultipleReaderWithLimit(strings.NewReader(expectedData), -1)
+	if err != nil {
+		t.Fatalf("An error occurs when new multiple reader: %s", err)
+	}
+	if _, ok := rr.(*myMultipleReader); !ok || rr.Size() != int64(len(expectedData)) {
+		t.Fatalf("Inconsistent multiple reader: %#v", rr)
+	}
+}
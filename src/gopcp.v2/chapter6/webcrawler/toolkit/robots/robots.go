@@ -0,0 +1,203 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSize 代表会被解析的robots.txt内容的最大字节数。
+// 超出部分会被忽略。
+const MaxSize = 500 * 1024
+
+// Rules 代表针对某个用户代理的robots.txt规则。
+type Rules struct {
+	// rules 代表按顺序排列的路径规则。
+	rules []rule
+	// crawlDelay 代表相邻两次请求之间的最小间隔时间。
+	crawlDelay time.Duration
+	// sitemaps 代表robots.txt中声明的站点地图的URL的列表。
+	sitemaps []string
+}
+
+// rule 代表单条路径规则。
+type rule struct {
+	// allow 代表该规则是否为允许规则。
+	allow bool
+	// pattern 代表路径模式。其中可以包含通配符“*”和结尾标记“$”。
+	pattern string
+}
+
+// group 代表robots.txt中的一组规则。
+type group struct {
+	// agents 代表该组适用的用户代理的列表。
+	agents []string
+	// rules 代表该组中的路径规则。
+	rules []rule
+	// crawlDelay 代表该组设定的请求间隔时间。
+	crawlDelay time.Duration
+}
+
+// AllowAll 用于生成允许访问所有路径的规则。
+// 当robots.txt不存在时应使用此规则。
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll 用于生成禁止访问所有路径的规则。
+// 其效果与只包含“Disallow: /”的robots.txt相同，但robots.txt本身总是允许访问的。
+func DisallowAll() *Rules {
+	return &Rules{rules: []rule{{allow: false, pattern: "/"}}}
+}
+
+// Parse 用于解析robots.txt的内容，并返回针对给定用户代理的规则。
+// 用户代理的匹配不区分大小写。若没有匹配的组，则使用“*”组的规则。
+func Parse(r io.Reader, userAgent string) (*Rules, error) {
+	var groups []*group
+	var sitemaps []string
+	var current *group
+	// lastAgent 代表上一条有效记录是否为User-agent记录。
+	var lastAgent bool
+	scanner := bufio.NewScanner(io.LimitReader(r, MaxSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		index := strings.Index(line, ":")
+		if index < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:index]))
+		value := strings.TrimSpace(line[index+1:])
+		switch key {
+		case "user-agent":
+			if current == nil || !lastAgent {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules,
+					rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+		lastAgent = false
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rules := &Rules{sitemaps: sitemaps}
+	if g := selectGroup(groups, userAgent); g != nil {
+		rules.rules = g.rules
+		rules.crawlDelay = g.crawlDelay
+	}
+	return rules, nil
+}
+
+// selectGroup 用于选出与给定用户代理最匹配的组。
+// 匹配长度最长的组会被选中，“*”组的优先级最低。
+func selectGroup(groups []*group, userAgent string) *group {
+	userAgent = strings.ToLower(userAgent)
+	var selected *group
+	var selectedLen int
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			var matchedLen int
+			switch {
+			case agent == "*":
+				matchedLen = 0
+			case agent != "" && strings.Contains(userAgent, agent):
+				matchedLen = len(agent)
+			default:
+				continue
+			}
+			if selected == nil || matchedLen > selectedLen {
+				selected = g
+				selectedLen = matchedLen
+			}
+		}
+	}
+	return selected
+}
+
+// Allowed 用于判断给定的路径是否允许被访问。
+// 参数path应包含查询部分（如果有的话）。
+// 匹配最长的规则会生效；长度相同时允许规则优先。
+func (rules *Rules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	allowed := true
+	matchedLen := -1
+	for _, r := range rules.rules {
+		if !match(r.pattern, path) {
+			continue
+		}
+		if len(r.pattern) > matchedLen ||
+			(len(r.pattern) == matchedLen && r.allow) {
+			allowed = r.allow
+			matchedLen = len(r.pattern)
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay 用于获取规则中声明的请求间隔时间。
+// 若未声明，则返回0。
+func (rules *Rules) CrawlDelay() time.Duration {
+	return rules.crawlDelay
+}
+
+// Sitemaps 用于获取robots.txt中声明的站点地图的URL的列表。
+func (rules *Rules) Sitemaps() []string {
+	sitemaps := make([]string, len(rules.sitemaps))
+	copy(sitemaps, rules.sitemaps)
+	return sitemaps
+}
+
+// match 用于判断给定的路径是否与路径模式相匹配。
+func match(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		index := strings.Index(path[pos:], part)
+		if index < 0 {
+			return false
+		}
+		pos += index + len(part)
+	}
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// content 代表测试用的robots.txt的内容。
+var content = `# robots.txt for testing
+User-agent: Baiduspider
+User-agent: Googlebot
+Disallow: /private
+Allow: /private/public
+Crawl-delay: 2
+
+User-agent: gopcp-crawler
+Disallow: /search
+Disallow: /*.pdf$
+Allow: /search/about
+Crawl-delay: 0.5
+
+User-agent: *
+Disallow: /
+
+Sitemap: http://example.com/sitemap.xml
+Sitemap: http://example.com/sitemap-news.xml
+`
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(strings.NewReader(content), "Mozilla/5.0 (compatible; gopcp-crawler/1.0)")
+	if err != nil {
+		t.Fatalf("An error occurs when parsing robots.txt: %s", err)
+	}
+	expectedDelay := 500 * time.Millisecond
+	if rules.CrawlDelay() != expectedDelay {
+		t.Fatalf("Inconsistent crawl delay: expected: %s, actual: %s",
+			expectedDelay, rules.CrawlDelay())
+	}
+	pathMap := map[string]bool{
+		"/":                 true,
+		"/index.html":       true,
+		"/search":           false,
+		"/search?q=golang":  false,
+		"/search/about":     true,
+		"/docs/book.pdf":    false,
+		"/docs/book.pdf?v1": true,
+		"/private":          true,
+		"/robots.txt":       true,
+	}
+	for path, expected := range pathMap {
+		if actual := rules.Allowed(path); actual != expected {
+			t.Fatalf("Inconsistent allowed result: expected: %v, actual: %v (path: %s)",
+				expected, actual, path)
+		}
+	}
+	sitemaps := rules.Sitemaps()
+	if len(sitemaps) != 2 || sitemaps[0] != "http://example.com/sitemap.xml" {
+		t.Fatalf("Inconsistent sitemaps: %v", sitemaps)
+	}
+	// 测试多个用户代理共用一组规则的情况。
+	rules, _ = Parse(strings.NewReader(content), "Googlebot/2.1")
+	expectedDelay = 2 * time.Second
+	if rules.CrawlDelay() != expectedDelay {
+		t.Fatalf("Inconsistent crawl delay: expected: %s, actual: %s",
+			expectedDelay, rules.CrawlDelay())
+	}
+	pathMap = map[string]bool{
+		"/search":             true,
+		"/private/":           false,
+		"/private/public/abc": true,
+	}
+	for path, expected := range pathMap {
+		if actual := rules.Allowed(path); actual != expected {
+			t.Fatalf("Inconsistent allowed result: expected: %v, actual: %v (path: %s)",
+				expected, actual, path)
+		}
+	}
+	// 测试使用“*”组的情况。
+	rules, _ = Parse(strings.NewReader(content), "unknown-bot")
+	if rules.Allowed("/index.html") {
+		t.Fatalf("It still can access %q with the rules of group %q!",
+			"/index.html", "*")
+	}
+	if rules.CrawlDelay() != 0 {
+		t.Fatalf("Inconsistent crawl delay: expected: %s, actual: %s",
+			time.Duration(0), rules.CrawlDelay())
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	rules, err := Parse(strings.NewReader(""), "gopcp-crawler")
+	if err != nil {
+		t.Fatalf("An error occurs when parsing empty robots.txt: %s", err)
+	}
+	if !rules.Allowed("/anything") {
+		t.Fatal("Couldn't access any path with empty robots.txt!")
+	}
+	rules, _ = Parse(strings.NewReader("Disallow: /\nUser-agent: *\nDisallow:\n"), "gopcp-crawler")
+	if !rules.Allowed("/anything") {
+		t.Fatal("Couldn't access any path with empty disallow rule!")
+	}
+}
+
+func TestAllowAndDisallowAll(t *testing.T) {
+	if !AllowAll().Allowed("/abc") {
+		t.Fatal("Couldn't access path with allow-all rules!")
+	}
+	if DisallowAll().Allowed("/abc") {
+		t.Fatal("It still can access path with disallow-all rules!")
+	}
+	if !DisallowAll().Allowed("/robots.txt") {
+		t.Fatal("Couldn't access robots.txt with disallow-all rules!")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"/", "/abc", true},
+		{"/abc", "/abcdef", true},
+		{"/abc", "/ab", false},
+		{"/*.php", "/index.php?x=1", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/*.php$", "/index.php", true},
+		{"/a*b*c", "/a123b456c789", true},
+		{"/a*b*c", "/a123c456b", false},
+		{"/abc$", "/abc", true},
+		{"/abc$", "/abcd", false},
+	}
+	for _, c := range cases {
+		if actual := match(c.pattern, c.path); actual != c.matched {
+			t.Fatalf("Inconsistent match result: expected: %v, actual: %v (pattern: %s, path: %s)",
+				c.matched, actual, c.pattern, c.path)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package warc
+
+import (
+	"bufio"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cdxHeader 代表CDX索引文件的首行，声明了各字段的含义：
+// N：规范化的URL，b：日期，a：原始URL，m：MIME类型，s：状态码，
+// k：载荷摘要，r：重定向地址，M：元标签，S：压缩后的记录大小，
+// V：记录在WARC文件中的偏移量，g：WARC文件名。
+const cdxHeader = " CDX N b a m s k r M S V g"
+
+// cdxLine 用于生成响应记录对应的CDX索引行。
+func cdxLine(
+	exchange *Exchange,
+	date time.Time,
+	payloadDigest string,
+	length int64,
+	offset int64,
+	fileName string) string {
+	httpResp := exchange.Response
+	mimeType := "-"
+	if contentType := httpResp.Header.Get("Content-Type"); contentType != "" {
+		if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+			mimeType = mt
+		}
+	}
+	redirect := "-"
+	if location := httpResp.Header.Get("Location"); location != "" {
+		redirect = strings.Replace(location, " ", "%20", -1)
+	}
+	originalURL := exchange.Request.URL.String()
+	return strings.Join([]string{
+		surt(exchange.Request.URL),
+		date.Format("20060102150405"),
+		strings.Replace(originalURL, " ", "%20", -1),
+		mimeType,
+		strconv.Itoa(httpResp.StatusCode),
+		strings.TrimPrefix(payloadDigest, "sha1:"),
+		redirect,
+		"-",
+		strconv.FormatInt(length, 10),
+		strconv.FormatInt(offset, 10),
+		fileName,
+	}, " ")
+}
+
+// surt 用于生成URL的SURT（Sort-friendly URI Reordering Transform）形式，
+// 如http://www.Example.com/a?b=1会被转换为com,example)/a?b=1。
+func surt(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	parts := strings.Split(host, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	key := strings.Join(parts, ",")
+	if port := u.Port(); port != "" &&
+		!(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		key += ":" + port
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		sort.Strings(params)
+		key += "?" + strings.ToLower(strings.Join(params, "&"))
+	}
+	return key
+}
+
+// writeCDX 用于把排序后的CDX索引行写入给定的文件。
+func writeCDX(path string, lines []string) error {
+	sorted := make([]string, len(lines))
+	copy(sorted, lines)
+	sort.Strings(sorted)
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(file)
+	fmt.Fprintln(bw, cdxHeader)
+	for _, line := range sorted {
+		fmt.Fprintln(bw, line)
+	}
+	err = bw.Flush()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
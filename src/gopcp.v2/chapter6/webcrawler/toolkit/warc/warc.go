@@ -0,0 +1,338 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxFileSize 代表默认的单个WARC文件的最大字节数（压缩后）。
+const DefaultMaxFileSize = 1024 * 1024 * 1024
+
+// software 代表在warcinfo记录中声明的软件名称。
+const software = "gopcp.v2 webcrawler"
+
+// Exchange 代表一次HTTP交互，即一个请求及其响应。
+type Exchange struct {
+	// Request 代表HTTP请求。其请求体不会被记录。
+	Request *http.Request
+	// Response 代表HTTP响应。其响应体不会被读取，而是由Body提供。
+	Response *http.Response
+	// Body 代表响应体。写入器会读取它两次，分别用于计算摘要和写入记录。
+	Body io.ReadSeeker
+	// Date 代表获取响应的时间。
+	Date time.Time
+}
+
+// Writer 代表WARC文件写入器的接口类型。
+// 写入的WARC文件是按记录分段的gzip压缩文件，
+// 每个WARC文件都会有一个同名的、已排序的CDX索引文件。
+// 该接口的实现类型必须是并发安全的！
+type Writer interface {
+	// Write 用于写入一次HTTP交互对应的响应记录和请求记录。
+	// 若当前的WARC文件已达到最大字节数，则会先切换到新的WARC文件。
+	Write(exchange *Exchange) error
+	// Files 用于获取已创建的WARC文件的路径的列表。
+	Files() []string
+	// Close 用于关闭当前的WARC文件并写出其CDX索引文件。
+	Close() error
+}
+
+// NewWriter 用于创建一个WARC文件写入器。
+// 参数dirPath代表存放WARC文件的目录，prefix代表WARC文件名的前缀，
+// maxFileSize代表单个WARC文件的最大字节数，为0时使用DefaultMaxFileSize。
+func NewWriter(dirPath string, prefix string, maxFileSize int64) (Writer, error) {
+	if dirPath == "" {
+		return nil, fmt.Errorf("empty directory path")
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("empty file name prefix")
+	}
+	if maxFileSize < 0 {
+		return nil, fmt.Errorf("negative max file size %d", maxFileSize)
+	}
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	absDirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDirPath, 0755); err != nil {
+		return nil, err
+	}
+	return &myWriter{
+		dirPath:     absDirPath,
+		prefix:      prefix,
+		maxFileSize: maxFileSize,
+	}, nil
+}
+
+// myWriter 代表WARC文件写入器的实现类型。
+type myWriter struct {
+	// lock 代表保护写入过程的互斥锁。
+	lock sync.Mutex
+	// dirPath 代表存放WARC文件的目录的绝对路径。
+	dirPath string
+	// prefix 代表WARC文件名的前缀。
+	prefix string
+	// maxFileSize 代表单个WARC文件的最大字节数。
+	maxFileSize int64
+	// serial 代表WARC文件的序号。
+	serial uint32
+	// file 代表当前的WARC文件。
+	file *os.File
+	// offset 代表当前的WARC文件已写入的字节数。
+	offset int64
+	// cdxLines 代表当前的WARC文件的CDX索引行。
+	cdxLines []string
+	// files 代表已创建的WARC文件的路径的列表。
+	files []string
+	// closed 代表写入器是否已关闭。
+	closed bool
+}
+
+func (w *myWriter) Write(exchange *Exchange) error {
+	if exchange == nil || exchange.Request == nil ||
+		exchange.Request.URL == nil || exchange.Response == nil {
+		return fmt.Errorf("incomplete exchange")
+	}
+	body := exchange.Body
+	if body == nil {
+		body = bytes.NewReader(nil)
+	}
+	date := exchange.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	date = date.UTC()
+	targetURI := exchange.Request.URL.String()
+	respHead := responseHead(exchange.Response)
+	payloadSize, payloadDigest, blockDigest, err := digest(respHead, body)
+	if err != nil {
+		return fmt.Errorf("couldn't compute digest: %s", err)
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return fmt.Errorf("closed WARC writer")
+	}
+	if err := w.prepareFile(date); err != nil {
+		return err
+	}
+	respID := newRecordID()
+	respFields := []string{
+		"WARC-Type", "response",
+		"WARC-Record-ID", respID,
+		"WARC-Date", date.Format(time.RFC3339),
+		"WARC-Target-URI", targetURI,
+		"WARC-Payload-Digest", payloadDigest,
+		"WARC-Block-Digest", blockDigest,
+		"Content-Type", "application/http; msgtype=response",
+	}
+	block := io.MultiReader(bytes.NewReader(respHead), body)
+	offset := w.offset
+	if err := w.writeRecord(respFields, block, int64(len(respHead))+payloadSize); err != nil {
+		return err
+	}
+	w.cdxLines = append(w.cdxLines,
+		cdxLine(exchange, date, payloadDigest, w.offset-offset, offset, filepath.Base(w.file.Name())))
+	reqHead := requestHead(exchange.Request)
+	reqFields := []string{
+		"WARC-Type", "request",
+		"WARC-Record-ID", newRecordID(),
+		"WARC-Date", date.Format(time.RFC3339),
+		"WARC-Target-URI", targetURI,
+		"WARC-Concurrent-To", respID,
+		"WARC-Block-Digest", sha1Digest(reqHead),
+		"Content-Type", "application/http; msgtype=request",
+	}
+	if err := w.writeRecord(reqFields, bytes.NewReader(reqHead), int64(len(reqHead))); err != nil {
+		return err
+	}
+	return nil
+}
+
+// prepareFile 用于在需要时创建新的WARC文件，并在其中写入warcinfo记录。
+func (w *myWriter) prepareFile(date time.Time) error {
+	if w.file != nil && w.offset < w.maxFileSize {
+		return nil
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	w.serial++
+	fileName := fmt.Sprintf("%s-%s-%05d.warc.gz",
+		w.prefix, date.Format("20060102150405"), w.serial)
+	file, err := os.OpenFile(filepath.Join(w.dirPath, fileName),
+		os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.offset = 0
+	w.cdxLines = nil
+	w.files = append(w.files, file.Name())
+	info := []byte(fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.0\r\n", software))
+	fields := []string{
+		"WARC-Type", "warcinfo",
+		"WARC-Record-ID", newRecordID(),
+		"WARC-Date", date.Format(time.RFC3339),
+		"WARC-Filename", fileName,
+		"Content-Type", "application/warc-fields",
+	}
+	return w.writeRecord(fields, bytes.NewReader(info), int64(len(info)))
+}
+
+// writeRecord 用于把一条WARC记录作为一个独立的gzip成员写入当前的WARC文件。
+func (w *myWriter) writeRecord(fields []string, block io.Reader, blockSize int64) error {
+	var head bytes.Buffer
+	head.WriteString("WARC/1.0\r\n")
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&head, "%s: %s\r\n", fields[i], fields[i+1])
+	}
+	fmt.Fprintf(&head, "Content-Length: %d\r\n\r\n", blockSize)
+	cw := &countingWriter{w: w.file}
+	gw := gzip.NewWriter(cw)
+	if _, err := gw.Write(head.Bytes()); err != nil {
+		return err
+	}
+	n, err := io.Copy(gw, block)
+	if err != nil {
+		return err
+	}
+	if n != blockSize {
+		return fmt.Errorf("inconsistent block size: expected: %d, actual: %d", blockSize, n)
+	}
+	if _, err := gw.Write([]byte("\r\n\r\n")); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	w.offset += cw.n
+	return nil
+}
+
+// closeFile 用于关闭当前的WARC文件并写出其CDX索引文件。
+func (w *myWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	warcPath := w.file.Name()
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	cdxPath := strings.TrimSuffix(warcPath, ".warc.gz") + ".cdx"
+	return writeCDX(cdxPath, w.cdxLines)
+}
+
+func (w *myWriter) Files() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	files := make([]string, len(w.files))
+	copy(files, w.files)
+	return files
+}
+
+func (w *myWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.closeFile()
+}
+
+// countingWriter 代表会统计写入字节数的写入器。
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// responseHead 用于生成HTTP响应的状态行和响应头。
+// 由于响应体已被解除分块传输编码，所以响应头中不会包含Transfer-Encoding。
+func responseHead(httpResp *http.Response) []byte {
+	var buf bytes.Buffer
+	status := httpResp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", httpResp.StatusCode, http.StatusText(httpResp.StatusCode))
+	}
+	fmt.Fprintf(&buf, "HTTP/1.1 %s\r\n", status)
+	httpResp.Header.WriteSubset(&buf, map[string]bool{"Transfer-Encoding": true})
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// requestHead 用于生成HTTP请求的请求行和请求头。
+func requestHead(httpReq *http.Request) []byte {
+	var buf bytes.Buffer
+	method := httpReq.Method
+	if method == "" {
+		method = "GET"
+	}
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, httpReq.URL.RequestURI())
+	host := httpReq.Host
+	if host == "" {
+		host = httpReq.URL.Host
+	}
+	fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	httpReq.Header.WriteSubset(&buf, map[string]bool{"Host": true})
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// digest 用于计算响应体的字节数、载荷摘要和记录块（响应头加响应体）摘要。
+// 计算完成后，响应体会被重置到起始位置。
+func digest(head []byte, body io.ReadSeeker) (int64, string, string, error) {
+	payloadHash := sha1.New()
+	blockHash := sha1.New()
+	blockHash.Write(head)
+	n, err := io.Copy(io.MultiWriter(payloadHash, blockHash), body)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return 0, "", "", err
+	}
+	return n, formatDigest(payloadHash.Sum(nil)), formatDigest(blockHash.Sum(nil)), nil
+}
+
+// sha1Digest 用于计算给定内容的摘要。
+func sha1Digest(content []byte) string {
+	sum := sha1.Sum(content)
+	return formatDigest(sum[:])
+}
+
+// formatDigest 用于生成WARC规范中的摘要字符串。
+func formatDigest(sum []byte) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum)
+}
+
+// newRecordID 用于生成新的WARC记录ID，即一个随机的UUID。
+func newRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,201 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func genExchange(t *testing.T, rawURL string, body string) *Exchange {
+	httpReq, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		t.Fatalf("An error occurs when creating HTTP request: %s", err)
+	}
+	httpReq.Header.Set("User-Agent", "test")
+	httpResp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Header: http.Header{
+			"Content-Type":      {"text/html; charset=utf-8"},
+			"Transfer-Encoding": {"chunked"},
+		},
+		Request: httpReq,
+	}
+	return &Exchange{
+		Request:  httpReq,
+		Response: httpResp,
+		Body:     strings.NewReader(body),
+		Date:     time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+}
+
+// readRecord 用于读取并解压缩给定偏移量和长度的一条WARC记录。
+func readRecord(t *testing.T, path string, offset, length int64) (map[string]string, string) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("An error occurs when opening WARC file: %s", err)
+	}
+	defer file.Close()
+	gr, err := gzip.NewReader(io.NewSectionReader(file, offset, length))
+	if err != nil {
+		t.Fatalf("An error occurs when creating gzip reader: %s", err)
+	}
+	br := bufio.NewReader(gr)
+	fields := map[string]string{}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("An error occurs when reading WARC header: %s", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if i := strings.Index(line, ": "); i > 0 {
+			fields[line[:i]] = line[i+2:]
+		} else {
+			fields[""] = line
+		}
+	}
+	block, err := ioutil.ReadAll(br)
+	if err != nil {
+		t.Fatalf("An error occurs when reading WARC block: %s", err)
+	}
+	return fields, string(block)
+}
+
+func TestWriter(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "warc")
+	if err != nil {
+		t.Fatalf("An error occurs when creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dirPath)
+	writer, err := NewWriter(dirPath, "crawl", 1)
+	if err != nil {
+		t.Fatalf("An error occurs when creating WARC writer: %s", err)
+	}
+	bodies := []string{"<html>b</html>", "<html>a</html>"}
+	urls := []string{"http://www.Example.com/b?y=2&x=1", "http://example.com:8080/a"}
+	for i := range urls {
+		if err := writer.Write(genExchange(t, urls[i], bodies[i])); err != nil {
+			t.Fatalf("An error occurs when writing exchange: %s", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("An error occurs when closing WARC writer: %s", err)
+	}
+	if err := writer.Write(genExchange(t, urls[0], bodies[0])); err == nil {
+		t.Fatalf("No error when writing exchange after closing!")
+	}
+	files := writer.Files()
+	if len(files) != len(urls) {
+		t.Fatalf("Inconsistent WARC file number: expected: %d, actual: %d",
+			len(urls), len(files))
+	}
+	expectedKeys := []string{"com,example)/b?x=1&y=2", "com,example:8080)/a"}
+	for i, path := range files {
+		cdxPath := strings.TrimSuffix(path, ".warc.gz") + ".cdx"
+		cdx, err := ioutil.ReadFile(cdxPath)
+		if err != nil {
+			t.Fatalf("An error occurs when reading CDX file: %s", err)
+		}
+		lines := strings.Split(strings.TrimRight(string(cdx), "\n"), "\n")
+		if len(lines) != 2 || lines[0] != cdxHeader {
+			t.Fatalf("Inconsistent CDX content: %q", cdx)
+		}
+		parts := strings.Split(lines[1], " ")
+		if len(parts) != 11 {
+			t.Fatalf("Inconsistent CDX field number: expected: %d, actual: %d",
+				11, len(parts))
+		}
+		if parts[0] != expectedKeys[i] || parts[1] != "20170102150405" ||
+			parts[2] != urls[i] || parts[3] != "text/html" || parts[4] != "200" {
+			t.Fatalf("Inconsistent CDX line: %q", lines[1])
+		}
+		length, _ := strconv.ParseInt(parts[8], 10, 64)
+		offset, _ := strconv.ParseInt(parts[9], 10, 64)
+		if offset == 0 {
+			t.Fatalf("The response record is placed before the warcinfo record!")
+		}
+		fields, block := readRecord(t, path, offset, length)
+		if fields[""] != "WARC/1.0" || fields["WARC-Type"] != "response" ||
+			fields["WARC-Target-URI"] != urls[i] ||
+			fields["WARC-Date"] != "2017-01-02T15:04:05Z" {
+			t.Fatalf("Inconsistent WARC header: %v", fields)
+		}
+		if fields["WARC-Payload-Digest"] != sha1Digest([]byte(bodies[i])) ||
+			parts[5] != strings.TrimPrefix(fields["WARC-Payload-Digest"], "sha1:") {
+			t.Fatalf("Inconsistent payload digest: expected: %s, actual: %s",
+				sha1Digest([]byte(bodies[i])), fields["WARC-Payload-Digest"])
+		}
+		block = strings.TrimSuffix(block, "\r\n\r\n")
+		if fields["Content-Length"] != strconv.Itoa(len(block)) {
+			t.Fatalf("Inconsistent content length: expected: %d, actual: %s",
+				len(block), fields["Content-Length"])
+		}
+		if fields["WARC-Block-Digest"] != sha1Digest([]byte(block)) {
+			t.Fatalf("Inconsistent block digest: expected: %s, actual: %s",
+				sha1Digest([]byte(block)), fields["WARC-Block-Digest"])
+		}
+		httpResp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(block)), nil)
+		if err != nil {
+			t.Fatalf("An error occurs when parsing HTTP response: %s", err)
+		}
+		body, _ := ioutil.ReadAll(httpResp.Body)
+		if string(body) != bodies[i] {
+			t.Fatalf("Inconsistent body: expected: %q, actual: %q", bodies[i], body)
+		}
+		// 整个文件可以被当作连续的gzip流读取。
+		file, _ := os.Open(path)
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("An error occurs when creating gzip reader: %s", err)
+		}
+		content, err := ioutil.ReadAll(gr)
+		file.Close()
+		if err != nil {
+			t.Fatalf("An error occurs when reading WARC file: %s", err)
+		}
+		for _, warcType := range []string{"warcinfo", "response", "request"} {
+			if !bytes.Contains(content, []byte("WARC-Type: "+warcType+"\r\n")) {
+				t.Fatalf("Missing %s record!", warcType)
+			}
+		}
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	if _, err := NewWriter("", "crawl", 0); err == nil {
+		t.Fatalf("No error when creating WARC writer with empty directory path!")
+	}
+	if _, err := NewWriter(os.TempDir(), "", 0); err == nil {
+		t.Fatalf("No error when creating WARC writer with empty prefix!")
+	}
+	if _, err := NewWriter(os.TempDir(), "crawl", -1); err == nil {
+		t.Fatalf("No error when creating WARC writer with negative max file size!")
+	}
+}
+
+func TestSURT(t *testing.T) {
+	cases := map[string]string{
+		"http://www.example.com/":           "com,example)/",
+		"https://sub.Example.com:443":       "com,example,sub)/",
+		"http://example.com:81/A/B?b=2&a=1": "com,example:81)/a/b?a=1&b=2",
+	}
+	for rawURL, expected := range cases {
+		u, _ := url.Parse(rawURL)
+		if actual := surt(u); actual != expected {
+			t.Fatalf("Inconsistent SURT: expected: %s, actual: %s (URL: %s)",
+				expected, actual, rawURL)
+		}
+	}
+}